@@ -2,7 +2,12 @@
 package args
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
 	sdkArgs "github.com/newrelic/infra-integrations-sdk/v3/args"
 	"github.com/newrelic/infra-integrations-sdk/v3/log"
 )
@@ -18,6 +23,8 @@ type ArgumentList struct {
 	CollectionList                       string `default:"{}" help:"A JSON object which defines the databases, schemas, tables, and indexes to collect. Can also be a JSON array that list databases to be collected. Can also be the string literal 'ALL' to collect everything. Collects nothing by default."`
 	CollectionIgnoreDatabaseList         string `default:"[]" help:"A JSON array that list databases that will be excluded from collection. Nothing is excluded by default."`
 	CollectionIgnoreTableList            string `default:"[]" help:"A JSON array that list tables that will be excluded from collection. Nothing is excluded by default."`
+	CollectionFunctionList               string `default:"{}" help:"A JSON object which defines, per database and schema, the functions to collect by name or signature. Databases not listed collect their top functions by total time."`
+	CollectionFunctionTopN               int    `default:"20" help:"The number of functions with the highest total time to collect for each database not listed in collection_function_list. Set 0 to disable."`
 	SSLRootCertLocation                  string `default:"" help:"Absolute path to PEM encoded root certificate file"`
 	SSLCertLocation                      string `default:"" help:"Absolute path to PEM encoded client cert file"`
 	SSLKeyLocation                       string `default:"" help:"Absolute path to PEM encoded client key file"`
@@ -26,13 +33,43 @@ type ArgumentList struct {
 	CustomMetricsConfig                  string `default:"" help:"YAML configuration with one or more custom SQL queries to collect"`
 	EnableSSL                            bool   `default:"false" help:"If true will use SSL encryption, false will not use encryption"`
 	TrustServerCertificate               bool   `default:"false" help:"If true server certificate is not verified for SSL. If false certificate will be verified against supplied certificate"`
-	Pgbouncer                            bool   `default:"false" help:"Collects metrics from PgBouncer instance. Assumes connection is through PgBouncer."`
-	CollectDbLockMetrics                 bool   `default:"false" help:"If true, enables collection of lock metrics for the specified database. (Note: requires that the 'tablefunc' extension is installed)"` //nolint: stylecheck
-	CollectBloatMetrics                  bool   `default:"true" help:"Enable collecting bloat metrics which can be performance intensive"`
+	Pgbouncer                            bool   `default:"false" help:"Collects metrics from PgBouncer instance. Assumes connection is through PgBouncer unless pgbouncer_hostname, pgbouncer_port or pgbouncer_instances are set."`
+	PgbouncerHostname                    string `default:"" help:"The PgBouncer hostname to connect to. Defaults to hostname"`
+	PgbouncerPort                        string `default:"" help:"The port to connect to the PgBouncer admin console. Defaults to port"`
+	PgbouncerUsername                    string `default:"" help:"The username for the PgBouncer admin console, which must be listed in stats_users or admin_users. Defaults to username"`
+	PgbouncerPassword                    string `default:"" help:"The password for the PgBouncer username. Defaults to password"`
+	PgbouncerInstances                   string `default:"[]" help:"A JSON array of PgBouncer instances to collect, each with a hostname and port and optionally a username and password, which default to the pgbouncer_* arguments, e.g. [{\"hostname\": \"pgbouncer-1\", \"port\": \"6432\"}]"`
+	PgbouncerEnableSSL                   bool   `default:"false" help:"If true will use SSL encryption to connect to PgBouncer. The PostgreSQL SSL settings apply instead when no PgBouncer connection setting is set"`
+	PgbouncerTrustServerCertificate      bool   `default:"false" help:"If true PgBouncer server certificate is not verified for SSL. If false certificate will be verified against supplied certificate"`
+	PgbouncerSSLRootCertLocation         string `default:"" help:"Absolute path to PEM encoded root certificate file for PgBouncer"`
+	PgbouncerSSLCertLocation             string `default:"" help:"Absolute path to PEM encoded client cert file for PgBouncer"`
+	PgbouncerSSLKeyLocation              string `default:"" help:"Absolute path to PEM encoded client key file for PgBouncer"`
+	Pgpool                               bool   `default:"false" help:"Collects metrics from the Pgpool-II instance at pgpool_hostname and pgpool_port, which is reached with the PostgreSQL database and SSL settings"`
+	PgpoolHostname                       string `default:"" help:"The Pgpool-II hostname to connect to. Defaults to hostname"`
+	PgpoolPort                           string `default:"9999" help:"The port Pgpool-II accepts client connections on"`
+	PgpoolUsername                       string `default:"" help:"The username to connect to Pgpool-II with. Defaults to username"`
+	PgpoolPassword                       string `default:"" help:"The password for the Pgpool-II username. Defaults to password"`
+	PatroniURL                           string `default:"" help:"The base URL of the Patroni REST API of the member running on this host, e.g. http://localhost:8008. The Patroni cluster state is collected when set"`
+	PatroniCACertLocation                string `default:"" help:"Absolute path to PEM encoded root certificate file to verify an HTTPS Patroni REST API"`
+	PgbackrestInfoCommand                string `default:"" help:"The pgBackRest command printing the backups of the stanzas in JSON, e.g. pgbackrest info --output=json. Backup freshness is collected when set"`
+	PgbackrestInfoFile                   string `default:"" help:"Absolute path to a file with the output of pgbackrest info --output=json, read instead of running pgbackrest_info_command"`
+	PgbackrestInfoTimeout                int    `default:"60" help:"Maximum time, in seconds, to wait for pgbackrest_info_command"`
+	CollectDbLockMetrics                 bool   `default:"false" help:"If true, enables collection of lock metrics for the specified database and the locks held or awaited on each collected table"` //nolint: stylecheck
+	CollectBloatMetrics                  bool   `default:"true" help:"Enable collecting table and B-tree index bloat metrics which can be performance intensive"`
+	CollectExactBloatMetrics             bool   `default:"false" help:"If true, measures table and B-tree index bloat with the pgstattuple extension, which must be installed in the public schema of each collected database"`
+	ExactBloatMaxRelationSizeMb          int    `default:"1024" help:"Tables and indexes larger than this size, in megabytes, are not measured by exact bloat collection"`
+	ExactBloatRelationsPerRun            int    `default:"10" help:"The maximum number of tables and indexes measured by exact bloat collection on each run. The ones measured the longest ago go first"`
+	CollectBufferCacheMetrics            bool   `default:"false" help:"If true, reports the shared buffers used by each database and by the collected tables and indexes with the pg_buffercache extension, which must be installed in the public schema of each collected database"`
+	BufferCacheIntervalMinutes           int    `default:"15" help:"Minimum time, in minutes, between two buffer cache collections, as reading pg_buffercache is expensive"`
+	BufferCacheRelationsPerDatabase      int    `default:"20" help:"The maximum number of tables and of indexes reported by buffer cache collection for each database. The ones holding the most buffers go first"`
 	ShowVersion                          bool   `default:"false" help:"Print build information and exit"`
 	EnableQueryMonitoring                bool   `default:"false" help:"Enable collection of detailed query performance metrics."`
 	QueryMonitoringResponseTimeThreshold int    `default:"1" help:"Threshold in milliseconds for query response time. If response time for the individual query exceeds this threshold, the individual query is reported in metrics"`
 	QueryMonitoringCountThreshold        int    `default:"20" help:"The number of records for each query performance metrics"`
+	LongRunningQueryThreshold            int    `default:"300" help:"Threshold in seconds for the duration of the running query of a session. Sessions exceeding it are reported by query monitoring. Set 0 to disable"`
+	LongRunningTransactionThreshold      int    `default:"600" help:"Threshold in seconds for the duration of the open transaction of a session. Sessions exceeding it are reported by query monitoring. Set 0 to disable"`
+	IdleInTransactionThreshold           int    `default:"60" help:"Threshold in seconds for the time a session has been idle in transaction. Sessions exceeding it are reported by query monitoring. Set 0 to disable"`
+	LongRunningThresholdOverrides        string `default:"" help:"A JSON object overriding the long running thresholds per database and per application name, e.g. {\"databases\": {\"reporting\": {\"query\": 1800}}, \"applications\": {\"batch\": {\"transaction\": 0}}}. Application overrides take precedence"`
 	IsRds                                bool   `default:"false" help:"If true, the integration will support on AWS RDS. This will enable RDS-specific metrics and configurations."`
 }
 
@@ -44,6 +81,18 @@ func (al ArgumentList) Validate() error {
 	if err := al.validateSSL(); err != nil {
 		return err
 	}
+	if err := al.validateExactBloat(); err != nil {
+		return err
+	}
+	if err := al.validateBufferCache(); err != nil {
+		return err
+	}
+	if err := al.validatePgBouncer(); err != nil {
+		return err
+	}
+	if err := al.validatePatroni(); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -60,3 +109,109 @@ func (al ArgumentList) validateSSL() error {
 
 	return nil
 }
+
+func (al ArgumentList) validateExactBloat() error {
+	if al.ExactBloatMaxRelationSizeMb < 0 || al.ExactBloatRelationsPerRun < 0 {
+		return errors.New("invalid configuration: exact_bloat_max_relation_size_mb and exact_bloat_relations_per_run can't be negative")
+	}
+
+	return nil
+}
+
+func (al ArgumentList) validateBufferCache() error {
+	if al.BufferCacheIntervalMinutes < 0 || al.BufferCacheRelationsPerDatabase < 0 {
+		return errors.New("invalid configuration: buffer_cache_interval_minutes and buffer_cache_relations_per_database can't be negative")
+	}
+
+	return nil
+}
+
+func (al ArgumentList) validatePgBouncer() error {
+	if !al.Pgbouncer || !al.HasPgBouncerSettings() {
+		return nil
+	}
+
+	if _, err := al.GetPgBouncerInstances(); err != nil {
+		return err
+	}
+
+	if al.PgbouncerEnableSSL {
+		if !al.PgbouncerTrustServerCertificate && al.PgbouncerSSLRootCertLocation == "" {
+			return errors.New("invalid configuration: must specify a PgBouncer certificate file when using SSL and not trusting server certificate")
+		}
+
+		if al.PgbouncerSSLCertLocation == "" || al.PgbouncerSSLKeyLocation == "" {
+			log.Warn("potentially invalid configuration: PgBouncer client cert and/or key file not present when SSL is enabled")
+		}
+	}
+
+	return nil
+}
+
+func (al ArgumentList) validatePatroni() error {
+	if al.PatroniURL == "" {
+		return nil
+	}
+
+	patroniURL, err := url.Parse(al.PatroniURL)
+	if err != nil || (patroniURL.Scheme != "http" && patroniURL.Scheme != "https") || patroniURL.Host == "" {
+		return errors.New("invalid configuration: patroni_url must be an http or https URL")
+	}
+	return nil
+}
+
+// PgBouncerInstance is the endpoint of a PgBouncer admin console
+type PgBouncerInstance struct {
+	Hostname string `json:"hostname"`
+	Port     string `json:"port"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// HasPgBouncerSettings returns true when any PgBouncer connection setting is set, in which case PgBouncer is
+// reached with its own settings, falling back to the PostgreSQL endpoint and credentials for the unset ones,
+// instead of with the PostgreSQL connection settings
+func (al ArgumentList) HasPgBouncerSettings() bool {
+	instances := strings.TrimSpace(al.PgbouncerInstances)
+	return al.PgbouncerHostname != "" || al.PgbouncerPort != "" || al.PgbouncerUsername != "" || al.PgbouncerPassword != "" ||
+		al.PgbouncerEnableSSL || al.PgbouncerTrustServerCertificate || al.PgbouncerSSLRootCertLocation != "" ||
+		al.PgbouncerSSLCertLocation != "" || al.PgbouncerSSLKeyLocation != "" || (instances != "" && instances != "[]")
+}
+
+// GetPgBouncerInstances returns the PgBouncer instances to collect, the ones of pgbouncer_instances or else a single
+// one, with the settings each of them leaves empty taken from the pgbouncer_* and then the PostgreSQL arguments
+func (al ArgumentList) GetPgBouncerInstances() ([]PgBouncerInstance, error) {
+	defaults := PgBouncerInstance{
+		Hostname: firstNonEmpty(al.PgbouncerHostname, al.Hostname),
+		Port:     firstNonEmpty(al.PgbouncerPort, al.Port),
+		Username: firstNonEmpty(al.PgbouncerUsername, al.Username),
+		Password: firstNonEmpty(al.PgbouncerPassword, al.Password),
+	}
+
+	var instances []PgBouncerInstance
+	if strings.TrimSpace(al.PgbouncerInstances) != "" {
+		if err := json.Unmarshal([]byte(al.PgbouncerInstances), &instances); err != nil {
+			return nil, fmt.Errorf("invalid configuration: failed to parse pgbouncer_instances: %w", err)
+		}
+	}
+	if len(instances) == 0 {
+		return []PgBouncerInstance{defaults}, nil
+	}
+
+	for i := range instances {
+		instances[i].Hostname = firstNonEmpty(instances[i].Hostname, defaults.Hostname)
+		instances[i].Port = firstNonEmpty(instances[i].Port, defaults.Port)
+		instances[i].Username = firstNonEmpty(instances[i].Username, defaults.Username)
+		instances[i].Password = firstNonEmpty(instances[i].Password, defaults.Password)
+	}
+	return instances, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
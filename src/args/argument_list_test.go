@@ -2,6 +2,8 @@ package args
 
 import (
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestValidate(t *testing.T) {
@@ -42,6 +44,54 @@ func TestValidate(t *testing.T) {
 			},
 			true,
 		},
+		{
+			"Negative Exact Bloat Relations Per Run",
+			&ArgumentList{
+				Username:                  "user",
+				Password:                  "password",
+				Hostname:                  "localhost",
+				Port:                      "90",
+				CollectionList:            "{}",
+				ExactBloatRelationsPerRun: -1,
+			},
+			true,
+		},
+		{
+			"Negative Exact Bloat Max Relation Size",
+			&ArgumentList{
+				Username:                    "user",
+				Password:                    "password",
+				Hostname:                    "localhost",
+				Port:                        "90",
+				CollectionList:              "{}",
+				ExactBloatMaxRelationSizeMb: -1,
+			},
+			true,
+		},
+		{
+			"Negative Buffer Cache Interval",
+			&ArgumentList{
+				Username:                   "user",
+				Password:                   "password",
+				Hostname:                   "localhost",
+				Port:                       "90",
+				CollectionList:             "{}",
+				BufferCacheIntervalMinutes: -1,
+			},
+			true,
+		},
+		{
+			"Negative Buffer Cache Relations Per Database",
+			&ArgumentList{
+				Username:                        "user",
+				Password:                        "password",
+				Hostname:                        "localhost",
+				Port:                            "90",
+				CollectionList:                  "{}",
+				BufferCacheRelationsPerDatabase: -1,
+			},
+			true,
+		},
 		{
 			"SSL and No Server Certificate",
 			&ArgumentList{
@@ -86,6 +136,86 @@ func TestValidate(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"PgBouncer instances not valid JSON",
+			&ArgumentList{
+				Username:           "user",
+				Password:           "password",
+				Hostname:           "localhost",
+				Port:               "90",
+				CollectionList:     "{}",
+				Pgbouncer:          true,
+				PgbouncerInstances: "[{",
+			},
+			true,
+		},
+		{
+			"PgBouncer SSL and No Server Certificate",
+			&ArgumentList{
+				Username:                        "user",
+				Password:                        "password",
+				Hostname:                        "localhost",
+				Port:                            "90",
+				CollectionList:                  "{}",
+				Pgbouncer:                       true,
+				PgbouncerPort:                   "6432",
+				PgbouncerEnableSSL:              true,
+				PgbouncerTrustServerCertificate: false,
+			},
+			true,
+		},
+		{
+			"PgBouncer SSL on the PostgreSQL endpoint without certificate",
+			&ArgumentList{
+				Username:           "user",
+				Password:           "password",
+				Hostname:           "localhost",
+				Port:               "90",
+				CollectionList:     "{}",
+				Pgbouncer:          true,
+				PgbouncerInstances: "[]",
+				PgbouncerEnableSSL: true,
+			},
+			true,
+		},
+		{
+			"PgBouncer username only",
+			&ArgumentList{
+				Username:           "user",
+				Password:           "password",
+				Hostname:           "localhost",
+				Port:               "90",
+				CollectionList:     "{}",
+				Pgbouncer:          true,
+				PgbouncerInstances: "[]",
+				PgbouncerUsername:  "stats",
+			},
+			false,
+		},
+		{
+			"Patroni URL without scheme",
+			&ArgumentList{
+				Username:       "user",
+				Password:       "password",
+				Hostname:       "localhost",
+				Port:           "90",
+				CollectionList: "{}",
+				PatroniURL:     "localhost:8008",
+			},
+			true,
+		},
+		{
+			"Patroni URL",
+			&ArgumentList{
+				Username:       "user",
+				Password:       "password",
+				Hostname:       "localhost",
+				Port:           "90",
+				CollectionList: "{}",
+				PatroniURL:     "https://localhost:8008",
+			},
+			false,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -97,3 +227,27 @@ func TestValidate(t *testing.T) {
 		}
 	}
 }
+
+func TestGetPgBouncerInstances(t *testing.T) {
+	al := ArgumentList{
+		Username:           "user",
+		Password:           "password",
+		Hostname:           "localhost",
+		Port:               "5432",
+		PgbouncerHostname:  "pgbouncer",
+		PgbouncerUsername:  "stats",
+		PgbouncerInstances: `[{"port": "6432"}, {"hostname": "pgbouncer-2", "port": "6433", "username": "other", "password": "secret"}]`,
+	}
+
+	instances, err := al.GetPgBouncerInstances()
+	assert.NoError(t, err)
+	assert.Equal(t, []PgBouncerInstance{
+		{Hostname: "pgbouncer", Port: "6432", Username: "stats", Password: "password"},
+		{Hostname: "pgbouncer-2", Port: "6433", Username: "other", Password: "secret"},
+	}, instances)
+
+	al.PgbouncerInstances = "[]"
+	instances, err = al.GetPgBouncerInstances()
+	assert.NoError(t, err)
+	assert.Equal(t, []PgBouncerInstance{{Hostname: "pgbouncer", Port: "5432", Username: "stats", Password: "password"}}, instances)
+}
@@ -0,0 +1,161 @@
+package backup
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/data/attribute"
+	"github.com/newrelic/infra-integrations-sdk/v3/data/metric"
+	"github.com/newrelic/infra-integrations-sdk/v3/integration"
+	"github.com/newrelic/infra-integrations-sdk/v3/log"
+	"github.com/newrelic/nri-postgresql/src/connection"
+)
+
+// archiverQuery reads pg_stat_archiver, available since PostgreSQL 9.4. The WAL segment size is needed to
+// count the segments between two WAL file names and pg_settings reports it in 8kB blocks before PostgreSQL 11.
+const archiverQuery = `SELECT -- BACKUP_ARCHIVER
+		archived_count,
+		last_archived_wal,
+		extract(epoch FROM now() - last_archived_time) AS seconds_since_last_archived,
+		failed_count,
+		last_failed_wal,
+		extract(epoch FROM now() - last_failed_time) AS seconds_since_last_failure,
+		(SELECT setting::bigint * CASE unit WHEN '8kB' THEN 8192 WHEN 'kB' THEN 1024 WHEN 'MB' THEN 1048576 ELSE 1 END
+			FROM pg_settings WHERE name = 'wal_segment_size') AS wal_segment_size
+	FROM pg_stat_archiver;`
+
+// archiverStats is the WAL archiving activity of the instance
+type archiverStats struct {
+	ArchivedCount            *int64   `db:"archived_count"              metric_name:"backup.archiver.archivedCount"            source_type:"gauge"`
+	LastArchivedWal          *string  `db:"last_archived_wal"           metric_name:"backup.archiver.lastArchivedWal"          source_type:"attribute"`
+	SecondsSinceLastArchived *float64 `db:"seconds_since_last_archived" metric_name:"backup.archiver.secondsSinceLastArchived" source_type:"gauge"`
+	FailedCount              *int64   `db:"failed_count"                metric_name:"backup.archiver.failedCount"              source_type:"gauge"`
+	LastFailedWal            *string  `db:"last_failed_wal"             metric_name:"backup.archiver.lastFailedWal"            source_type:"attribute"`
+	SecondsSinceLastFailure  *float64 `db:"seconds_since_last_failure"  metric_name:"backup.archiver.secondsSinceLastFailure"  source_type:"gauge"`
+	WalSegmentSize           *int64   `db:"wal_segment_size"`
+}
+
+// backupTypes are the pgBackRest backup types and the name they are reported with
+var backupTypes = []struct {
+	backupType, name string
+}{
+	{"full", "Full"},
+	{"diff", "Differential"},
+	{"incr", "Incremental"},
+}
+
+// PopulateMetrics populates a PostgresqlBackupSample on the instance entity for each pgBackRest stanza, with the
+// latest backups of each type and the WAL archive of the repository compared with the archiver of the instance
+func PopulateMetrics(stanzas []Stanza, con *connection.PGSQLConnection, instanceEntity *integration.Entity) {
+	archiver := collectArchiverStats(con)
+	now := time.Now().Unix()
+
+	for _, stanza := range stanzas {
+		metricSet := instanceEntity.NewMetricSet("PostgresqlBackupSample",
+			attribute.Attribute{Key: "displayName", Value: instanceEntity.Metadata.Name},
+			attribute.Attribute{Key: "entityName", Value: instanceEntity.Metadata.Namespace + ":" + instanceEntity.Metadata.Name},
+			attribute.Attribute{Key: "backup.stanza", Value: stanza.Name},
+		)
+
+		setMetric(metricSet, "backup.statusCode", stanza.Status.Code, metric.GAUGE)
+		setMetric(metricSet, "backup.status", stanza.Status.Message, metric.ATTRIBUTE)
+
+		for _, t := range backupTypes {
+			last := stanza.LastBackup(t.backupType)
+			if last == nil {
+				continue
+			}
+			setMetric(metricSet, fmt.Sprintf("backup.last%sBackupLabel", t.name), last.Label, metric.ATTRIBUTE)
+			setMetric(metricSet, fmt.Sprintf("backup.secondsSinceLast%sBackup", t.name), now-last.Timestamp.Stop, metric.GAUGE)
+			setMetric(metricSet, fmt.Sprintf("backup.last%sBackupDurationInSeconds", t.name), last.Timestamp.Stop-last.Timestamp.Start, metric.GAUGE)
+			setMetric(metricSet, fmt.Sprintf("backup.last%sBackupSizeInBytes", t.name), last.Info.Size, metric.GAUGE)
+			setMetric(metricSet, fmt.Sprintf("backup.last%sBackupDeltaInBytes", t.name), last.Info.Delta, metric.GAUGE)
+			setMetric(metricSet, fmt.Sprintf("backup.last%sBackupRepositorySizeInBytes", t.name), last.Info.Repository.Size, metric.GAUGE)
+			setMetric(metricSet, fmt.Sprintf("backup.last%sBackupRepositoryDeltaInBytes", t.name), last.Info.Repository.Delta, metric.GAUGE)
+		}
+
+		if last := stanza.LastBackup(""); last != nil {
+			setMetric(metricSet, "backup.secondsSinceLastSuccessfulBackup", now-last.Timestamp.Stop, metric.GAUGE)
+		}
+
+		archive := stanza.CurrentArchive()
+		if archive != nil {
+			if archive.Min != nil {
+				setMetric(metricSet, "backup.archiveMin", *archive.Min, metric.ATTRIBUTE)
+			}
+			if archive.Max != nil {
+				setMetric(metricSet, "backup.archiveMax", *archive.Max, metric.ATTRIBUTE)
+			}
+		}
+
+		if archiver == nil {
+			continue
+		}
+		if err := metricSet.MarshalMetrics(archiver); err != nil {
+			log.Error("Failed to populate instance entity with archiver metrics: %s", err.Error())
+		}
+		if gap, ok := archiveGap(archive, archiver); ok {
+			setMetric(metricSet, "backup.archiveGapSegments", gap, metric.GAUGE)
+		}
+	}
+}
+
+func collectArchiverStats(con *connection.PGSQLConnection) *archiverStats {
+	rows := make([]archiverStats, 0, 1)
+	if err := con.Query(&rows, archiverQuery); err != nil {
+		log.Warn("Could not execute archiver query: %s", err.Error())
+		return nil
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return &rows[0]
+}
+
+// archiveGap returns the number of WAL segments the instance archived after the newest segment in the
+// repository, which should be 0 unless archiving to the repository falls behind or archives elsewhere
+func archiveGap(archive *Archive, archiver *archiverStats) (int64, bool) {
+	if archive == nil || archive.Max == nil || archiver.LastArchivedWal == nil || archiver.WalSegmentSize == nil {
+		return 0, false
+	}
+
+	archived, ok := walSegmentNumber(*archiver.LastArchivedWal, *archiver.WalSegmentSize)
+	if !ok {
+		return 0, false
+	}
+	inRepository, ok := walSegmentNumber(*archive.Max, *archiver.WalSegmentSize)
+	if !ok {
+		return 0, false
+	}
+
+	if archived < inRepository {
+		return 0, true
+	}
+	return archived - inRepository, true
+}
+
+// walSegmentNumber returns the position of a WAL segment from its file name, made of the timeline, the log
+// and the segment within the log in hexadecimal. The timeline is ignored, as segment numbers continue across
+// timelines. History files have no segment number.
+func walSegmentNumber(walFileName string, walSegmentSize int64) (int64, bool) {
+	if len(walFileName) < 24 || walSegmentSize <= 0 {
+		return 0, false
+	}
+
+	logID, err := strconv.ParseInt(walFileName[8:16], 16, 64)
+	if err != nil {
+		return 0, false
+	}
+	segment, err := strconv.ParseInt(walFileName[16:24], 16, 64)
+	if err != nil {
+		return 0, false
+	}
+	return logID*(0x100000000/walSegmentSize) + segment, true
+}
+
+func setMetric(metricSet *metric.Set, name string, value interface{}, sourceType metric.SourceType) {
+	if err := metricSet.SetMetric(name, value, sourceType); err != nil {
+		log.Error("Failed to populate backup metric %s: %s", name, err.Error())
+	}
+}
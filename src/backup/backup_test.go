@@ -0,0 +1,115 @@
+package backup
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/integration"
+	"github.com/newrelic/nri-postgresql/src/connection"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestPopulateMetrics(t *testing.T) {
+	stanzas, err := ReadPgBackRestInfo(&recordedRunner{output: readRecordedInfo(t)}, "pgbackrest info --output=json", "")
+	assert.NoError(t, err)
+
+	testConnection, mock := connection.CreateMockSQL(t)
+	mock.ExpectQuery(regexp.QuoteMeta(archiverQuery)).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"archived_count", "last_archived_wal", "seconds_since_last_archived", "failed_count", "last_failed_wal",
+			"seconds_since_last_failure", "wal_segment_size",
+		}).AddRow(120, "000000020000000100000021", 42.5, 1, "000000020000000100000019", 3600.0, 16777216))
+
+	testIntegration, _ := integration.New("test", "test")
+	instance, _ := testIntegration.Entity("testhost:1234", "pg-instance")
+
+	PopulateMetrics(stanzas, testConnection, instance)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	assert.Len(t, instance.Metrics, 2)
+	stanza := instance.Metrics[0].Metrics
+	assert.Greater(t, stanza["backup.secondsSinceLastFullBackup"], float64(0))
+	assert.Equal(t, stanza["backup.secondsSinceLastDifferentialBackup"], stanza["backup.secondsSinceLastSuccessfulBackup"])
+	for _, name := range []string{"backup.secondsSinceLastFullBackup", "backup.secondsSinceLastDifferentialBackup", "backup.secondsSinceLastSuccessfulBackup"} {
+		delete(stanza, name)
+	}
+	assert.Equal(t, map[string]interface{}{
+		"event_type":                                          "PostgresqlBackupSample",
+		"displayName":                                         "testhost:1234",
+		"entityName":                                          "pg-instance:testhost:1234",
+		"backup.stanza":                                       "main",
+		"backup.statusCode":                                   float64(0),
+		"backup.status":                                       "ok",
+		"backup.lastFullBackupLabel":                          "20261017-010000F",
+		"backup.lastFullBackupDurationInSeconds":              float64(600),
+		"backup.lastFullBackupSizeInBytes":                    float64(31457280),
+		"backup.lastFullBackupDeltaInBytes":                   float64(31457280),
+		"backup.lastFullBackupRepositorySizeInBytes":          float64(4194304),
+		"backup.lastFullBackupRepositoryDeltaInBytes":         float64(4194304),
+		"backup.lastDifferentialBackupLabel":                  "20261017-010000F_20261018-010000D",
+		"backup.lastDifferentialBackupDurationInSeconds":      float64(60),
+		"backup.lastDifferentialBackupSizeInBytes":            float64(33554432),
+		"backup.lastDifferentialBackupDeltaInBytes":           float64(8388608),
+		"backup.lastDifferentialBackupRepositorySizeInBytes":  float64(4718592),
+		"backup.lastDifferentialBackupRepositoryDeltaInBytes": float64(1048576),
+		"backup.archiveMin":                                   "000000020000000100000002",
+		"backup.archiveMax":                                   "00000002000000010000001E",
+		"backup.archiver.archivedCount":                       float64(120),
+		"backup.archiver.lastArchivedWal":                     "000000020000000100000021",
+		"backup.archiver.secondsSinceLastArchived":            42.5,
+		"backup.archiver.failedCount":                         float64(1),
+		"backup.archiver.lastFailedWal":                       "000000020000000100000019",
+		"backup.archiver.secondsSinceLastFailure":             float64(3600),
+		"backup.archiveGapSegments":                           float64(3),
+	}, stanza)
+
+	empty := instance.Metrics[1].Metrics
+	assert.Equal(t, "empty", empty["backup.stanza"])
+	assert.Equal(t, float64(1), empty["backup.statusCode"])
+	assert.Equal(t, "missing stanza path", empty["backup.status"])
+	assert.NotContains(t, empty, "backup.secondsSinceLastSuccessfulBackup")
+	assert.NotContains(t, empty, "backup.archiveGapSegments")
+}
+
+func TestPopulateMetrics_ArchiverUnavailable(t *testing.T) {
+	stanzas, err := ReadPgBackRestInfo(&recordedRunner{output: readRecordedInfo(t)}, "pgbackrest info --output=json", "")
+	assert.NoError(t, err)
+
+	testConnection, mock := connection.CreateMockSQL(t)
+	mock.ExpectQuery(regexp.QuoteMeta(archiverQuery)).WillReturnError(errors.New("permission denied"))
+
+	testIntegration, _ := integration.New("test", "test")
+	instance, _ := testIntegration.Entity("testhost:1234", "pg-instance")
+
+	PopulateMetrics(stanzas, testConnection, instance)
+
+	assert.Len(t, instance.Metrics, 2)
+	assert.Equal(t, "20261017-010000F", instance.Metrics[0].Metrics["backup.lastFullBackupLabel"])
+	assert.NotContains(t, instance.Metrics[0].Metrics, "backup.archiver.archivedCount")
+	assert.NotContains(t, instance.Metrics[0].Metrics, "backup.archiveGapSegments")
+}
+
+func TestWalSegmentNumber(t *testing.T) {
+	testCases := []struct {
+		name           string
+		walFileName    string
+		walSegmentSize int64
+		want           int64
+		wantOk         bool
+	}{
+		{"16MB segments", "000000010000000100000002", 16 * 1024 * 1024, 258, true},
+		{"1GB segments", "000000010000000100000002", 1024 * 1024 * 1024, 6, true},
+		{"Timeline ignored", "0000000A0000000100000002", 16 * 1024 * 1024, 258, true},
+		{"Backup history file", "000000010000000100000002.00000028.backup", 16 * 1024 * 1024, 258, true},
+		{"Timeline history file", "00000002.history", 16 * 1024 * 1024, 0, false},
+		{"Not hexadecimal", "0000000100000001000000ZZ", 16 * 1024 * 1024, 0, false},
+	}
+
+	for _, tc := range testCases {
+		got, ok := walSegmentNumber(tc.walFileName, tc.walSegmentSize)
+		assert.Equal(t, tc.wantOk, ok, tc.name)
+		assert.Equal(t, tc.want, got, tc.name)
+	}
+}
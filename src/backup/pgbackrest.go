@@ -0,0 +1,96 @@
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Stanza is a stanza of pgbackrest info --output=json
+type Stanza struct {
+	Name   string `json:"name"`
+	Status struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"status"`
+	Archive []Archive `json:"archive"`
+	Backup  []Backup  `json:"backup"`
+}
+
+// Archive is the range of WAL segments in the repository for a database of the stanza
+type Archive struct {
+	ID  string  `json:"id"`
+	Min *string `json:"min"`
+	Max *string `json:"max"`
+}
+
+// Backup is a backup of the stanza. Type is full, diff or incr, the timestamps are Unix times in seconds and
+// Error is set when page checksum errors were found during the backup, since pgBackRest 2.36.
+type Backup struct {
+	Label     string `json:"label"`
+	Type      string `json:"type"`
+	Error     bool   `json:"error"`
+	Timestamp struct {
+		Start int64 `json:"start"`
+		Stop  int64 `json:"stop"`
+	} `json:"timestamp"`
+	Info struct {
+		Size       int64 `json:"size"`
+		Delta      int64 `json:"delta"`
+		Repository struct {
+			Size  int64 `json:"size"`
+			Delta int64 `json:"delta"`
+		} `json:"repository"`
+	} `json:"info"`
+}
+
+// LastBackup returns the most recent backup of the given type without errors, or of any type when
+// backupType is empty
+func (s Stanza) LastBackup(backupType string) *Backup {
+	var last *Backup
+	for i, backup := range s.Backup {
+		if backup.Error || (backupType != "" && backup.Type != backupType) {
+			continue
+		}
+		if last == nil || backup.Timestamp.Stop > last.Timestamp.Stop {
+			last = &s.Backup[i]
+		}
+	}
+	return last
+}
+
+// CurrentArchive returns the archive of the current database of the stanza, which pgBackRest lists last
+func (s Stanza) CurrentArchive() *Archive {
+	if len(s.Archive) == 0 {
+		return nil
+	}
+	return &s.Archive[len(s.Archive)-1]
+}
+
+// ReadPgBackRestInfo reads the stanzas from infoFile, a file with the output of pgbackrest info --output=json,
+// when set, and otherwise from the output of command run with runner
+func ReadPgBackRestInfo(runner CommandRunner, command, infoFile string) ([]Stanza, error) {
+	var output []byte
+	var err error
+	if infoFile != "" {
+		if output, err = os.ReadFile(infoFile); err != nil {
+			return nil, fmt.Errorf("failed to read pgBackRest info file: %w", err)
+		}
+	} else {
+		fields := strings.Fields(command)
+		if len(fields) == 0 {
+			return nil, errors.New("no pgBackRest info command or file configured")
+		}
+		if output, err = runner.Run(fields[0], fields[1:]...); err != nil {
+			return nil, fmt.Errorf("failed to run pgBackRest info command: %w", err)
+		}
+	}
+
+	stanzas := make([]Stanza, 0)
+	if err := json.Unmarshal(output, &stanzas); err != nil {
+		return nil, fmt.Errorf("failed to parse pgBackRest info: %w", err)
+	}
+	return stanzas, nil
+}
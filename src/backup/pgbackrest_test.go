@@ -0,0 +1,86 @@
+package backup
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordedRunner returns a recorded output instead of running the command
+type recordedRunner struct {
+	output []byte
+	err    error
+	name   string
+	args   []string
+}
+
+func (r *recordedRunner) Run(name string, args ...string) ([]byte, error) {
+	r.name, r.args = name, args
+	return r.output, r.err
+}
+
+func readRecordedInfo(t *testing.T) []byte {
+	output, err := os.ReadFile(filepath.Join("testdata", "pgbackrest_info.json"))
+	assert.NoError(t, err)
+	return output
+}
+
+func TestReadPgBackRestInfo_Command(t *testing.T) {
+	runner := &recordedRunner{output: readRecordedInfo(t)}
+
+	stanzas, err := ReadPgBackRestInfo(runner, "pgbackrest --config=/etc/pgbackrest.conf info --output=json", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "pgbackrest", runner.name)
+	assert.Equal(t, []string{"--config=/etc/pgbackrest.conf", "info", "--output=json"}, runner.args)
+
+	assert.Len(t, stanzas, 2)
+	stanza := stanzas[0]
+	assert.Equal(t, "main", stanza.Name)
+	assert.Equal(t, "ok", stanza.Status.Message)
+	assert.Equal(t, "20261017-010000F", stanza.LastBackup("full").Label)
+	assert.Equal(t, "20261017-010000F_20261018-010000D", stanza.LastBackup("diff").Label)
+	assert.Nil(t, stanza.LastBackup("incr"), "backups with errors are not successful")
+	assert.Equal(t, "20261017-010000F_20261018-010000D", stanza.LastBackup("").Label)
+	assert.Equal(t, "16-2", stanza.CurrentArchive().ID)
+	assert.Equal(t, "00000002000000010000001E", *stanza.CurrentArchive().Max)
+
+	empty := stanzas[1]
+	assert.Equal(t, 1, empty.Status.Code)
+	assert.Nil(t, empty.LastBackup(""))
+	assert.Nil(t, empty.CurrentArchive())
+}
+
+func TestReadPgBackRestInfo_File(t *testing.T) {
+	runner := &recordedRunner{err: errors.New("should not run")}
+
+	stanzas, err := ReadPgBackRestInfo(runner, "pgbackrest info --output=json", filepath.Join("testdata", "pgbackrest_info.json"))
+	assert.NoError(t, err)
+	assert.Len(t, stanzas, 2)
+	assert.Empty(t, runner.name)
+}
+
+func TestReadPgBackRestInfo_Errors(t *testing.T) {
+	_, err := ReadPgBackRestInfo(&recordedRunner{err: errors.New("exit status 1")}, "pgbackrest info --output=json", "")
+	assert.Error(t, err)
+
+	_, err = ReadPgBackRestInfo(&recordedRunner{output: []byte("stanza: main\n    status: ok")}, "pgbackrest info", "")
+	assert.Error(t, err)
+
+	_, err = ReadPgBackRestInfo(&recordedRunner{}, " ", "")
+	assert.Error(t, err)
+
+	_, err = ReadPgBackRestInfo(&recordedRunner{}, "", filepath.Join("testdata", "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestExecRunner(t *testing.T) {
+	output, err := ExecRunner{}.Run("echo", "[]")
+	assert.NoError(t, err)
+	assert.Equal(t, "[]\n", string(output))
+
+	_, err = ExecRunner{}.Run("sh", "-c", "echo failed >&2; exit 1")
+	assert.ErrorContains(t, err, "failed")
+}
@@ -0,0 +1,38 @@
+// Package backup contains the collection of backup freshness from pgBackRest and the WAL archiver of PostgreSQL
+package backup
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// CommandRunner runs a command and returns its standard output
+type CommandRunner interface {
+	Run(name string, args ...string) ([]byte, error)
+}
+
+// ExecRunner runs commands as processes, killing them when they last longer than Timeout
+type ExecRunner struct {
+	Timeout time.Duration
+}
+
+// Run runs the command and returns its standard output. The standard error is part of the error when it fails.
+func (r ExecRunner) Run(name string, args ...string) ([]byte, error) {
+	ctx := context.Background()
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+
+	output, err := exec.CommandContext(ctx, name, args...).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("%w: %s", err, exitErr.Stderr)
+		}
+		return nil, err
+	}
+	return output, nil
+}
@@ -31,6 +31,11 @@ type SchemaList map[string]TableList
 // TableList is a map from table name to an array of indexes to collect
 type TableList map[string][]string
 
+// FunctionList is a map from database name to a map from schema name to the functions to collect.
+// A function is given either by name, which matches all of its overloads, or by its signature
+// made of its argument types as returned by oidvectortypes, without argument names, e.g. "my_function(integer, text)"
+type FunctionList map[string]map[string][]string
+
 // ignoreList is a map to store items to be ignored during collection
 type ignoreList map[string]struct{}
 
@@ -78,6 +83,21 @@ func BuildCollectionList(al args.ArgumentList, ci connection.Info) (DatabaseList
 	return dbList, nil
 }
 
+// BuildFunctionCollectionList unmarshals the collection_function_list from the args. Databases
+// missing from the list have their top functions by total time collected instead
+func BuildFunctionCollectionList(al args.ArgumentList) (FunctionList, error) {
+	functionList := FunctionList{}
+	if al.CollectionFunctionList == "" {
+		return functionList, nil
+	}
+
+	if err := json.Unmarshal([]byte(al.CollectionFunctionList), &functionList); err != nil {
+		return nil, fmt.Errorf("failed to parse function collection list: %w", err)
+	}
+
+	return functionList, nil
+}
+
 func parseIgnoreList(list string) (ignoreList, error) {
 	ignoreItems := []string{}
 	ignoreMap := ignoreList{}
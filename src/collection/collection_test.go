@@ -214,3 +214,28 @@ func TestBuildCollectionList_IgnoreTables(t *testing.T) {
 	assert.NoError(t, mock1.ExpectationsWereMet())
 	ci.AssertExpectations(t)
 }
+
+func TestBuildFunctionCollectionList(t *testing.T) {
+	al := args.ArgumentList{
+		CollectionFunctionList: `{"database1": {"public": ["my_function", "other_function(integer, text)"]}}`,
+	}
+
+	expected := FunctionList{
+		"database1": {
+			"public": []string{"my_function", "other_function(integer, text)"},
+		},
+	}
+
+	fl, err := BuildFunctionCollectionList(al)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, fl)
+}
+
+func TestBuildFunctionCollectionList_Invalid(t *testing.T) {
+	al := args.ArgumentList{
+		CollectionFunctionList: `["database1"]`,
+	}
+
+	_, err := BuildFunctionCollectionList(al)
+	assert.NotNil(t, err)
+}
@@ -19,6 +19,9 @@ const (
            e.extname AS extension
       FROM pg_extension AS e
       JOIN pg_namespace AS n ON n.oid = e.extnamespace;`
+
+	// pgbouncerDatabase is the database of the PgBouncer admin console
+	pgbouncerDatabase = "pgbouncer"
 )
 
 // PGSQLConnection represents a wrapper around a PostgreSQL connection
@@ -64,6 +67,55 @@ func DefaultConnectionInfo(al *args.ArgumentList) Info {
 	}
 }
 
+// PgBouncerConnectionInfo returns the connection of each PgBouncer instance to collect. When no PgBouncer endpoint is
+// set PgBouncer is reached with the PostgreSQL connection settings.
+func PgBouncerConnectionInfo(al *args.ArgumentList) ([]Info, error) {
+	if !al.HasPgBouncerSettings() {
+		return []Info{DefaultConnectionInfo(al)}, nil
+	}
+
+	instances, err := al.GetPgBouncerInstances()
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]Info, 0, len(instances))
+	for _, instance := range instances {
+		infos = append(infos, &connectionInfo{
+			Database:               pgbouncerDatabase,
+			Username:               instance.Username,
+			Password:               instance.Password,
+			Host:                   instance.Hostname,
+			Port:                   instance.Port,
+			Timeout:                al.Timeout,
+			EnableSSL:              al.PgbouncerEnableSSL,
+			SSLCertLocation:        al.PgbouncerSSLCertLocation,
+			SSLRootCertLocation:    al.PgbouncerSSLRootCertLocation,
+			SSLKeyLocation:         al.PgbouncerSSLKeyLocation,
+			TrustServerCertificate: al.PgbouncerTrustServerCertificate,
+		})
+	}
+	return infos, nil
+}
+
+// PgpoolConnectionInfo returns the connection to Pgpool-II, which takes the settings it doesn't set from the PostgreSQL ones
+func PgpoolConnectionInfo(al *args.ArgumentList) Info {
+	ci := DefaultConnectionInfo(al).(*connectionInfo)
+	if al.PgpoolHostname != "" {
+		ci.Host = al.PgpoolHostname
+	}
+	if al.PgpoolPort != "" {
+		ci.Port = al.PgpoolPort
+	}
+	if al.PgpoolUsername != "" {
+		ci.Username = al.PgpoolUsername
+	}
+	if al.PgpoolPassword != "" {
+		ci.Password = al.PgpoolPassword
+	}
+	return ci
+}
+
 // NewConnection creates a new PGSQLConnection from args
 func (ci *connectionInfo) NewConnection(database string) (*PGSQLConnection, error) {
 	db, err := sqlx.Open("postgres", createConnectionURL(ci, database))
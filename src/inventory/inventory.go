@@ -8,6 +8,8 @@ import (
 
 const (
 	configQuery = `SELECT name, setting, boot_val, reset_val FROM pg_settings`
+
+	pgbouncerConfigQuery = `SHOW CONFIG;`
 )
 
 type configQueryRow struct {
@@ -17,6 +19,13 @@ type configQueryRow struct {
 	ResetVal interface{} `db:"reset_val"`
 }
 
+// pgbouncerConfigRow is a setting of SHOW CONFIG. The default column was added in PgBouncer 1.17.
+type pgbouncerConfigRow struct {
+	Key     string      `db:"key"`
+	Value   interface{} `db:"value"`
+	Default interface{} `db:"default"`
+}
+
 // PopulateInventory collects all the configuration and populates the instance entity
 func PopulateInventory(entity *integration.Entity, connection *connection.PGSQLConnection) {
 	configRows := make([]*configQueryRow, 0)
@@ -31,6 +40,22 @@ func PopulateInventory(entity *integration.Entity, connection *connection.PGSQLC
 	}
 }
 
+// PopulatePgBouncerInventory collects the configuration of PgBouncer and populates the PgBouncer instance entity
+func PopulatePgBouncerInventory(entity *integration.Entity, connection *connection.PGSQLConnection) {
+	configRows := make([]*pgbouncerConfigRow, 0)
+	// Use QueryUnsafe as the columns of SHOW CONFIG vary between PgBouncer versions
+	if err := connection.QueryUnsafe(&configRows, pgbouncerConfigQuery); err != nil {
+		log.Error("Failed to execute pgbouncer config query: %v", err)
+	}
+
+	for _, row := range configRows {
+		logInventoryFailure(entity.SetInventoryItem(row.Key+"/value", "value", row.Value))
+		if row.Default != nil {
+			logInventoryFailure(entity.SetInventoryItem(row.Key+"/default", "value", row.Default))
+		}
+	}
+}
+
 func logInventoryFailure(err error) {
 	if err != nil {
 		log.Error("Failed set inventory item: %v", err)
@@ -48,3 +48,57 @@ func TestPopulateInventory(t *testing.T) {
 
 	assert.Equal(t, expected, testEntity.Inventory.Items())
 }
+
+func TestPopulatePgBouncerInventory(t *testing.T) {
+	testIntegration, _ := integration.New("test", "0.1.0")
+	testEntity, _ := testIntegration.Entity("test", "pgbouncer-instance")
+
+	testConnection, mock := connection.CreateMockSQL(t)
+
+	configRows := sqlmock.NewRows([]string{"key", "value", "default", "changeable"}).
+		AddRow("max_client_conn", "500", "100", "yes").
+		AddRow("pool_mode", "transaction", "session", "yes")
+
+	mock.ExpectQuery(pgbouncerConfigQuery).WillReturnRows(configRows)
+
+	PopulatePgBouncerInventory(testEntity, testConnection)
+
+	expected := inventory.Items{
+		"max_client_conn/value": {
+			"value": "500",
+		},
+		"max_client_conn/default": {
+			"value": "100",
+		},
+		"pool_mode/value": {
+			"value": "transaction",
+		},
+		"pool_mode/default": {
+			"value": "session",
+		},
+	}
+
+	assert.Equal(t, expected, testEntity.Inventory.Items())
+}
+
+func TestPopulatePgBouncerInventory_NoDefaultColumn(t *testing.T) {
+	testIntegration, _ := integration.New("test", "0.1.0")
+	testEntity, _ := testIntegration.Entity("test", "pgbouncer-instance")
+
+	testConnection, mock := connection.CreateMockSQL(t)
+
+	configRows := sqlmock.NewRows([]string{"key", "value", "changeable"}).
+		AddRow("max_client_conn", "500", "yes")
+
+	mock.ExpectQuery(pgbouncerConfigQuery).WillReturnRows(configRows)
+
+	PopulatePgBouncerInventory(testEntity, testConnection)
+
+	expected := inventory.Items{
+		"max_client_conn/value": {
+			"value": "500",
+		},
+	}
+
+	assert.Equal(t, expected, testEntity.Inventory.Items())
+}
@@ -4,21 +4,30 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
+	"time"
 
 	queryperformancemonitoring "github.com/newrelic/nri-postgresql/src/query-performance-monitoring"
 
 	"github.com/newrelic/infra-integrations-sdk/v3/integration"
 	"github.com/newrelic/infra-integrations-sdk/v3/log"
+	"github.com/newrelic/infra-integrations-sdk/v3/persist"
 	"github.com/newrelic/nri-postgresql/src/args"
+	"github.com/newrelic/nri-postgresql/src/backup"
 	"github.com/newrelic/nri-postgresql/src/collection"
 	"github.com/newrelic/nri-postgresql/src/connection"
 	"github.com/newrelic/nri-postgresql/src/inventory"
 	"github.com/newrelic/nri-postgresql/src/metrics"
+	"github.com/newrelic/nri-postgresql/src/patroni"
 )
 
 const (
 	integrationName = "com.newrelic.postgresql"
+	// stateStoreName must not start with integrationName, otherwise the SDK removes the state
+	// store file together with its own old store files
+	stateStoreName = "nri-postgresql-state"
+	stateStoreTTL  = 24 * time.Hour
 )
 
 var (
@@ -62,6 +71,18 @@ func main() {
 	collectionList, err := collection.BuildCollectionList(args, connectionInfo)
 	if err != nil {
 		log.Error("Error creating list of entities to collect: %s", err)
+		// PgBouncer and Pgpool-II don't depend on PostgreSQL, so they are still reported when PostgreSQL is unreachable
+		if args.Pgbouncer || args.Pgpool {
+			collectPoolers(pgIntegration, args)
+			if err = pgIntegration.Publish(); err != nil {
+				log.Error(err.Error())
+			}
+		}
+		os.Exit(1)
+	}
+	functionList, err := collection.BuildFunctionCollectionList(args)
+	if err != nil {
+		log.Error("Error creating list of functions to collect: %s", err)
 		os.Exit(1)
 	}
 	instance, err := pgIntegration.Entity(fmt.Sprintf("%s:%s", args.Hostname, args.Port), "pg-instance")
@@ -69,11 +90,26 @@ func main() {
 		log.Error("Error creating instance entity: %s", err.Error())
 		os.Exit(1)
 	}
+	stateStore := newStateStore(pgIntegration, args)
+
 	if args.HasMetrics() {
-		metrics.PopulateMetrics(connectionInfo, collectionList, instance, pgIntegration, args.Pgbouncer, args.CollectDbLockMetrics, args.CollectBloatMetrics, args.CustomMetricsQuery)
+		metrics.PopulateMetrics(connectionInfo, collectionList, instance, pgIntegration, args.CollectDbLockMetrics, args.CollectBloatMetrics, args.CustomMetricsQuery)
+		metrics.PopulateFunctionMetrics(collectionList, functionList, args.CollectionFunctionTopN, pgIntegration, connectionInfo)
+		if args.CollectExactBloatMetrics {
+			metrics.PopulateExactBloatMetrics(collectionList, args.ExactBloatMaxRelationSizeMb, args.ExactBloatRelationsPerRun, pgIntegration, connectionInfo, stateStore)
+		}
+		if args.CollectBufferCacheMetrics {
+			metrics.PopulateBufferCacheMetrics(collectionList, args.BufferCacheIntervalMinutes, args.BufferCacheRelationsPerDatabase, pgIntegration, instance, connectionInfo, stateStore)
+		}
 		if args.CustomMetricsConfig != "" {
 			metrics.PopulateCustomMetricsFromFile(connectionInfo, args.CustomMetricsConfig, pgIntegration)
 		}
+		if args.PatroniURL != "" {
+			collectPatroni(instance, args, stateStore)
+		}
+		if args.PgbackrestInfoCommand != "" || args.PgbackrestInfoFile != "" {
+			collectBackups(instance, connectionInfo, args)
+		}
 	}
 
 	if args.HasInventory() {
@@ -86,12 +122,125 @@ func main() {
 		}
 	}
 
+	collectPoolers(pgIntegration, args)
+
 	if err = pgIntegration.Publish(); err != nil {
 		log.Error(err.Error())
 	}
 
+	if err = stateStore.Save(); err != nil {
+		log.Error("Error saving state store: %s", err.Error())
+	}
+
 	if args.EnableQueryMonitoring {
 		queryperformancemonitoring.QueryPerformanceMain(args, pgIntegration, collectionList)
 	}
 
 }
+
+// collectPatroni collects the state of the Patroni cluster of the instance from the Patroni REST API
+func collectPatroni(instance *integration.Entity, al args.ArgumentList, stateStore persist.Storer) {
+	timeout, err := strconv.Atoi(al.Timeout)
+	if err != nil {
+		log.Warn("Invalid timeout %s, using the default Patroni timeout: %s", al.Timeout, err.Error())
+		timeout = 10
+	}
+
+	client, err := patroni.NewClient(al.PatroniURL, time.Duration(timeout)*time.Second, al.PatroniCACertLocation)
+	if err != nil {
+		log.Error("Patroni collection failed: %s", err.Error())
+		return
+	}
+
+	patroni.PopulateMetrics(client, instance, stateStore)
+}
+
+// collectBackups collects the freshness of the pgBackRest backups of the instance and compares the WAL archive
+// in the repository with the archiver of the instance
+func collectBackups(instance *integration.Entity, ci connection.Info, al args.ArgumentList) {
+	runner := backup.ExecRunner{Timeout: time.Duration(al.PgbackrestInfoTimeout) * time.Second}
+	stanzas, err := backup.ReadPgBackRestInfo(runner, al.PgbackrestInfoCommand, al.PgbackrestInfoFile)
+	if err != nil {
+		log.Error("Backup collection failed: %s", err.Error())
+		return
+	}
+
+	con, err := ci.NewConnection(ci.DatabaseName())
+	if err != nil {
+		log.Error("Backup collection failed: error creating connection to PostgreSQL: %s", err.Error())
+		return
+	}
+	defer con.Close()
+
+	backup.PopulateMetrics(stanzas, con, instance)
+}
+
+// collectPoolers collects the connection poolers in front of PostgreSQL that are enabled
+func collectPoolers(pgIntegration *integration.Integration, al args.ArgumentList) {
+	if al.Pgbouncer {
+		collectPgBouncer(pgIntegration, al)
+	}
+	if al.Pgpool && al.HasMetrics() {
+		collectPgpool(pgIntegration, al)
+	}
+}
+
+// collectPgpool collects the backend nodes and processes of Pgpool-II with its SHOW commands
+func collectPgpool(pgIntegration *integration.Integration, al args.ArgumentList) {
+	ci := connection.PgpoolConnectionInfo(&al)
+	con, err := ci.NewConnection(ci.DatabaseName())
+	if err != nil {
+		log.Error("Error creating connection to pgpool: %s", err.Error())
+		return
+	}
+	defer con.Close()
+
+	metrics.PopulatePgpoolMetrics(pgIntegration, con, ci)
+}
+
+// collectPgBouncer collects the metrics and inventory of each PgBouncer instance through its admin console. It
+// does not depend on the PostgreSQL connection, so PgBouncer is still collected when PostgreSQL is unreachable.
+func collectPgBouncer(pgIntegration *integration.Integration, al args.ArgumentList) {
+	connectionInfos, err := connection.PgBouncerConnectionInfo(&al)
+	if err != nil {
+		log.Error("PgBouncer collection failed: %s", err.Error())
+		return
+	}
+
+	for _, ci := range connectionInfos {
+		con, err := ci.NewConnection("pgbouncer")
+		if err != nil {
+			log.Error("Error creating connection to pgbouncer database: %s", err.Error())
+			continue
+		}
+
+		if al.HasMetrics() {
+			metrics.PopulatePgBouncerMetrics(pgIntegration, con, ci)
+		}
+		if al.HasInventory() {
+			if entity, err := metrics.PgBouncerInstanceEntity(pgIntegration, ci); err != nil {
+				log.Error("PgBouncer inventory collection failed: error creating pgbouncer instance entity: %s", err.Error())
+			} else {
+				inventory.PopulatePgBouncerInventory(entity, con)
+			}
+		}
+		con.Close()
+	}
+}
+
+// newStateStore returns the store that keeps collection state between runs, such as the relations
+// already measured for exact bloat. Its entries need to outlive the cache TTL of the integration store.
+func newStateStore(pgIntegration *integration.Integration, al args.ArgumentList) persist.Storer {
+	logger := log.NewStdErr(al.Verbose)
+	storePath, err := persist.NewStorePath(stateStoreName, pgIntegration.CreateUniqueID(), al.TempDir, logger, stateStoreTTL)
+	if err == nil {
+		storePath.CleanOldFiles()
+		var store persist.Storer
+		if store, err = persist.NewFileStore(storePath.GetFilePath(), logger, stateStoreTTL); err == nil {
+			return store
+		}
+	}
+
+	log.Warn("Failed to create state store, state will not be kept between runs: %s", err.Error())
+	return persist.NewInMemoryStore()
+}
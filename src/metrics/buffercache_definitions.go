@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"github.com/newrelic/nri-postgresql/src/collection"
+)
+
+func generateBufferCacheDefinitions(schemaList collection.SchemaList, limit int) []*QueryDefinition {
+	queryDefinitions := make([]*QueryDefinition, 0, 2)
+	if def := bufferCacheTableDefinition.insertSchemaTables(schemaList); def != nil {
+		queryDefinitions = append(queryDefinitions, def.insertLimit(limit))
+	}
+
+	if def := bufferCacheIndexDefinition.insertSchemaTableIndexes(schemaList); def != nil {
+		queryDefinitions = append(queryDefinitions, def.insertLimit(limit))
+	}
+
+	return queryDefinitions
+}
+
+// bufferCacheBase is the shared buffer residency of a relation. The usage counts go from 0 to 5 and
+// buffers with a low usage count are the first ones evicted.
+type bufferCacheBase struct {
+	Buffers              *int64   `db:"buffers"                metric_name:"bufferCache.buffers"              source_type:"gauge"`
+	SharedBuffersPercent *float64 `db:"shared_buffers_percent" metric_name:"bufferCache.sharedBuffersPercent" source_type:"gauge"`
+	DirtyBuffers         *int64   `db:"dirty_buffers"          metric_name:"bufferCache.dirtyBuffers"         source_type:"gauge"`
+	UsageCount0          *int64   `db:"usage_count_0"          metric_name:"bufferCache.usageCount0Buffers"   source_type:"gauge"`
+	UsageCount1          *int64   `db:"usage_count_1"          metric_name:"bufferCache.usageCount1Buffers"   source_type:"gauge"`
+	UsageCount2          *int64   `db:"usage_count_2"          metric_name:"bufferCache.usageCount2Buffers"   source_type:"gauge"`
+	UsageCount3          *int64   `db:"usage_count_3"          metric_name:"bufferCache.usageCount3Buffers"   source_type:"gauge"`
+	UsageCount4          *int64   `db:"usage_count_4"          metric_name:"bufferCache.usageCount4Buffers"   source_type:"gauge"`
+	UsageCount5          *int64   `db:"usage_count_5"          metric_name:"bufferCache.usageCount5Buffers"   source_type:"gauge"`
+}
+
+// bufferCacheTableDefinition aggregates the buffers of the current database by relfilenode before
+// matching them to relations, and returns the tables holding the most buffers first.
+var bufferCacheTableDefinition = &QueryDefinition{
+	query: `SELECT -- BUFFERCACHE_TABLES
+			current_database() AS database,
+			N.nspname AS schema_name,
+			C.relname AS table_name,
+			B.buffers AS buffers,
+			100.0 * B.buffers / S.shared_buffers AS shared_buffers_percent,
+			B.dirty_buffers AS dirty_buffers,
+			B.usage_count_0, B.usage_count_1, B.usage_count_2, B.usage_count_3, B.usage_count_4, B.usage_count_5
+		FROM (
+			SELECT relfilenode,
+				count(*) AS buffers,
+				sum(CASE WHEN isdirty THEN 1 ELSE 0 END) AS dirty_buffers,
+				sum(CASE WHEN usagecount = 0 THEN 1 ELSE 0 END) AS usage_count_0,
+				sum(CASE WHEN usagecount = 1 THEN 1 ELSE 0 END) AS usage_count_1,
+				sum(CASE WHEN usagecount = 2 THEN 1 ELSE 0 END) AS usage_count_2,
+				sum(CASE WHEN usagecount = 3 THEN 1 ELSE 0 END) AS usage_count_3,
+				sum(CASE WHEN usagecount = 4 THEN 1 ELSE 0 END) AS usage_count_4,
+				sum(CASE WHEN usagecount = 5 THEN 1 ELSE 0 END) AS usage_count_5
+			FROM public.pg_buffercache
+			WHERE reldatabase = (SELECT oid FROM pg_database WHERE datname = current_database())
+			GROUP BY relfilenode
+		) B
+		JOIN pg_class C ON pg_relation_filenode(C.oid) = B.relfilenode
+		JOIN pg_namespace N ON N.oid = C.relnamespace
+		CROSS JOIN (SELECT setting::numeric AS shared_buffers FROM pg_settings WHERE name = 'shared_buffers') S
+		WHERE C.relkind = 'r' AND N.nspname || '.' || C.relname IN (%SCHEMA_TABLES%)
+		ORDER BY B.buffers DESC
+		LIMIT %LIMIT%;`,
+
+	dataModels: []struct {
+		databaseBase
+		schemaBase
+		tableBase
+		bufferCacheBase
+	}{},
+}
+
+var bufferCacheIndexDefinition = &QueryDefinition{
+	query: `SELECT -- BUFFERCACHE_INDEXES
+			current_database() AS database,
+			N.nspname AS schema_name,
+			T.relname AS table_name,
+			I.relname AS index_name,
+			B.buffers AS buffers,
+			100.0 * B.buffers / S.shared_buffers AS shared_buffers_percent,
+			B.dirty_buffers AS dirty_buffers,
+			B.usage_count_0, B.usage_count_1, B.usage_count_2, B.usage_count_3, B.usage_count_4, B.usage_count_5
+		FROM (
+			SELECT relfilenode,
+				count(*) AS buffers,
+				sum(CASE WHEN isdirty THEN 1 ELSE 0 END) AS dirty_buffers,
+				sum(CASE WHEN usagecount = 0 THEN 1 ELSE 0 END) AS usage_count_0,
+				sum(CASE WHEN usagecount = 1 THEN 1 ELSE 0 END) AS usage_count_1,
+				sum(CASE WHEN usagecount = 2 THEN 1 ELSE 0 END) AS usage_count_2,
+				sum(CASE WHEN usagecount = 3 THEN 1 ELSE 0 END) AS usage_count_3,
+				sum(CASE WHEN usagecount = 4 THEN 1 ELSE 0 END) AS usage_count_4,
+				sum(CASE WHEN usagecount = 5 THEN 1 ELSE 0 END) AS usage_count_5
+			FROM public.pg_buffercache
+			WHERE reldatabase = (SELECT oid FROM pg_database WHERE datname = current_database())
+			GROUP BY relfilenode
+		) B
+		JOIN pg_class I ON pg_relation_filenode(I.oid) = B.relfilenode
+		JOIN pg_index X ON X.indexrelid = I.oid
+		JOIN pg_class T ON T.oid = X.indrelid
+		JOIN pg_namespace N ON N.oid = T.relnamespace
+		CROSS JOIN (SELECT setting::numeric AS shared_buffers FROM pg_settings WHERE name = 'shared_buffers') S
+		WHERE N.nspname || '.' || T.relname || '.' || I.relname IN (%SCHEMA_TABLE_INDEXES%)
+		ORDER BY B.buffers DESC
+		LIMIT %LIMIT%;`,
+
+	dataModels: []struct {
+		databaseBase
+		schemaBase
+		tableBase
+		indexBase
+		bufferCacheBase
+	}{},
+}
+
+// bufferCacheSummaryDefinition reports the buffers used by each database. Buffers of the shared
+// catalogs have a reldatabase of 0 and unused buffers have none.
+var bufferCacheSummaryDefinition = &QueryDefinition{
+	query: `SELECT -- BUFFERCACHE_SUMMARY
+			CASE
+				WHEN B.reldatabase IS NULL THEN '(unused)'
+				WHEN B.reldatabase = 0 THEN '(shared)'
+				ELSE coalesce(D.datname, B.reldatabase::text)
+			END AS database,
+			count(*) AS buffers,
+			100.0 * count(*) / max(S.shared_buffers) AS shared_buffers_percent,
+			sum(CASE WHEN B.isdirty THEN 1 ELSE 0 END) AS dirty_buffers,
+			sum(CASE WHEN B.usagecount = 0 THEN 1 ELSE 0 END) AS usage_count_0,
+			sum(CASE WHEN B.usagecount = 1 THEN 1 ELSE 0 END) AS usage_count_1,
+			sum(CASE WHEN B.usagecount = 2 THEN 1 ELSE 0 END) AS usage_count_2,
+			sum(CASE WHEN B.usagecount = 3 THEN 1 ELSE 0 END) AS usage_count_3,
+			sum(CASE WHEN B.usagecount = 4 THEN 1 ELSE 0 END) AS usage_count_4,
+			sum(CASE WHEN B.usagecount = 5 THEN 1 ELSE 0 END) AS usage_count_5
+		FROM public.pg_buffercache B
+		LEFT JOIN pg_database D ON D.oid = B.reldatabase
+		CROSS JOIN (SELECT setting::numeric AS shared_buffers FROM pg_settings WHERE name = 'shared_buffers') S
+		GROUP BY 1;`,
+
+	dataModels: []struct {
+		Database *string `db:"database" metric_name:"database" source_type:"attribute"`
+		bufferCacheBase
+	}{},
+}
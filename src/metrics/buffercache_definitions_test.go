@@ -0,0 +1,29 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/newrelic/nri-postgresql/src/collection"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_generateBufferCacheDefinitions(t *testing.T) {
+	schemaList := collection.SchemaList{
+		"schema1": collection.TableList{
+			"table1": []string{"index1"},
+		},
+	}
+
+	queryDefinitions := generateBufferCacheDefinitions(schemaList, 5)
+	assert.Len(t, queryDefinitions, 2)
+	assert.Contains(t, queryDefinitions[0].GetQuery(), "BUFFERCACHE_TABLES")
+	assert.Contains(t, queryDefinitions[0].GetQuery(), "IN ('schema1.table1')")
+	assert.Contains(t, queryDefinitions[0].GetQuery(), "LIMIT 5;")
+	assert.Contains(t, queryDefinitions[1].GetQuery(), "BUFFERCACHE_INDEXES")
+	assert.Contains(t, queryDefinitions[1].GetQuery(), "IN ('schema1.table1.index1')")
+
+	queryDefinitions = generateBufferCacheDefinitions(collection.SchemaList{"schema1": {"table1": []string{}}}, 5)
+	assert.Len(t, queryDefinitions, 1)
+
+	assert.Empty(t, generateBufferCacheDefinitions(collection.SchemaList{}, 5))
+}
@@ -0,0 +1,200 @@
+package metrics
+
+import (
+	"github.com/blang/semver/v4"
+	"github.com/newrelic/infra-integrations-sdk/v3/log"
+	"github.com/newrelic/nri-postgresql/src/collection"
+)
+
+func generateConnectionDefinitions(databases collection.DatabaseList, version *semver.Version) []*QueryDefinition {
+	queryDefinitions := make([]*QueryDefinition, 0, 1)
+	if len(databases) == 0 {
+		return queryDefinitions
+	}
+
+	v96 := semver.MustParse("9.6.0")
+	v10 := semver.MustParse("10.0.0")
+	v16 := semver.MustParse("16.0.0")
+
+	switch {
+	case version.GE(v16):
+		queryDefinitions = append(queryDefinitions, connectionDefinitionOver16.insertDatabaseNames(databases))
+	case version.GE(v10):
+		queryDefinitions = append(queryDefinitions, connectionDefinitionOver10.insertDatabaseNames(databases))
+	case version.GE(v96):
+		queryDefinitions = append(queryDefinitions, connectionDefinitionOver96.insertDatabaseNames(databases))
+	default:
+		log.Debug("Skipping connection metrics, they require PostgreSQL 9.6 or later and the version is %s", version.String())
+	}
+
+	return queryDefinitions
+}
+
+// connectionDefinitionOver10 aggregates pg_stat_activity per database, user, application and backend type.
+// Background processes are not attached to a database and are reported without one.
+// Connection limits only apply to client backends, so utilization only takes those into account.
+// A rolconnlimit or datconnlimit of -1 means no limit and no utilization is reported.
+var connectionDefinitionOver10 = &QueryDefinition{
+	query: `SELECT -- CONNECTIONS_OVER10
+		A.datname AS database,
+		A.usename AS user_name,
+		A.application_name AS application_name,
+		A.backend_type AS backend_type,
+		count(*) AS total,
+		count(*) FILTER (WHERE A.state = 'active') AS active,
+		count(*) FILTER (WHERE A.state = 'idle') AS idle,
+		count(*) FILTER (WHERE A.state = 'idle in transaction') AS idle_in_transaction,
+		count(*) FILTER (WHERE A.state = 'idle in transaction (aborted)') AS idle_in_transaction_aborted,
+		count(*) FILTER (WHERE A.state = 'fastpath function call') AS fastpath,
+		count(*) FILTER (WHERE A.wait_event_type = 'Lock') AS waiting_on_lock,
+		cast(max(extract(epoch FROM (now() - A.xact_start))) AS bigint) AS max_transaction_seconds,
+		cast(max(extract(epoch FROM (now() - A.query_start))) FILTER (WHERE A.state = 'active') AS bigint) AS max_query_seconds,
+		cast(max(extract(epoch FROM (now() - A.state_change))) FILTER (WHERE A.state LIKE 'idle in transaction%') AS bigint) AS max_idle_in_transaction_seconds,
+		R.rolconnlimit AS user_connection_limit,
+		U.connections AS user_connections,
+		CASE WHEN R.rolconnlimit > 0 THEN 100.0 * U.connections / R.rolconnlimit END AS user_connection_utilization,
+		D.datconnlimit AS database_connection_limit,
+		DC.connections AS database_connections,
+		CASE WHEN D.datconnlimit > 0 THEN 100.0 * DC.connections / D.datconnlimit END AS database_connection_utilization,
+		S.available AS instance_connection_limit,
+		I.connections AS instance_connections,
+		CASE WHEN S.available > 0 THEN 100.0 * I.connections / S.available END AS instance_connection_utilization
+		FROM pg_stat_activity A
+		LEFT JOIN pg_roles R ON R.oid = A.usesysid
+		LEFT JOIN pg_database D ON D.oid = A.datid
+		LEFT JOIN (SELECT usesysid, count(*) AS connections FROM pg_stat_activity
+			WHERE backend_type = 'client backend' GROUP BY usesysid) U ON U.usesysid = A.usesysid
+		LEFT JOIN (SELECT datid, count(*) AS connections FROM pg_stat_activity
+			WHERE backend_type = 'client backend' GROUP BY datid) DC ON DC.datid = A.datid
+		CROSS JOIN (SELECT count(*) AS connections FROM pg_stat_activity
+			WHERE backend_type = 'client backend') I
+		CROSS JOIN (SELECT current_setting('max_connections')::integer
+			- current_setting('superuser_reserved_connections')::integer AS available) S
+		WHERE A.datname IN (%DATABASES%) OR A.datname IS NULL
+		GROUP BY A.datname, A.usename, A.application_name, A.backend_type,
+			R.rolconnlimit, U.connections, D.datconnlimit, DC.connections, S.available, I.connections;`,
+
+	dataModels: []struct {
+		connectionBase
+		BackendType *string `db:"backend_type" metric_name:"backendType" source_type:"attribute"`
+	}{},
+}
+
+// connectionDefinitionOver16 is the same as connectionDefinitionOver10, also leaving out of the instance
+// connection limit the reserved_connections slots kept for roles with pg_use_reserved_connections.
+var connectionDefinitionOver16 = &QueryDefinition{
+	query: `SELECT -- CONNECTIONS_OVER16
+		A.datname AS database,
+		A.usename AS user_name,
+		A.application_name AS application_name,
+		A.backend_type AS backend_type,
+		count(*) AS total,
+		count(*) FILTER (WHERE A.state = 'active') AS active,
+		count(*) FILTER (WHERE A.state = 'idle') AS idle,
+		count(*) FILTER (WHERE A.state = 'idle in transaction') AS idle_in_transaction,
+		count(*) FILTER (WHERE A.state = 'idle in transaction (aborted)') AS idle_in_transaction_aborted,
+		count(*) FILTER (WHERE A.state = 'fastpath function call') AS fastpath,
+		count(*) FILTER (WHERE A.wait_event_type = 'Lock') AS waiting_on_lock,
+		cast(max(extract(epoch FROM (now() - A.xact_start))) AS bigint) AS max_transaction_seconds,
+		cast(max(extract(epoch FROM (now() - A.query_start))) FILTER (WHERE A.state = 'active') AS bigint) AS max_query_seconds,
+		cast(max(extract(epoch FROM (now() - A.state_change))) FILTER (WHERE A.state LIKE 'idle in transaction%') AS bigint) AS max_idle_in_transaction_seconds,
+		R.rolconnlimit AS user_connection_limit,
+		U.connections AS user_connections,
+		CASE WHEN R.rolconnlimit > 0 THEN 100.0 * U.connections / R.rolconnlimit END AS user_connection_utilization,
+		D.datconnlimit AS database_connection_limit,
+		DC.connections AS database_connections,
+		CASE WHEN D.datconnlimit > 0 THEN 100.0 * DC.connections / D.datconnlimit END AS database_connection_utilization,
+		S.available AS instance_connection_limit,
+		I.connections AS instance_connections,
+		CASE WHEN S.available > 0 THEN 100.0 * I.connections / S.available END AS instance_connection_utilization
+		FROM pg_stat_activity A
+		LEFT JOIN pg_roles R ON R.oid = A.usesysid
+		LEFT JOIN pg_database D ON D.oid = A.datid
+		LEFT JOIN (SELECT usesysid, count(*) AS connections FROM pg_stat_activity
+			WHERE backend_type = 'client backend' GROUP BY usesysid) U ON U.usesysid = A.usesysid
+		LEFT JOIN (SELECT datid, count(*) AS connections FROM pg_stat_activity
+			WHERE backend_type = 'client backend' GROUP BY datid) DC ON DC.datid = A.datid
+		CROSS JOIN (SELECT count(*) AS connections FROM pg_stat_activity
+			WHERE backend_type = 'client backend') I
+		CROSS JOIN (SELECT current_setting('max_connections')::integer
+			- current_setting('superuser_reserved_connections')::integer
+			- current_setting('reserved_connections')::integer AS available) S
+		WHERE A.datname IN (%DATABASES%) OR A.datname IS NULL
+		GROUP BY A.datname, A.usename, A.application_name, A.backend_type,
+			R.rolconnlimit, U.connections, D.datconnlimit, DC.connections, S.available, I.connections;`,
+
+	dataModels: []struct {
+		connectionBase
+		BackendType *string `db:"backend_type" metric_name:"backendType" source_type:"attribute"`
+	}{},
+}
+
+// connectionDefinitionOver96 is the same as connectionDefinitionOver10 for versions where
+// pg_stat_activity only lists client backends and has no backend_type column.
+var connectionDefinitionOver96 = &QueryDefinition{
+	query: `SELECT -- CONNECTIONS_OVER96
+		A.datname AS database,
+		A.usename AS user_name,
+		A.application_name AS application_name,
+		count(*) AS total,
+		count(*) FILTER (WHERE A.state = 'active') AS active,
+		count(*) FILTER (WHERE A.state = 'idle') AS idle,
+		count(*) FILTER (WHERE A.state = 'idle in transaction') AS idle_in_transaction,
+		count(*) FILTER (WHERE A.state = 'idle in transaction (aborted)') AS idle_in_transaction_aborted,
+		count(*) FILTER (WHERE A.state = 'fastpath function call') AS fastpath,
+		count(*) FILTER (WHERE A.wait_event_type = 'Lock') AS waiting_on_lock,
+		cast(max(extract(epoch FROM (now() - A.xact_start))) AS bigint) AS max_transaction_seconds,
+		cast(max(extract(epoch FROM (now() - A.query_start))) FILTER (WHERE A.state = 'active') AS bigint) AS max_query_seconds,
+		cast(max(extract(epoch FROM (now() - A.state_change))) FILTER (WHERE A.state LIKE 'idle in transaction%') AS bigint) AS max_idle_in_transaction_seconds,
+		R.rolconnlimit AS user_connection_limit,
+		U.connections AS user_connections,
+		CASE WHEN R.rolconnlimit > 0 THEN 100.0 * U.connections / R.rolconnlimit END AS user_connection_utilization,
+		D.datconnlimit AS database_connection_limit,
+		DC.connections AS database_connections,
+		CASE WHEN D.datconnlimit > 0 THEN 100.0 * DC.connections / D.datconnlimit END AS database_connection_utilization,
+		S.available AS instance_connection_limit,
+		I.connections AS instance_connections,
+		CASE WHEN S.available > 0 THEN 100.0 * I.connections / S.available END AS instance_connection_utilization
+		FROM pg_stat_activity A
+		LEFT JOIN pg_roles R ON R.oid = A.usesysid
+		LEFT JOIN pg_database D ON D.oid = A.datid
+		LEFT JOIN (SELECT usesysid, count(*) AS connections FROM pg_stat_activity
+			GROUP BY usesysid) U ON U.usesysid = A.usesysid
+		LEFT JOIN (SELECT datid, count(*) AS connections FROM pg_stat_activity
+			GROUP BY datid) DC ON DC.datid = A.datid
+		CROSS JOIN (SELECT count(*) AS connections FROM pg_stat_activity) I
+		CROSS JOIN (SELECT current_setting('max_connections')::integer
+			- current_setting('superuser_reserved_connections')::integer AS available) S
+		WHERE A.datname IN (%DATABASES%)
+		GROUP BY A.datname, A.usename, A.application_name,
+			R.rolconnlimit, U.connections, D.datconnlimit, DC.connections, S.available, I.connections;`,
+
+	dataModels: []struct {
+		connectionBase
+	}{},
+}
+
+type connectionBase struct {
+	Database                      *string  `db:"database"                        metric_name:"database"                                     source_type:"attribute"`
+	User                          *string  `db:"user_name"                       metric_name:"user"                                         source_type:"attribute"`
+	Application                   *string  `db:"application_name"                metric_name:"application"                                  source_type:"attribute"`
+	Total                         *int64   `db:"total"                           metric_name:"connections.total"                            source_type:"gauge"`
+	Active                        *int64   `db:"active"                          metric_name:"connections.active"                           source_type:"gauge"`
+	Idle                          *int64   `db:"idle"                            metric_name:"connections.idle"                             source_type:"gauge"`
+	IdleInTransaction             *int64   `db:"idle_in_transaction"             metric_name:"connections.idleInTransaction"                source_type:"gauge"`
+	IdleInTransactionAborted      *int64   `db:"idle_in_transaction_aborted"     metric_name:"connections.idleInTransactionAborted"         source_type:"gauge"`
+	Fastpath                      *int64   `db:"fastpath"                        metric_name:"connections.fastpath"                         source_type:"gauge"`
+	WaitingOnLock                 *int64   `db:"waiting_on_lock"                 metric_name:"connections.waitingOnLock"                    source_type:"gauge"`
+	MaxTransactionSeconds         *int64   `db:"max_transaction_seconds"         metric_name:"connections.maxTransactionDurationInSeconds"  source_type:"gauge"`
+	MaxQuerySeconds               *int64   `db:"max_query_seconds"               metric_name:"connections.maxQueryDurationInSeconds"        source_type:"gauge"`
+	MaxIdleInTransactionSeconds   *int64   `db:"max_idle_in_transaction_seconds" metric_name:"connections.maxIdleInTransactionInSeconds"    source_type:"gauge"`
+	UserConnectionLimit           *int64   `db:"user_connection_limit"           metric_name:"connections.userConnectionLimit"              source_type:"gauge"`
+	UserConnections               *int64   `db:"user_connections"                metric_name:"connections.userConnections"                  source_type:"gauge"`
+	UserConnectionUtilization     *float64 `db:"user_connection_utilization"     metric_name:"connections.userUtilizationPercent"           source_type:"gauge"`
+	DatabaseConnectionLimit       *int64   `db:"database_connection_limit"       metric_name:"connections.databaseConnectionLimit"          source_type:"gauge"`
+	DatabaseConnections           *int64   `db:"database_connections"            metric_name:"connections.databaseConnections"              source_type:"gauge"`
+	DatabaseConnectionUtilization *float64 `db:"database_connection_utilization" metric_name:"connections.databaseUtilizationPercent"       source_type:"gauge"`
+	InstanceConnectionLimit       *int64   `db:"instance_connection_limit"       metric_name:"connections.instanceConnectionLimit"          source_type:"gauge"`
+	InstanceConnections           *int64   `db:"instance_connections"            metric_name:"connections.instanceConnections"              source_type:"gauge"`
+	InstanceConnectionUtilization *float64 `db:"instance_connection_utilization" metric_name:"connections.instanceUtilizationPercent"       source_type:"gauge"`
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/newrelic/nri-postgresql/src/collection"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_generateConnectionDefinitions(t *testing.T) {
+	databaseList := collection.DatabaseList{"test1": {}}
+
+	tests := []struct {
+		name          string
+		version       string
+		expectedQuery string
+	}{
+		{
+			name:          "PostgreSQL 9.5",
+			version:       "9.5.0",
+			expectedQuery: "",
+		},
+		{
+			name:          "PostgreSQL 9.6",
+			version:       "9.6.3",
+			expectedQuery: "CONNECTIONS_OVER96",
+		},
+		{
+			name:          "PostgreSQL 15",
+			version:       "15.4.0",
+			expectedQuery: "CONNECTIONS_OVER10",
+		},
+		{
+			name:          "PostgreSQL 16",
+			version:       "16.1.0",
+			expectedQuery: "CONNECTIONS_OVER16",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version := semver.MustParse(tt.version)
+			queryDefinitions := generateConnectionDefinitions(databaseList, &version)
+			if tt.expectedQuery == "" {
+				assert.Empty(t, queryDefinitions)
+				return
+			}
+			assert.Len(t, queryDefinitions, 1)
+			assert.Contains(t, queryDefinitions[0].GetQuery(), tt.expectedQuery)
+			assert.Contains(t, queryDefinitions[0].GetQuery(), "'test1'")
+		})
+	}
+}
+
+func Test_generateConnectionDefinitions_NoDatabases(t *testing.T) {
+	version := semver.MustParse("16.1.0")
+
+	assert.Empty(t, generateConnectionDefinitions(collection.DatabaseList{}, &version))
+}
+
+func Test_connectionDefinitionOver16_ReservedConnections(t *testing.T) {
+	assert.Contains(t, connectionDefinitionOver16.GetQuery(), "- current_setting('reserved_connections')::integer AS available")
+	assert.NotContains(t, connectionDefinitionOver10.GetQuery(), "'reserved_connections'")
+	assert.Equal(t, connectionDefinitionOver10.dataModels, connectionDefinitionOver16.dataModels)
+}
@@ -6,7 +6,7 @@ import (
 )
 
 func generateDatabaseDefinitions(databases collection.DatabaseList, version *semver.Version) []*QueryDefinition {
-	queryDefinitions := make([]*QueryDefinition, 0, 2)
+	queryDefinitions := make([]*QueryDefinition, 0, 4)
 	if len(databases) == 0 {
 		return queryDefinitions
 	}
@@ -24,6 +24,16 @@ func generateDatabaseDefinitions(databases collection.DatabaseList, version *sem
 		queryDefinitions = append(queryDefinitions, databaseDefinitionOver92.insertDatabaseNames(databases))
 	}
 
+	if version.GE(semver.MustParse("12.0.0")) {
+		queryDefinitions = append(queryDefinitions, databaseDefinitionOver12.insertDatabaseNames(databases))
+	}
+
+	if version.GE(semver.MustParse("14.0.0")) {
+		queryDefinitions = append(queryDefinitions, databaseDefinitionOver14.insertDatabaseNames(databases))
+	}
+
+	queryDefinitions = append(queryDefinitions, databasePreparedTransactionsDefinition.insertDatabaseNames(databases))
+
 	return queryDefinitions
 }
 
@@ -117,6 +127,7 @@ var databaseDefinitionOver91 = &QueryDefinition{
 }
 
 // databaseDefinitionOver92 is the query used to fetch extra metrics from Postgres version 9.2 and above.
+// pg_database_size fails on databases the user cannot connect to, so their size is not reported.
 var databaseDefinitionOver92 = &QueryDefinition{
 	query: `SELECT 
 		D.datname AS database,
@@ -124,7 +135,9 @@ var databaseDefinitionOver92 = &QueryDefinition{
 		SD.temp_bytes AS temporary_bytes_written,
 		SD.deadlocks AS deadlocks,
 		cast(SD.blk_read_time AS bigint) AS time_spent_reading_data,
-		cast(SD.blk_write_time AS bigint) AS time_spent_writing_data
+		cast(SD.blk_write_time AS bigint) AS time_spent_writing_data,
+		CASE WHEN has_database_privilege(D.oid, 'CONNECT') THEN pg_database_size(D.oid) END AS size,
+		extract(epoch from SD.stats_reset)::bigint AS stats_reset
 		FROM pg_stat_database SD 
 		INNER JOIN pg_database D ON D.datname = SD.datname 
 		INNER JOIN pg_stat_database_conflicts DBC ON DBC.datname = D.datname 
@@ -140,5 +153,87 @@ var databaseDefinitionOver92 = &QueryDefinition{
 		Deadlocks          *int64 `db:"deadlocks"               metric_name:"db.deadlocksPerSecond"               source_type:"rate"`
 		TimeSpentReading   *int64 `db:"time_spent_reading_data" metric_name:"db.readTimeInMillisecondsPerSecond"  source_type:"rate"`
 		TimeSpentWriting   *int64 `db:"time_spent_writing_data" metric_name:"db.writeTimeInMillisecondsPerSecond" source_type:"rate"`
+		Size               *int64 `db:"size"                    metric_name:"db.sizeInBytes"                      source_type:"gauge"`
+		StatsReset         *int64 `db:"stats_reset"             metric_name:"db.statsReset"                       source_type:"gauge"`
+	}{},
+}
+
+// databaseDefinitionOver12 reports the data checksum failures, which are NULL when data checksums are disabled
+var databaseDefinitionOver12 = &QueryDefinition{
+	query: `SELECT -- DATABASE_OVER12
+		D.datname AS database,
+		SD.checksum_failures AS checksum_failures,
+		extract(epoch from SD.checksum_last_failure)::bigint AS checksum_last_failure
+		FROM pg_stat_database SD 
+		INNER JOIN pg_database D ON D.datname = SD.datname 
+		WHERE D.datistemplate = FALSE 
+			AND D.datname IS NOT NULL
+			AND D.datname IN (%DATABASES%);`,
+
+	dataModels: []struct {
+		databaseBase
+		ChecksumFailures    *int64 `db:"checksum_failures"     metric_name:"db.checksumFailures"    source_type:"gauge"`
+		ChecksumLastFailure *int64 `db:"checksum_last_failure" metric_name:"db.checksumLastFailure" source_type:"gauge"`
+	}{},
+}
+
+// databaseDefinitionOver14 reports the session statistics. Sessions abandoned, fatal and killed are the
+// ones ended by the client disconnecting, by a fatal error and by an operator intervention.
+var databaseDefinitionOver14 = &QueryDefinition{
+	query: `SELECT -- DATABASE_OVER14
+		D.datname AS database,
+		SD.session_time AS session_time,
+		SD.active_time AS active_time,
+		SD.idle_in_transaction_time AS idle_in_transaction_time,
+		SD.sessions AS sessions,
+		SD.sessions_abandoned AS sessions_abandoned,
+		SD.sessions_fatal AS sessions_fatal,
+		SD.sessions_killed AS sessions_killed
+		FROM pg_stat_database SD 
+		INNER JOIN pg_database D ON D.datname = SD.datname 
+		WHERE D.datistemplate = FALSE 
+			AND D.datname IS NOT NULL
+			AND D.datname IN (%DATABASES%);`,
+
+	dataModels: []struct {
+		databaseBase
+		SessionTime           *float64 `db:"session_time"             metric_name:"db.sessionTimeInMillisecondsPerSecond"           source_type:"rate"`
+		ActiveTime            *float64 `db:"active_time"              metric_name:"db.activeTimeInMillisecondsPerSecond"            source_type:"rate"`
+		IdleInTransactionTime *float64 `db:"idle_in_transaction_time" metric_name:"db.idleInTransactionTimeInMillisecondsPerSecond" source_type:"rate"`
+		Sessions              *int64   `db:"sessions"                 metric_name:"db.sessionsPerSecond"                            source_type:"rate"`
+		SessionsAbandoned     *int64   `db:"sessions_abandoned"       metric_name:"db.sessionsAbandonedPerSecond"                   source_type:"rate"`
+		SessionsFatal         *int64   `db:"sessions_fatal"           metric_name:"db.sessionsFatalPerSecond"                       source_type:"rate"`
+		SessionsKilled        *int64   `db:"sessions_killed"          metric_name:"db.sessionsKilledPerSecond"                      source_type:"rate"`
+	}{},
+}
+
+// databasePreparedTransactionsDefinition reports the transactions prepared for two-phase commit and not yet
+// committed or rolled back, which keep their locks and hold back the xmin horizon until they are. The age is 0
+// when there are none. Transactions can only be prepared when max_prepared_transactions is above 0.
+var databasePreparedTransactionsDefinition = &QueryDefinition{
+	query: `SELECT -- PREPARED_XACTS
+		D.datname AS database,
+		count(P.gid) AS prepared_transactions,
+		coalesce(extract(epoch from now() - min(P.prepared)), 0) AS oldest_prepared_transaction_age,
+		(array_agg(P.gid ORDER BY P.prepared))[1] AS oldest_prepared_transaction_gid,
+		(array_agg(P.owner ORDER BY P.prepared))[1] AS oldest_prepared_transaction_owner,
+		extract(epoch from min(P.prepared))::bigint AS oldest_prepared_transaction_prepared_at,
+		(SELECT setting::integer FROM pg_settings WHERE name = 'max_prepared_transactions') AS max_prepared_transactions,
+		(SELECT setting::integer > 0 FROM pg_settings WHERE name = 'max_prepared_transactions') AS prepared_transactions_enabled
+		FROM pg_database D
+		LEFT JOIN pg_prepared_xacts P ON P.database = D.datname
+		WHERE D.datistemplate = FALSE
+			AND D.datname IN (%DATABASES%)
+		GROUP BY D.datname;`,
+
+	dataModels: []struct {
+		databaseBase
+		PreparedTransactions                *int64   `db:"prepared_transactions"                   metric_name:"db.preparedTransactions"                  source_type:"gauge"`
+		OldestPreparedTransactionAge        *float64 `db:"oldest_prepared_transaction_age"         metric_name:"db.oldestPreparedTransactionAgeInSeconds" source_type:"gauge"`
+		OldestPreparedTransactionGID        *string  `db:"oldest_prepared_transaction_gid"         metric_name:"db.oldestPreparedTransactionGid"          source_type:"attribute"`
+		OldestPreparedTransactionOwner      *string  `db:"oldest_prepared_transaction_owner"       metric_name:"db.oldestPreparedTransactionOwner"        source_type:"attribute"`
+		OldestPreparedTransactionPreparedAt *int64   `db:"oldest_prepared_transaction_prepared_at" metric_name:"db.oldestPreparedTransactionPreparedAt"   source_type:"gauge"`
+		MaxPreparedTransactions             *int64   `db:"max_prepared_transactions"               metric_name:"db.maxPreparedTransactions"               source_type:"gauge"`
+		PreparedTransactionsEnabled         *bool    `db:"prepared_transactions_enabled"           metric_name:"db.preparedTransactionsEnabled"           source_type:"gauge"`
 	}{},
 }
@@ -15,7 +15,7 @@ func Test_generateDatabaseDefinitions_LengthV8(t *testing.T) {
 
 	queryDefinitions := generateDatabaseDefinitions(databaseList, &v8)
 
-	assert.Equal(t, 1, len(queryDefinitions))
+	assert.Equal(t, 2, len(queryDefinitions))
 }
 
 func Test_generateDatabaseDefinitions_LengthV912(t *testing.T) {
@@ -24,7 +24,7 @@ func Test_generateDatabaseDefinitions_LengthV912(t *testing.T) {
 
 	queryDefinitions := generateDatabaseDefinitions(databaseList, &v912)
 
-	assert.Equal(t, 1, len(queryDefinitions))
+	assert.Equal(t, 2, len(queryDefinitions))
 }
 
 func Test_generateDatabaseDefinitions_LengthV925(t *testing.T) {
@@ -33,7 +33,28 @@ func Test_generateDatabaseDefinitions_LengthV925(t *testing.T) {
 
 	queryDefinitions := generateDatabaseDefinitions(databaseList, &v925)
 
-	assert.Equal(t, 2, len(queryDefinitions))
+	assert.Equal(t, 3, len(queryDefinitions))
+}
+
+func Test_generateDatabaseDefinitions_LengthV12(t *testing.T) {
+	v12 := semver.MustParse("12.0.0")
+	databaseList := collection.DatabaseList{"test1": {}}
+
+	queryDefinitions := generateDatabaseDefinitions(databaseList, &v12)
+
+	assert.Equal(t, 4, len(queryDefinitions))
+	assert.Contains(t, queryDefinitions[2].GetQuery(), "DATABASE_OVER12")
+}
+
+func Test_generateDatabaseDefinitions_LengthV14(t *testing.T) {
+	v14 := semver.MustParse("14.0.0")
+	databaseList := collection.DatabaseList{"test1": {}}
+
+	queryDefinitions := generateDatabaseDefinitions(databaseList, &v14)
+
+	assert.Equal(t, 5, len(queryDefinitions))
+	assert.Contains(t, queryDefinitions[3].GetQuery(), "DATABASE_OVER14")
+	assert.Contains(t, queryDefinitions[4].GetQuery(), "PREPARED_XACTS")
 }
 
 func Test_insertDatabaseNames(t *testing.T) {
@@ -0,0 +1,151 @@
+package metrics
+
+import (
+	"github.com/blang/semver/v4"
+	"github.com/newrelic/infra-integrations-sdk/v3/log"
+	"github.com/newrelic/nri-postgresql/src/collection"
+)
+
+// exactBloatCandidate is a table or B-tree index that can be measured with pgstattuple
+type exactBloatCandidate struct {
+	Database    string  `db:"database"`
+	Schema      string  `db:"schema_name"`
+	Table       string  `db:"table_name"`
+	Index       *string `db:"index_name"`
+	OID         int64   `db:"oid"`
+	lastScanned int64
+}
+
+// storeKey identifies the relation in the state store, where the time it was last measured is kept
+func (c exactBloatCandidate) storeKey() string {
+	key := "exactBloat:" + c.Database + "." + c.Schema + "." + c.Table
+	if c.Index != nil {
+		key += "." + *c.Index
+	}
+	return key
+}
+
+// generateExactBloatCandidateDefinitions returns the tables and B-tree indexes to measure. Tables are
+// measured with pgstattuple_approx, which is only available from PostgreSQL 9.5, and indexes with
+// pgstatindex(regclass), which is only available from PostgreSQL 9.6.
+func generateExactBloatCandidateDefinitions(schemaList collection.SchemaList, maxSize int64, version *semver.Version) []*QueryDefinition {
+	queryDefinitions := make([]*QueryDefinition, 0, 2)
+	if version.GE(semver.MustParse("9.5.0")) {
+		if def := exactBloatTableCandidatesDefinition.insertSchemaTables(schemaList); def != nil {
+			queryDefinitions = append(queryDefinitions, def.insertMaxSize(maxSize))
+		}
+	} else {
+		log.Debug("Skipping exact table bloat metrics, pgstattuple_approx requires PostgreSQL 9.5 or later and the version is %s", version.String())
+	}
+
+	if version.GE(semver.MustParse("9.6.0")) {
+		if def := exactBloatIndexCandidatesDefinition.insertSchemaTableIndexes(schemaList); def != nil {
+			queryDefinitions = append(queryDefinitions, def.insertMaxSize(maxSize))
+		}
+	} else {
+		log.Debug("Skipping exact index bloat metrics, pgstatindex(regclass) requires PostgreSQL 9.6 or later and the version is %s", version.String())
+	}
+
+	return queryDefinitions
+}
+
+func generateExactBloatDefinition(candidate exactBloatCandidate) *QueryDefinition {
+	if candidate.Index != nil {
+		return exactIndexBloatDefinition.insertRelationOID(candidate.OID)
+	}
+
+	return exactTableBloatDefinition.insertRelationOID(candidate.OID)
+}
+
+var exactBloatTableCandidatesDefinition = &QueryDefinition{
+	query: `SELECT -- EXACT_BLOAT_TABLE_CANDIDATES
+			current_database() AS database,
+			N.nspname AS schema_name,
+			C.relname AS table_name,
+			C.oid AS oid
+		FROM pg_class C
+		JOIN pg_namespace N ON N.oid = C.relnamespace
+		WHERE C.relkind = 'r'
+			AND pg_relation_size(C.oid) <= %MAX_SIZE%
+			AND N.nspname || '.' || C.relname IN (%SCHEMA_TABLES%);`,
+
+	dataModels: []exactBloatCandidate{},
+}
+
+var exactBloatIndexCandidatesDefinition = &QueryDefinition{
+	query: `SELECT -- EXACT_BLOAT_INDEX_CANDIDATES
+			current_database() AS database,
+			N.nspname AS schema_name,
+			T.relname AS table_name,
+			I.relname AS index_name,
+			I.oid AS oid
+		FROM pg_index X
+		JOIN pg_class I ON I.oid = X.indexrelid
+		JOIN pg_class T ON T.oid = X.indrelid
+		JOIN pg_namespace N ON N.oid = T.relnamespace
+		JOIN pg_am A ON A.oid = I.relam
+		WHERE A.amname = 'btree' AND X.indisvalid
+			AND pg_relation_size(I.oid) <= %MAX_SIZE%
+			AND N.nspname || '.' || T.relname || '.' || I.relname IN (%SCHEMA_TABLE_INDEXES%);`,
+
+	dataModels: []exactBloatCandidate{},
+}
+
+// exactTableBloatDefinition uses pgstattuple_approx, which skips the pages the visibility map reports as
+// all-visible and only estimates their free space, so it is much cheaper than a full pgstattuple scan.
+var exactTableBloatDefinition = &QueryDefinition{
+	query: `SELECT -- EXACT_TABLE_BLOAT
+			current_database() AS database,
+			N.nspname AS schema_name,
+			C.relname AS table_name,
+			S.scanned_percent AS scanned_percent,
+			S.dead_tuple_percent AS dead_tuple_percent,
+			S.approx_free_space AS free_space,
+			S.approx_free_percent AS free_percent
+		FROM pg_class C
+		JOIN pg_namespace N ON N.oid = C.relnamespace
+		CROSS JOIN public.pgstattuple_approx(C.oid) S
+		WHERE C.oid = %RELATION_OID%;`,
+
+	dataModels: []struct {
+		databaseBase
+		schemaBase
+		tableBase
+		ScannedPercent   *float64 `db:"scanned_percent"    metric_name:"table.exactBloat.scannedPercent"   source_type:"gauge"`
+		DeadTuplePercent *float64 `db:"dead_tuple_percent" metric_name:"table.exactBloat.deadTuplePercent" source_type:"gauge"`
+		FreeSpace        *int64   `db:"free_space"         metric_name:"table.exactBloat.freeSpaceInBytes" source_type:"gauge"`
+		FreePercent      *float64 `db:"free_percent"       metric_name:"table.exactBloat.freePercent"      source_type:"gauge"`
+	}{},
+}
+
+// exactIndexBloatDefinition uses pgstatindex, which reads the whole B-tree index
+var exactIndexBloatDefinition = &QueryDefinition{
+	query: `SELECT -- EXACT_INDEX_BLOAT
+			current_database() AS database,
+			N.nspname AS schema_name,
+			T.relname AS table_name,
+			I.relname AS index_name,
+			S.avg_leaf_density AS leaf_density,
+			S.leaf_fragmentation AS leaf_fragmentation,
+			S.leaf_pages AS leaf_pages,
+			S.empty_pages AS empty_pages,
+			S.deleted_pages AS deleted_pages
+		FROM pg_index X
+		JOIN pg_class I ON I.oid = X.indexrelid
+		JOIN pg_class T ON T.oid = X.indrelid
+		JOIN pg_namespace N ON N.oid = T.relnamespace
+		CROSS JOIN public.pgstatindex(I.oid) S
+		WHERE I.oid = %RELATION_OID%;`,
+
+	dataModels: []struct {
+		databaseBase
+		schemaBase
+		tableBase
+		indexBase
+		LeafDensity       *float64 `db:"leaf_density"       metric_name:"index.exactBloat.leafDensityPercent"       source_type:"gauge"`
+		LeafFragmentation *float64 `db:"leaf_fragmentation" metric_name:"index.exactBloat.leafFragmentationPercent" source_type:"gauge"`
+		LeafPages         *int64   `db:"leaf_pages"         metric_name:"index.exactBloat.leafPages"                source_type:"gauge"`
+		EmptyPages        *int64   `db:"empty_pages"        metric_name:"index.exactBloat.emptyPages"               source_type:"gauge"`
+		DeletedPages      *int64   `db:"deleted_pages"      metric_name:"index.exactBloat.deletedPages"             source_type:"gauge"`
+	}{},
+}
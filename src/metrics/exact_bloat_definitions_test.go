@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/newrelic/nri-postgresql/src/collection"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_generateExactBloatCandidateDefinitions(t *testing.T) {
+	schemaList := collection.SchemaList{
+		"schema1": collection.TableList{
+			"table1": []string{"index1"},
+			"table2": []string{},
+		},
+	}
+
+	version := semver.MustParse("9.6.0")
+	queryDefinitions := generateExactBloatCandidateDefinitions(schemaList, 1048576, &version)
+	assert.Len(t, queryDefinitions, 2)
+	assert.Contains(t, queryDefinitions[0].GetQuery(), "EXACT_BLOAT_TABLE_CANDIDATES")
+	assert.Contains(t, queryDefinitions[0].GetQuery(), "<= 1048576")
+	assert.Contains(t, queryDefinitions[1].GetQuery(), "EXACT_BLOAT_INDEX_CANDIDATES")
+	assert.Contains(t, queryDefinitions[1].GetQuery(), "IN ('schema1.table1.index1')")
+
+	queryDefinitions = generateExactBloatCandidateDefinitions(collection.SchemaList{"schema1": {"table2": []string{}}}, 1048576, &version)
+	assert.Len(t, queryDefinitions, 1)
+
+	// pgstatindex(regclass) is not available before PostgreSQL 9.6, so only tables are measured
+	version = semver.MustParse("9.5.25")
+	queryDefinitions = generateExactBloatCandidateDefinitions(schemaList, 1048576, &version)
+	assert.Len(t, queryDefinitions, 1)
+	assert.Contains(t, queryDefinitions[0].GetQuery(), "EXACT_BLOAT_TABLE_CANDIDATES")
+
+	// pgstattuple_approx is not available before PostgreSQL 9.5 either, so nothing is measured
+	version = semver.MustParse("9.4.26")
+	assert.Empty(t, generateExactBloatCandidateDefinitions(schemaList, 1048576, &version))
+}
+
+func Test_generateExactBloatDefinition(t *testing.T) {
+	index := "index1"
+
+	tableDefinition := generateExactBloatDefinition(exactBloatCandidate{Database: "db1", Schema: "schema1", Table: "table1", OID: 100})
+	assert.Contains(t, tableDefinition.GetQuery(), "pgstattuple_approx")
+	assert.Contains(t, tableDefinition.GetQuery(), "C.oid = 100;")
+
+	indexDefinition := generateExactBloatDefinition(exactBloatCandidate{Database: "db1", Schema: "schema1", Table: "table1", Index: &index, OID: 101})
+	assert.Contains(t, indexDefinition.GetQuery(), "pgstatindex")
+	assert.Contains(t, indexDefinition.GetQuery(), "I.oid = 101;")
+}
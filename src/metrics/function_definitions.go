@@ -0,0 +1,71 @@
+package metrics
+
+// generateFunctionDefinitions returns the functions listed for the database or, when none are
+// listed, its topN functions by total time. No definition is returned if topN is not positive.
+func generateFunctionDefinitions(schemaFunctions map[string][]string, topN int) []*QueryDefinition {
+	queryDefinitions := make([]*QueryDefinition, 0, 1)
+	if def := functionDefinition.insertSchemaFunctions(schemaFunctions); def != nil {
+		return append(queryDefinitions, def)
+	}
+
+	if topN > 0 {
+		queryDefinitions = append(queryDefinitions, functionTopDefinition.insertLimit(topN))
+	}
+
+	return queryDefinitions
+}
+
+type functionMetricsBase struct {
+	databaseBase
+	schemaBase
+	functionBase
+	Calls       *int64   `db:"calls"        metric_name:"function.callsPerSecond"                   source_type:"rate"`
+	TotalTime   *float64 `db:"total_time"   metric_name:"function.totalTimeInMillisecondsPerSecond" source_type:"rate"`
+	SelfTime    *float64 `db:"self_time"    metric_name:"function.selfTimeInMillisecondsPerSecond"  source_type:"rate"`
+	AverageTime *float64 `db:"average_time" metric_name:"function.averageDurationInMilliseconds"    source_type:"gauge"`
+}
+
+// functionDefinition selects functions either by schema qualified name or by schema qualified signature.
+// Signatures are made of the argument types only, as argument names are not part of the identity of a
+// function, and are compared without whitespace. pg_stat_user_functions only has rows while
+// track_functions is enabled, but keeps them after it is disabled, so the setting is checked as well.
+var functionDefinition = &QueryDefinition{
+	query: `SELECT -- FUNCTIONQUERY
+		current_database() AS database,
+		F.schemaname AS schema_name,
+		F.funcname || '(' || oidvectortypes(P.proargtypes) || ')' AS function_name,
+		F.calls AS calls,
+		F.total_time AS total_time,
+		F.self_time AS self_time,
+		CASE WHEN F.calls > 0 THEN F.total_time / F.calls END AS average_time
+		FROM pg_stat_user_functions F
+		JOIN pg_proc P ON P.oid = F.funcid
+		WHERE current_setting('track_functions') <> 'none'
+			AND (F.schemaname || '.' || F.funcname IN (%SCHEMA_FUNCTIONS%)
+			OR F.schemaname || '.' || F.funcname || '(' || replace(oidvectortypes(P.proargtypes), ' ', '') || ')' IN (%SCHEMA_FUNCTIONS%));`,
+
+	dataModels: []struct {
+		functionMetricsBase
+	}{},
+}
+
+// functionTopDefinition selects the functions with the highest total time
+var functionTopDefinition = &QueryDefinition{
+	query: `SELECT -- FUNCTIONTOPQUERY
+		current_database() AS database,
+		F.schemaname AS schema_name,
+		F.funcname || '(' || oidvectortypes(P.proargtypes) || ')' AS function_name,
+		F.calls AS calls,
+		F.total_time AS total_time,
+		F.self_time AS self_time,
+		CASE WHEN F.calls > 0 THEN F.total_time / F.calls END AS average_time
+		FROM pg_stat_user_functions F
+		JOIN pg_proc P ON P.oid = F.funcid
+		WHERE current_setting('track_functions') <> 'none'
+		ORDER BY F.total_time DESC
+		LIMIT %LIMIT%;`,
+
+	dataModels: []struct {
+		functionMetricsBase
+	}{},
+}
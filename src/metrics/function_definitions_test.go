@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/integration"
+	"github.com/newrelic/nri-postgresql/src/collection"
+	"github.com/newrelic/nri-postgresql/src/connection"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func Test_generateFunctionDefinitions(t *testing.T) {
+	tests := []struct {
+		name            string
+		schemaFunctions map[string][]string
+		topN            int
+		expectedQueries []string
+	}{
+		{
+			name:            "Top functions",
+			schemaFunctions: nil,
+			topN:            20,
+			expectedQueries: []string{"LIMIT 20;"},
+		},
+		{
+			name:            "Top functions disabled",
+			schemaFunctions: nil,
+			topN:            0,
+			expectedQueries: []string{},
+		},
+		{
+			name:            "Listed functions",
+			schemaFunctions: map[string][]string{"public": {"my_function(integer, text)"}},
+			topN:            20,
+			expectedQueries: []string{"IN ('public.my_function(integer,text)'))"},
+		},
+		{
+			name:            "Listed signature with extra whitespace",
+			schemaFunctions: map[string][]string{"public": {"my_procedure( integer,character varying )", "my_function"}},
+			topN:            20,
+			expectedQueries: []string{"IN ('public.my_procedure(integer,charactervarying)','public.my_function'))"},
+		},
+		{
+			name:            "Schema without functions",
+			schemaFunctions: map[string][]string{"public": {}},
+			topN:            5,
+			expectedQueries: []string{"LIMIT 5;"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			queryDefinitions := generateFunctionDefinitions(tc.schemaFunctions, tc.topN)
+			assert.Len(t, queryDefinitions, len(tc.expectedQueries))
+			for i, expected := range tc.expectedQueries {
+				assert.Contains(t, queryDefinitions[i].GetQuery(), expected)
+				assert.NotContains(t, queryDefinitions[i].GetQuery(), "%")
+			}
+		})
+	}
+}
+
+func Test_functionDefinition_MatchesListedSignature(t *testing.T) {
+	testIntegration, _ := integration.New("test", "test")
+
+	testConnection, mock := connection.CreateMockSQL(t)
+	// oidvectortypes gives the argument types without names, e.g. "integer, text" for my_function(a integer, b text),
+	// so the listed signature is compared to it once both are stripped of whitespace
+	mock.ExpectQuery(regexp.QuoteMeta(`F.schemaname || '.' || F.funcname || '(' || replace(oidvectortypes(P.proargtypes), ' ', '') || ')' IN ('schema1.my_function(integer,text)')`)).
+		WillReturnRows(sqlmock.NewRows([]string{"database", "schema_name", "function_name", "calls", "total_time", "self_time", "average_time"}).
+			AddRow("db1", "schema1", "my_function(integer, text)", 4, 10.0, 6.0, 2.5))
+	mock.ExpectClose()
+
+	ci := &connection.MockInfo{}
+	ci.On("NewConnection", "db1").Return(testConnection, nil)
+
+	databases := collection.DatabaseList{"db1": collection.SchemaList{}}
+	functions := collection.FunctionList{"db1": {"schema1": {"my_function( integer, text )"}}}
+	PopulateFunctionMetrics(databases, functions, 20, testIntegration, ci)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	functionEntity, err := testIntegration.Entity("my_function(integer, text)", "pg-function",
+		integration.NewIDAttribute("pg-database", "db1"),
+		integration.NewIDAttribute("pg-schema", "schema1"),
+		integration.NewIDAttribute("host", "testhost"),
+		integration.NewIDAttribute("port", "1234"))
+	assert.NoError(t, err)
+	assert.Len(t, functionEntity.Metrics, 1)
+	assert.Equal(t, 2.5, functionEntity.Metrics[0].Metrics["function.averageDurationInMilliseconds"])
+}
@@ -4,12 +4,29 @@ import (
 	"github.com/newrelic/nri-postgresql/src/collection"
 )
 
-func generateIndexDefinitions(schemaList collection.SchemaList) []*QueryDefinition {
+func generateIndexDefinitions(schemaList collection.SchemaList, collectBloat bool) []*QueryDefinition {
 	queryDefinitions := make([]*QueryDefinition, 0)
+
 	if def := indexDefinition.insertSchemaTableIndexes(schemaList); def != nil {
 		queryDefinitions = append(queryDefinitions, def)
 	}
 
+	// the bloat estimate goes last, as a failing query stops the collection of the database's indexes
+	if collectBloat {
+		if def := indexBloatDefinition.insertSchemaTableIndexes(schemaList); def != nil {
+			queryDefinitions = append(queryDefinitions, def)
+		}
+	}
+
+	return queryDefinitions
+}
+
+func generateIndexHealthDefinitions(schemaList collection.SchemaList) []*QueryDefinition {
+	queryDefinitions := make([]*QueryDefinition, 0)
+	if def := indexHealthDefinition.insertSchemaTableIndexes(schemaList); def != nil {
+		queryDefinitions = append(queryDefinitions, def)
+	}
+
 	return queryDefinitions
 }
 
@@ -20,15 +37,30 @@ var indexDefinition = &QueryDefinition{
 					t.tablename as table_name,
 					indexname as index_name,
 					pg_relation_size(foo.indexoid) AS index_size,
+					idx_scan AS scans,
 					idx_tup_read AS tuples_read,
-					idx_tup_fetch AS tuples_fetched
+					idx_tup_fetch AS tuples_fetched,
+					idx_blks_read AS blocks_read,
+					idx_blks_hit AS blocks_hit,
+					indisunique AS is_unique,
+					indisprimary AS is_primary,
+					is_partial,
+					is_expression,
+					indisvalid AS is_valid,
+					indisready AS is_ready,
+					array_to_string(ARRAY(SELECT pg_get_indexdef(foo.indexoid, k, true)
+						FROM generate_series(1, foo.number_of_columns) AS k ORDER BY k), ', ') AS index_columns,
+					(SELECT spcname FROM pg_tablespace WHERE oid = coalesce(nullif(foo.index_tablespace, 0),
+						(SELECT dattablespace FROM pg_database WHERE datname = current_database()))) AS tablespace
 			FROM pg_tables t
 			LEFT OUTER JOIN
-					( SELECT c.relname AS ctablename, n.nspname AS cschemaname, x.indexrelid indexoid, ipg.relname AS indexname, x.indnatts AS number_of_columns, idx_scan, idx_tup_read, idx_tup_fetch, indexrelname, indisunique FROM pg_index x
+					( SELECT c.relname AS ctablename, n.nspname AS cschemaname, x.indexrelid indexoid, ipg.relname AS indexname, x.indnatts AS number_of_columns, ipg.reltablespace AS index_tablespace, psai.idx_scan, psai.idx_tup_read, psai.idx_tup_fetch, psio.idx_blks_read, psio.idx_blks_hit, psai.indexrelname, indisunique,
+								 indisprimary, indisvalid, indisready, x.indpred IS NOT NULL AS is_partial, x.indexprs IS NOT NULL AS is_expression FROM pg_index x
 								 JOIN pg_class c ON c.oid = x.indrelid
 								 JOIN pg_namespace n ON c.relnamespace = n.oid
 								 JOIN pg_class ipg ON ipg.oid = x.indexrelid
 								 JOIN pg_stat_all_indexes psai ON x.indexrelid = psai.indexrelid
+								 LEFT JOIN pg_statio_user_indexes psio ON x.indexrelid = psio.indexrelid
 					)
 					AS foo
 					ON t.tablename = foo.ctablename AND t.schemaname = foo.cschemaname
@@ -40,8 +72,183 @@ var indexDefinition = &QueryDefinition{
 		schemaBase
 		tableBase
 		indexBase
-		IndexSize   *int64 `db:"index_size"     metric_name:"index.sizeInBytes"          source_type:"gauge"`
-		RowsRead    *int64 `db:"tuples_read"    metric_name:"index.rowsReadPerSecond"    source_type:"rate"`
-		RowsFetched *int64 `db:"tuples_fetched" metric_name:"index.rowsFetchedPerSecond" source_type:"rate"`
+		IndexSize    *int64  `db:"index_size"     metric_name:"index.sizeInBytes"          source_type:"gauge"`
+		Scans        *int64  `db:"scans"          metric_name:"index.scansPerSecond"       source_type:"rate"`
+		RowsRead     *int64  `db:"tuples_read"    metric_name:"index.rowsReadPerSecond"    source_type:"rate"`
+		RowsFetched  *int64  `db:"tuples_fetched" metric_name:"index.rowsFetchedPerSecond" source_type:"rate"`
+		BlocksRead   *int64  `db:"blocks_read"    metric_name:"index.blocksReadPerSecond"  source_type:"rate"`
+		BlocksHit    *int64  `db:"blocks_hit"     metric_name:"index.blocksHitPerSecond"   source_type:"rate"`
+		IsUnique     *bool   `db:"is_unique"      metric_name:"index.isUnique"             source_type:"gauge"`
+		IsPrimary    *bool   `db:"is_primary"     metric_name:"index.isPrimary"            source_type:"gauge"`
+		IsPartial    *bool   `db:"is_partial"     metric_name:"index.isPartial"            source_type:"gauge"`
+		IsExpression *bool   `db:"is_expression"  metric_name:"index.isExpression"         source_type:"gauge"`
+		IsValid      *bool   `db:"is_valid"       metric_name:"index.isValid"              source_type:"gauge"`
+		IsReady      *bool   `db:"is_ready"       metric_name:"index.isReady"              source_type:"gauge"`
+		Columns      *string `db:"index_columns"  metric_name:"index.columns"              source_type:"attribute"`
+		Tablespace   *string `db:"tablespace"     metric_name:"index.tablespace"           source_type:"attribute"`
+	}{},
+}
+
+// indexBloatDefinition estimates the bloat of B-tree indexes from pg_stats, the same way tableBloatDefinition
+// does for tables. The estimated size is the size the index would have if freshly built with its fillfactor.
+var indexBloatDefinition = &QueryDefinition{
+	query: `SELECT -- INDEXBLOATQUERY
+			current_database() as database,
+			nspname AS schema_name, tblname AS table_name, idxname AS index_name,
+			bs*est_pages_ff AS estimated_size,
+			CASE WHEN relpages > est_pages_ff
+				THEN bs*(relpages-est_pages_ff)
+				ELSE 0
+			END AS bloat_size,
+			CASE WHEN relpages > est_pages_ff
+				THEN 100 * (relpages-est_pages_ff)::float / relpages
+				ELSE 0
+			END AS bloat_ratio
+		FROM (
+			SELECT coalesce(1 +
+					ceil(reltuples/floor((bs-pageopqdata-pagehdr)*fillfactor/(100*(4+nulldatahdrwidth)::float))), 0
+				) AS est_pages_ff,
+				bs, nspname, tblname, idxname, relpages, fillfactor, is_na
+			FROM (
+				SELECT maxalign, bs, nspname, tblname, idxname, reltuples, relpages, idxoid, fillfactor,
+					( index_tuple_hdr_bm +
+						maxalign - CASE WHEN index_tuple_hdr_bm%maxalign = 0 THEN maxalign ELSE index_tuple_hdr_bm%maxalign END
+						+ nulldatawidth + maxalign - CASE
+							WHEN nulldatawidth = 0 THEN 0
+							WHEN nulldatawidth::integer%maxalign = 0 THEN maxalign
+							ELSE nulldatawidth::integer%maxalign
+						END
+					)::numeric AS nulldatahdrwidth, pagehdr, pageopqdata, is_na
+				FROM (
+					SELECT n.nspname, i.tblname, i.idxname, i.reltuples, i.relpages,
+						i.idxoid, i.fillfactor, current_setting('block_size')::numeric AS bs,
+						CASE WHEN version()~'mingw32' OR version()~'64-bit|x86_64|ppc64|ia64|amd64' THEN 8 ELSE 4 END AS maxalign,
+						24 AS pagehdr,
+						16 AS pageopqdata,
+						CASE WHEN max(coalesce(s.null_frac,0)) = 0
+							THEN 8
+							ELSE 8 + (( 32 + 8 - 1 ) / 8)
+						END AS index_tuple_hdr_bm,
+						sum( (1-coalesce(s.null_frac, 0)) * coalesce(s.avg_width, 1024)) AS nulldatawidth,
+						max( CASE WHEN i.atttypid = 'pg_catalog.name'::regtype THEN 1 ELSE 0 END ) > 0 AS is_na
+					FROM (
+						SELECT ct.relname AS tblname, ct.relnamespace, ic.idxname, ic.attpos, ic.indkey, ic.indkey[ic.attpos],
+							ic.reltuples, ic.relpages, ic.tbloid, ic.idxoid, ic.fillfactor,
+							coalesce(a1.attnum, a2.attnum) AS attnum, coalesce(a1.attname, a2.attname) AS attname,
+							coalesce(a1.atttypid, a2.atttypid) AS atttypid,
+							CASE WHEN a1.attnum IS NULL THEN ic.idxname ELSE ct.relname END AS attrelname
+						FROM (
+							SELECT idxname, reltuples, relpages, tbloid, idxoid, fillfactor, indkey,
+								generate_series(1, indnatts) AS attpos
+							FROM (
+								SELECT ci.relname AS idxname, ci.reltuples, ci.relpages, i.indrelid AS tbloid,
+									i.indexrelid AS idxoid,
+									coalesce(substring(
+										array_to_string(ci.reloptions, ' ')
+										FROM 'fillfactor=([0-9]+)')::smallint, 90) AS fillfactor,
+									i.indnatts,
+									string_to_array(textin(int2vectorout(i.indkey)), ' ')::int[] AS indkey
+								FROM pg_index i
+								JOIN pg_class ci ON ci.oid = i.indexrelid
+								WHERE ci.relam = (SELECT oid FROM pg_am WHERE amname = 'btree')
+									AND ci.relpages > 0
+							) AS idx_data
+						) AS ic
+						JOIN pg_class ct ON ct.oid = ic.tbloid
+						LEFT JOIN pg_attribute a1 ON ic.indkey[ic.attpos] <> 0
+							AND a1.attrelid = ic.tbloid
+							AND a1.attnum = ic.indkey[ic.attpos]
+						LEFT JOIN pg_attribute a2 ON ic.indkey[ic.attpos] = 0
+							AND a2.attrelid = ic.idxoid
+							AND a2.attnum = ic.attpos
+					) i
+					JOIN pg_namespace n ON n.oid = i.relnamespace
+					JOIN pg_stats s ON s.schemaname = n.nspname
+						AND s.tablename = i.attrelname
+						AND s.attname = i.attname
+					GROUP BY 1,2,3,4,5,6,7,8,9,10,11
+				) AS rows_data_stats
+			) AS rows_hdr_pdg_stats
+		) AS relation_stats
+		where not is_na
+		and nspname || '.' || tblname || '.' || idxname in (%SCHEMA_TABLE_INDEXES%);`,
+
+	dataModels: []struct {
+		databaseBase
+		schemaBase
+		tableBase
+		indexBase
+		EstimatedSize *float64 `db:"estimated_size" metric_name:"index.estimatedSizeInBytes" source_type:"gauge"`
+		BloatSize     *float64 `db:"bloat_size"     metric_name:"index.bloatSizeInBytes"     source_type:"gauge"`
+		BloatRatio    *float64 `db:"bloat_ratio"    metric_name:"index.bloatRatio"           source_type:"gauge"`
+	}{},
+}
+
+// indexHealthDefinition compares all the indexes of the database and reports the selected ones that are
+// unused since the statistics were reset, invalid or not ready (usually left by a failed
+// CREATE INDEX CONCURRENTLY), exact duplicates of another index, or redundant because their columns are
+// a leading prefix of another index. Unique indexes enforce constraints, so they are never reported as
+// unused or redundant. Only one index of each duplicate pair is reported, the one to drop: indexes backing a
+// constraint are kept over unique ones, which are kept over the others, and the oldest index is kept otherwise.
+var indexHealthDefinition = &QueryDefinition{
+	query: `WITH I AS (
+				SELECT
+					N.nspname AS schema_name,
+					T.relname AS table_name,
+					C.relname AS index_name,
+					X.indexrelid, X.indrelid, X.indisunique, X.indisvalid, X.indisready, C.relam,
+					CASE WHEN EXISTS (SELECT 1 FROM pg_constraint K WHERE K.conindid = X.indexrelid) THEN 2
+						WHEN X.indisunique THEN 1 ELSE 0 END AS keep_rank,
+					X.indkey::text AS indkey,
+					X.indclass::text AS indclass,
+					X.indoption::text AS indoption,
+					coalesce(pg_get_expr(X.indexprs, X.indrelid), '') AS exprs,
+					coalesce(pg_get_expr(X.indpred, X.indrelid), '') AS pred,
+					S.idx_scan,
+					pg_relation_size(X.indexrelid) AS index_size
+				FROM pg_index X
+				JOIN pg_class C ON C.oid = X.indexrelid
+				JOIN pg_class T ON T.oid = X.indrelid
+				JOIN pg_namespace N ON N.oid = T.relnamespace
+				LEFT JOIN pg_stat_all_indexes S ON S.indexrelid = X.indexrelid
+				WHERE N.nspname NOT IN ('pg_catalog', 'information_schema') AND N.nspname !~ '^pg_toast'
+			)
+			SELECT -- INDEX_HEALTH
+				current_database() AS database, schema_name, table_name, index_name, issue, related_index, index_size
+			FROM (
+				SELECT I.schema_name, I.table_name, I.index_name, 'unused'::text AS issue, NULL::text AS related_index, I.index_size
+					FROM I
+					WHERE I.idx_scan = 0 AND NOT I.indisunique AND I.indisvalid
+				UNION ALL
+				SELECT I.schema_name, I.table_name, I.index_name, 'invalid'::text, NULL::text, I.index_size
+					FROM I
+					WHERE NOT I.indisvalid OR NOT I.indisready
+				UNION ALL
+				SELECT A.schema_name, A.table_name, A.index_name, 'duplicate'::text, B.index_name::text, A.index_size
+					FROM I A
+					JOIN I B ON B.indrelid = A.indrelid AND B.indexrelid <> A.indexrelid AND B.relam = A.relam
+						AND B.indkey = A.indkey AND B.indclass = A.indclass AND B.indoption = A.indoption
+						AND B.exprs = A.exprs AND B.pred = A.pred
+					WHERE A.indisvalid AND B.indisvalid
+						AND (B.keep_rank > A.keep_rank OR (B.keep_rank = A.keep_rank AND B.indexrelid < A.indexrelid))
+				UNION ALL
+				SELECT A.schema_name, A.table_name, A.index_name, 'redundant'::text, B.index_name::text, A.index_size
+					FROM I A
+					JOIN I B ON B.indrelid = A.indrelid AND B.indexrelid <> A.indexrelid AND B.relam = A.relam
+						AND B.indkey LIKE A.indkey || ' %' AND B.indclass LIKE A.indclass || ' %' AND B.indoption LIKE A.indoption || ' %'
+						AND A.exprs = '' AND B.exprs = '' AND A.pred = '' AND B.pred = ''
+					WHERE NOT A.indisunique AND A.indisvalid AND B.indisvalid
+			) H
+			WHERE schema_name || '.' || table_name || '.' || index_name IN (%SCHEMA_TABLE_INDEXES%)
+			ORDER BY 1, 2, 3, 4;`,
+
+	dataModels: []struct {
+		databaseBase
+		schemaBase
+		tableBase
+		indexBase
+		Issue        *string `db:"issue"         metric_name:"indexHealth.issue"            source_type:"attribute"`
+		RelatedIndex *string `db:"related_index" metric_name:"indexHealth.relatedIndex"     source_type:"attribute"`
+		IndexSize    *int64  `db:"index_size"    metric_name:"indexHealth.indexSizeInBytes" source_type:"gauge"`
 	}{},
 }
@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/newrelic/nri-postgresql/src/collection"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_generateIndexDefinitions(t *testing.T) {
+	schemaList := collection.SchemaList{
+		"schema1": collection.TableList{
+			"table1": []string{"index1"},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		schemaList      collection.SchemaList
+		collectBloat    bool
+		expectedQueries []string
+	}{
+		{
+			name:            "Without bloat",
+			schemaList:      schemaList,
+			expectedQueries: []string{"INDEXQUERY"},
+		},
+		{
+			name:            "With bloat",
+			schemaList:      schemaList,
+			collectBloat:    true,
+			expectedQueries: []string{"INDEXQUERY", "INDEXBLOATQUERY"},
+		},
+		{
+			name:            "No indexes",
+			schemaList:      collection.SchemaList{"schema1": collection.TableList{"table1": []string{}}},
+			collectBloat:    true,
+			expectedQueries: []string{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			queryDefinitions := generateIndexDefinitions(tc.schemaList, tc.collectBloat)
+			assert.Len(t, queryDefinitions, len(tc.expectedQueries))
+			for i, expected := range tc.expectedQueries {
+				assert.Contains(t, queryDefinitions[i].GetQuery(), "-- "+expected+"\n")
+				assert.Contains(t, queryDefinitions[i].GetQuery(), "in ('schema1.table1.index1')")
+			}
+		})
+	}
+}
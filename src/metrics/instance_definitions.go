@@ -16,6 +16,16 @@ var versionDefinitions = []VersionDefinition{
 			instanceDefinitionBase170,
 			instanceDefinition170,
 			instanceDefinitionInputOutput170,
+			instanceDefinitionRecoveryPrefetch150,
+		},
+	},
+	{
+		minVersion: semver.MustParse("15.0.0"),
+		queryDefinitions: []*QueryDefinition{
+			instanceDefinitionBase,
+			instanceDefinition91,
+			instanceDefinition92,
+			instanceDefinitionRecoveryPrefetch150,
 		},
 	},
 	{
@@ -35,6 +45,14 @@ var versionDefinitions = []VersionDefinition{
 	},
 }
 
+// slruDefinitions are ordered from the newest version, only the first applicable one is used
+var slruDefinitions = []VersionDefinition{
+	{
+		minVersion:       semver.MustParse("13.0.0"),
+		queryDefinitions: []*QueryDefinition{instanceDefinitionSlru130},
+	},
+}
+
 func generateInstanceDefinitions(version *semver.Version) []*QueryDefinition {
 	// Find the first version definition that's applicable
 	for _, versionDef := range versionDefinitions {
@@ -46,6 +64,16 @@ func generateInstanceDefinitions(version *semver.Version) []*QueryDefinition {
 	return []*QueryDefinition{instanceDefinitionBase}
 }
 
+func generateSlruDefinitions(version *semver.Version) []*QueryDefinition {
+	for _, versionDef := range slruDefinitions {
+		if version.GE(versionDef.minVersion) {
+			return versionDef.queryDefinitions
+		}
+	}
+
+	return []*QueryDefinition{}
+}
+
 var instanceDefinitionBase = &QueryDefinition{
 	query: `SELECT
 		BG.checkpoints_timed AS scheduled_checkpoints_performed,
@@ -133,3 +161,59 @@ var instanceDefinitionInputOutput170 = &QueryDefinition{
 		BackendExecutedOwnFsync *int64 `db:"times_backend_executed_own_fsync" metric_name:"io.backendFsyncCallsPerSecond"        source_type:"rate"`
 	}{},
 }
+
+// instanceDefinitionRecoveryPrefetch150 reports how effective the prefetching of blocks referenced in the
+// WAL is during recovery. It only changes on standbys, and when recovery_prefetch is enabled.
+var instanceDefinitionRecoveryPrefetch150 = &QueryDefinition{
+	query: `SELECT -- RECOVERY_PREFETCH_150
+		RP.prefetch AS prefetched,
+		RP.hit AS hit,
+		RP.skip_init AS skipped_init,
+		RP.skip_new AS skipped_new,
+		RP.skip_fpw AS skipped_full_page_write,
+		RP.skip_rep AS skipped_recently_prefetched,
+		RP.wal_distance AS wal_distance,
+		RP.block_distance AS block_distance,
+		RP.io_depth AS io_depth
+		FROM pg_stat_recovery_prefetch RP;`,
+
+	dataModels: []struct {
+		Prefetched                *int64 `db:"prefetched"                  metric_name:"recoveryPrefetch.prefetchesPerSecond"                source_type:"rate"`
+		Hit                       *int64 `db:"hit"                         metric_name:"recoveryPrefetch.hitsPerSecond"                      source_type:"rate"`
+		SkippedInit               *int64 `db:"skipped_init"                metric_name:"recoveryPrefetch.skippedInitPerSecond"               source_type:"rate"`
+		SkippedNew                *int64 `db:"skipped_new"                 metric_name:"recoveryPrefetch.skippedNewPerSecond"                source_type:"rate"`
+		SkippedFullPageWrite      *int64 `db:"skipped_full_page_write"     metric_name:"recoveryPrefetch.skippedFullPageWritePerSecond"      source_type:"rate"`
+		SkippedRecentlyPrefetched *int64 `db:"skipped_recently_prefetched" metric_name:"recoveryPrefetch.skippedRecentlyPrefetchedPerSecond" source_type:"rate"`
+		WalDistance               *int64 `db:"wal_distance"                metric_name:"recoveryPrefetch.walDistanceInBytes"                 source_type:"gauge"`
+		BlockDistance             *int64 `db:"block_distance"              metric_name:"recoveryPrefetch.blockDistance"                      source_type:"gauge"`
+		IODepth                   *int64 `db:"io_depth"                    metric_name:"recoveryPrefetch.ioDepth"                            source_type:"gauge"`
+	}{},
+}
+
+// slruMetrics is a row of pg_stat_slru, reported with the SLRU name as attribute
+type slruMetrics struct {
+	Name          *string `db:"name"`
+	BlocksZeroed  *int64  `db:"blks_zeroed"  metric_name:"slru.blocksZeroedPerSecond"  source_type:"rate"`
+	BlocksHit     *int64  `db:"blks_hit"     metric_name:"slru.blocksHitPerSecond"     source_type:"rate"`
+	BlocksRead    *int64  `db:"blks_read"    metric_name:"slru.blocksReadPerSecond"    source_type:"rate"`
+	BlocksWritten *int64  `db:"blks_written" metric_name:"slru.blocksWrittenPerSecond" source_type:"rate"`
+	BlocksExists  *int64  `db:"blks_exists"  metric_name:"slru.blocksExistsPerSecond"  source_type:"rate"`
+	Flushes       *int64  `db:"flushes"      metric_name:"slru.flushesPerSecond"       source_type:"rate"`
+	Truncates     *int64  `db:"truncates"    metric_name:"slru.truncatesPerSecond"     source_type:"rate"`
+}
+
+// instanceDefinitionSlru130 reports the simple LRU caches, such as the subtransaction and multixact ones
+var instanceDefinitionSlru130 = &QueryDefinition{
+	query: `SELECT -- SLRU_130
+		S.name AS name,
+		S.blks_zeroed AS blks_zeroed,
+		S.blks_hit AS blks_hit,
+		S.blks_read AS blks_read,
+		S.blks_written AS blks_written,
+		S.blks_exists AS blks_exists,
+		S.flushes AS flushes,
+		S.truncates AS truncates
+		FROM pg_stat_slru S;`,
+
+	dataModels: []slruMetrics{},
+}
@@ -33,15 +33,20 @@ func Test_generateInstanceDefinitions(t *testing.T) {
 			version:         "10.2.0",
 			expectedQueries: []*QueryDefinition{instanceDefinitionBase, instanceDefinition91, instanceDefinition92},
 		},
+		{
+			name:            "PostgreSQL 14.9",
+			version:         "14.9.0",
+			expectedQueries: []*QueryDefinition{instanceDefinitionBase, instanceDefinition91, instanceDefinition92},
+		},
 		{
 			name:            "PostgreSQL 16.4",
 			version:         "16.4.2",
-			expectedQueries: []*QueryDefinition{instanceDefinitionBase, instanceDefinition91, instanceDefinition92},
+			expectedQueries: []*QueryDefinition{instanceDefinitionBase, instanceDefinition91, instanceDefinition92, instanceDefinitionRecoveryPrefetch150},
 		},
 		{
 			name:            "PostgreSQL 17.0",
 			version:         "17.0.0",
-			expectedQueries: []*QueryDefinition{instanceDefinitionBase170, instanceDefinition170, instanceDefinitionInputOutput170},
+			expectedQueries: []*QueryDefinition{instanceDefinitionBase170, instanceDefinition170, instanceDefinitionInputOutput170, instanceDefinitionRecoveryPrefetch150},
 		},
 	}
 
@@ -66,3 +71,11 @@ func Test_generateInstanceDefinitionsOutOfOrder(t *testing.T) {
 		assert.False(t, assert.ObjectsAreEqual(expectedQueries, queryDefinitions), "Query definitions should be in the correct order")
 	})
 }
+
+func Test_generateSlruDefinitions(t *testing.T) {
+	v12 := semver.MustParse("12.4.0")
+	assert.Empty(t, generateSlruDefinitions(&v12))
+
+	v13 := semver.MustParse("13.0.0")
+	assert.Equal(t, []*QueryDefinition{instanceDefinitionSlru130}, generateSlruDefinitions(&v13))
+}
@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"github.com/blang/semver/v4"
+	"github.com/newrelic/infra-integrations-sdk/v3/data/attribute"
+)
+
+func generateIODefinitions(version *semver.Version) []*QueryDefinition {
+	queryDefinitions := make([]*QueryDefinition, 0, 1)
+	if version.GE(semver.MustParse("18.0.0")) {
+		queryDefinitions = append(queryDefinitions, ioDefinitionOver18)
+	} else if version.GE(semver.MustParse("16.0.0")) {
+		queryDefinitions = append(queryDefinitions, ioDefinitionOver16)
+	}
+
+	return queryDefinitions
+}
+
+// IOModeler represents something identified by the backend type, object and context of pg_stat_io
+type IOModeler interface {
+	GetIOAttributes() []attribute.Attribute
+}
+
+// ioBase is a row of pg_stat_io. The timings are only reported while I/O timing is tracked,
+// otherwise pg_stat_io reports them as 0.
+type ioBase struct {
+	BackendType   *string  `db:"backend_type"`
+	Object        *string  `db:"object"`
+	Context       *string  `db:"context"`
+	Reads         *int64   `db:"reads"          metric_name:"io.readsPerSecond"                       source_type:"rate"`
+	ReadTime      *float64 `db:"read_time"      metric_name:"io.readTimeInMillisecondsPerSecond"      source_type:"rate"`
+	Writes        *int64   `db:"writes"         metric_name:"io.writesPerSecond"                      source_type:"rate"`
+	WriteTime     *float64 `db:"write_time"     metric_name:"io.writeTimeInMillisecondsPerSecond"     source_type:"rate"`
+	Writebacks    *int64   `db:"writebacks"     metric_name:"io.writebacksPerSecond"                  source_type:"rate"`
+	WritebackTime *float64 `db:"writeback_time" metric_name:"io.writebackTimeInMillisecondsPerSecond" source_type:"rate"`
+	Extends       *int64   `db:"extends"        metric_name:"io.extendsPerSecond"                     source_type:"rate"`
+	ExtendTime    *float64 `db:"extend_time"    metric_name:"io.extendTimeInMillisecondsPerSecond"    source_type:"rate"`
+	Hits          *int64   `db:"hits"           metric_name:"io.hitsPerSecond"                        source_type:"rate"`
+	Evictions     *int64   `db:"evictions"      metric_name:"io.evictionsPerSecond"                   source_type:"rate"`
+	Reuses        *int64   `db:"reuses"         metric_name:"io.reusesPerSecond"                      source_type:"rate"`
+	Fsyncs        *int64   `db:"fsyncs"         metric_name:"io.fsyncsPerSecond"                      source_type:"rate"`
+	FsyncTime     *float64 `db:"fsync_time"     metric_name:"io.fsyncTimeInMillisecondsPerSecond"     source_type:"rate"`
+}
+
+// GetIOAttributes returns the attributes identifying the row, which must be part of the metric set
+// namespace for the rates of each row to be computed separately
+func (d ioBase) GetIOAttributes() []attribute.Attribute {
+	attributes := make([]attribute.Attribute, 0, 3)
+	if d.BackendType != nil {
+		attributes = append(attributes, attribute.Attribute{Key: "io.backendType", Value: *d.BackendType})
+	}
+	if d.Object != nil {
+		attributes = append(attributes, attribute.Attribute{Key: "io.object", Value: *d.Object})
+	}
+	if d.Context != nil {
+		attributes = append(attributes, attribute.Attribute{Key: "io.context", Value: *d.Context})
+	}
+	return attributes
+}
+
+// ioDefinitionOver16 skips the combinations that never had any I/O, which are most of pg_stat_io
+var ioDefinitionOver16 = &QueryDefinition{
+	query: `SELECT -- IO_OVER16
+			backend_type, object, context,
+			reads, writes, writebacks, extends, hits, evictions, reuses, fsyncs,
+			CASE WHEN current_setting('track_io_timing') = 'on' THEN read_time END AS read_time,
+			CASE WHEN current_setting('track_io_timing') = 'on' THEN write_time END AS write_time,
+			CASE WHEN current_setting('track_io_timing') = 'on' THEN writeback_time END AS writeback_time,
+			CASE WHEN current_setting('track_io_timing') = 'on' THEN extend_time END AS extend_time,
+			CASE WHEN current_setting('track_io_timing') = 'on' THEN fsync_time END AS fsync_time
+		FROM pg_stat_io
+		WHERE reads > 0 OR writes > 0 OR writebacks > 0 OR extends > 0 OR hits > 0 OR evictions > 0 OR reuses > 0 OR fsyncs > 0;`,
+
+	dataModels: []struct {
+		ioBase
+	}{},
+}
+
+// ioDefinitionOver18 adds the bytes read, written and extended. The timings of the wal object
+// are tracked by track_wal_io_timing instead of track_io_timing.
+var ioDefinitionOver18 = &QueryDefinition{
+	query: `SELECT -- IO_OVER18
+			backend_type, object, context,
+			reads, writes, writebacks, extends, hits, evictions, reuses, fsyncs,
+			read_bytes, write_bytes, extend_bytes,
+			CASE WHEN timing = 'on' THEN read_time END AS read_time,
+			CASE WHEN timing = 'on' THEN write_time END AS write_time,
+			CASE WHEN timing = 'on' THEN writeback_time END AS writeback_time,
+			CASE WHEN timing = 'on' THEN extend_time END AS extend_time,
+			CASE WHEN timing = 'on' THEN fsync_time END AS fsync_time
+		FROM (
+			SELECT IO.*,
+				current_setting(CASE WHEN IO.object = 'wal' THEN 'track_wal_io_timing' ELSE 'track_io_timing' END) AS timing
+			FROM pg_stat_io IO
+		) S
+		WHERE reads > 0 OR writes > 0 OR writebacks > 0 OR extends > 0 OR hits > 0 OR evictions > 0 OR reuses > 0 OR fsyncs > 0;`,
+
+	dataModels: []struct {
+		ioBase
+		ReadBytes   *int64 `db:"read_bytes"   metric_name:"io.readBytesPerSecond"   source_type:"rate"`
+		WriteBytes  *int64 `db:"write_bytes"  metric_name:"io.writeBytesPerSecond"  source_type:"rate"`
+		ExtendBytes *int64 `db:"extend_bytes" metric_name:"io.extendBytesPerSecond" source_type:"rate"`
+	}{},
+}
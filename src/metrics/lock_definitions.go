@@ -1,68 +1,191 @@
 package metrics
 
 import (
+	"strings"
+
+	"github.com/blang/semver/v4"
 	"github.com/newrelic/nri-postgresql/src/collection"
 )
 
-func generateLockDefinitions(databases collection.DatabaseList) []*QueryDefinition {
+func generateLockDefinitions(databases collection.DatabaseList, version *semver.Version) []*QueryDefinition {
 	queryDefinitions := make([]*QueryDefinition, 0, 1)
 	if len(databases) == 0 {
 		return queryDefinitions
 	}
 
-	queryDefinitions = append(queryDefinitions, lockDefinitions.insertDatabaseNames(databases))
+	if version.GE(semver.MustParse("14.0.0")) {
+		queryDefinitions = append(queryDefinitions, lockDefinitionsOver14.insertDatabaseNames(databases))
+	} else {
+		queryDefinitions = append(queryDefinitions, lockDefinitions.insertDatabaseNames(databases))
+	}
+
+	return queryDefinitions
+}
+
+func generateRelationLockDefinitions(schemaList collection.SchemaList, version *semver.Version) []*QueryDefinition {
+	queryDefinitions := make([]*QueryDefinition, 0, 1)
+
+	definition := relationLockDefinition
+	if version.GE(semver.MustParse("14.0.0")) {
+		definition = relationLockDefinitionOver14
+	}
+
+	if def := definition.insertSchemaTables(schemaList); def != nil {
+		queryDefinitions = append(queryDefinitions, def)
+	}
 
 	return queryDefinitions
 }
 
+// lockBase holds the locks held or awaited by the sessions connected to a database, per mode
+type lockBase struct {
+	databaseBase
+	AccessExclusiveLock             *int64 `db:"access_exclusive_lock"               metric_name:"db.locks.accessExclusiveLock"             source_type:"gauge"`
+	AccessShareLock                 *int64 `db:"access_share_lock"                   metric_name:"db.locks.accessShareLock"                 source_type:"gauge"`
+	ExclusiveLock                   *int64 `db:"exclusive_lock"                      metric_name:"db.locks.exclusiveLock"                   source_type:"gauge"`
+	RowExclusiveLock                *int64 `db:"row_exclusive_lock"                  metric_name:"db.locks.rowExclusiveLock"                source_type:"gauge"`
+	RowShareLock                    *int64 `db:"row_share_lock"                      metric_name:"db.locks.rowShareLock"                    source_type:"gauge"`
+	ShareLock                       *int64 `db:"share_lock"                          metric_name:"db.locks.shareLock"                       source_type:"gauge"`
+	ShareRowExclusiveLock           *int64 `db:"share_row_exclusive_lock"            metric_name:"db.locks.shareRowExclusiveLock"           source_type:"gauge"`
+	ShareUpdateExclusiveLock        *int64 `db:"share_update_exclusive_lock"         metric_name:"db.locks.shareUpdateExclusiveLock"        source_type:"gauge"`
+	AccessExclusiveLockGranted      *int64 `db:"access_exclusive_lock_granted"       metric_name:"db.locks.accessExclusiveLockGranted"      source_type:"gauge"`
+	AccessShareLockGranted          *int64 `db:"access_share_lock_granted"           metric_name:"db.locks.accessShareLockGranted"          source_type:"gauge"`
+	ExclusiveLockGranted            *int64 `db:"exclusive_lock_granted"              metric_name:"db.locks.exclusiveLockGranted"            source_type:"gauge"`
+	RowExclusiveLockGranted         *int64 `db:"row_exclusive_lock_granted"          metric_name:"db.locks.rowExclusiveLockGranted"         source_type:"gauge"`
+	RowShareLockGranted             *int64 `db:"row_share_lock_granted"              metric_name:"db.locks.rowShareLockGranted"             source_type:"gauge"`
+	ShareLockGranted                *int64 `db:"share_lock_granted"                  metric_name:"db.locks.shareLockGranted"                source_type:"gauge"`
+	ShareRowExclusiveLockGranted    *int64 `db:"share_row_exclusive_lock_granted"    metric_name:"db.locks.shareRowExclusiveLockGranted"    source_type:"gauge"`
+	ShareUpdateExclusiveLockGranted *int64 `db:"share_update_exclusive_lock_granted" metric_name:"db.locks.shareUpdateExclusiveLockGranted" source_type:"gauge"`
+	AccessExclusiveLockWaiting      *int64 `db:"access_exclusive_lock_waiting"       metric_name:"db.locks.accessExclusiveLockWaiting"      source_type:"gauge"`
+	AccessShareLockWaiting          *int64 `db:"access_share_lock_waiting"           metric_name:"db.locks.accessShareLockWaiting"          source_type:"gauge"`
+	ExclusiveLockWaiting            *int64 `db:"exclusive_lock_waiting"              metric_name:"db.locks.exclusiveLockWaiting"            source_type:"gauge"`
+	RowExclusiveLockWaiting         *int64 `db:"row_exclusive_lock_waiting"          metric_name:"db.locks.rowExclusiveLockWaiting"         source_type:"gauge"`
+	RowShareLockWaiting             *int64 `db:"row_share_lock_waiting"              metric_name:"db.locks.rowShareLockWaiting"             source_type:"gauge"`
+	ShareLockWaiting                *int64 `db:"share_lock_waiting"                  metric_name:"db.locks.shareLockWaiting"                source_type:"gauge"`
+	ShareRowExclusiveLockWaiting    *int64 `db:"share_row_exclusive_lock_waiting"    metric_name:"db.locks.shareRowExclusiveLockWaiting"    source_type:"gauge"`
+	ShareUpdateExclusiveLockWaiting *int64 `db:"share_update_exclusive_lock_waiting" metric_name:"db.locks.shareUpdateExclusiveLockWaiting" source_type:"gauge"`
+	Granted                         *int64 `db:"granted"                             metric_name:"db.locks.granted"                         source_type:"gauge"`
+	Waiting                         *int64 `db:"waiting"                             metric_name:"db.locks.waiting"                         source_type:"gauge"`
+}
+
+// lockQuery counts the locks of each mode with conditional aggregation, so no extension is needed. The mode
+// totals include both granted and awaited locks. The versions only differ by the age of the oldest wait,
+// inserted at %OLDEST_WAIT%.
+const lockQuery = `SELECT -- LOCKS_DEFINITION
+			A.datname AS database,
+			sum(CASE WHEN L.mode = 'AccessExclusiveLock' THEN 1 ELSE 0 END) AS access_exclusive_lock,
+			sum(CASE WHEN L.mode = 'AccessShareLock' THEN 1 ELSE 0 END) AS access_share_lock,
+			sum(CASE WHEN L.mode = 'ExclusiveLock' THEN 1 ELSE 0 END) AS exclusive_lock,
+			sum(CASE WHEN L.mode = 'RowExclusiveLock' THEN 1 ELSE 0 END) AS row_exclusive_lock,
+			sum(CASE WHEN L.mode = 'RowShareLock' THEN 1 ELSE 0 END) AS row_share_lock,
+			sum(CASE WHEN L.mode = 'ShareLock' THEN 1 ELSE 0 END) AS share_lock,
+			sum(CASE WHEN L.mode = 'ShareRowExclusiveLock' THEN 1 ELSE 0 END) AS share_row_exclusive_lock,
+			sum(CASE WHEN L.mode = 'ShareUpdateExclusiveLock' THEN 1 ELSE 0 END) AS share_update_exclusive_lock,
+			sum(CASE WHEN L.mode = 'AccessExclusiveLock' AND L.granted THEN 1 ELSE 0 END) AS access_exclusive_lock_granted,
+			sum(CASE WHEN L.mode = 'AccessExclusiveLock' AND NOT L.granted THEN 1 ELSE 0 END) AS access_exclusive_lock_waiting,
+			sum(CASE WHEN L.mode = 'AccessShareLock' AND L.granted THEN 1 ELSE 0 END) AS access_share_lock_granted,
+			sum(CASE WHEN L.mode = 'AccessShareLock' AND NOT L.granted THEN 1 ELSE 0 END) AS access_share_lock_waiting,
+			sum(CASE WHEN L.mode = 'ExclusiveLock' AND L.granted THEN 1 ELSE 0 END) AS exclusive_lock_granted,
+			sum(CASE WHEN L.mode = 'ExclusiveLock' AND NOT L.granted THEN 1 ELSE 0 END) AS exclusive_lock_waiting,
+			sum(CASE WHEN L.mode = 'RowExclusiveLock' AND L.granted THEN 1 ELSE 0 END) AS row_exclusive_lock_granted,
+			sum(CASE WHEN L.mode = 'RowExclusiveLock' AND NOT L.granted THEN 1 ELSE 0 END) AS row_exclusive_lock_waiting,
+			sum(CASE WHEN L.mode = 'RowShareLock' AND L.granted THEN 1 ELSE 0 END) AS row_share_lock_granted,
+			sum(CASE WHEN L.mode = 'RowShareLock' AND NOT L.granted THEN 1 ELSE 0 END) AS row_share_lock_waiting,
+			sum(CASE WHEN L.mode = 'ShareLock' AND L.granted THEN 1 ELSE 0 END) AS share_lock_granted,
+			sum(CASE WHEN L.mode = 'ShareLock' AND NOT L.granted THEN 1 ELSE 0 END) AS share_lock_waiting,
+			sum(CASE WHEN L.mode = 'ShareRowExclusiveLock' AND L.granted THEN 1 ELSE 0 END) AS share_row_exclusive_lock_granted,
+			sum(CASE WHEN L.mode = 'ShareRowExclusiveLock' AND NOT L.granted THEN 1 ELSE 0 END) AS share_row_exclusive_lock_waiting,
+			sum(CASE WHEN L.mode = 'ShareUpdateExclusiveLock' AND L.granted THEN 1 ELSE 0 END) AS share_update_exclusive_lock_granted,
+			sum(CASE WHEN L.mode = 'ShareUpdateExclusiveLock' AND NOT L.granted THEN 1 ELSE 0 END) AS share_update_exclusive_lock_waiting,
+			sum(CASE WHEN L.granted THEN 1 ELSE 0 END) AS granted,
+			sum(CASE WHEN NOT L.granted THEN 1 ELSE 0 END) AS waiting,
+			%OLDEST_WAIT%
+		FROM pg_locks L
+		JOIN pg_stat_activity A ON A.pid = L.pid
+		WHERE A.datname IN (%DATABASES%)
+		GROUP BY A.datname;`
+
+// lockDefinitions reports the age of the oldest statement waiting for a lock, as before PostgreSQL 14 pg_locks
+// does not tell when a wait started. It includes the time the statement ran before waiting.
 var lockDefinitions = &QueryDefinition{
-	query: `SELECT -- LOCKS_DEFINITION
-                 database,
-                 COALESCE(access_exclusive_lock, 0) AS access_exclusive_lock,
-                 COALESCE(access_share_lock, 0) AS access_share_lock,
-                 COALESCE(exclusive_lock, 0) AS exclusive_lock,
-                 COALESCE(row_exclusive_lock, 0) AS row_exclusive_lock,
-                 COALESCE(row_share_lock, 0) AS row_share_lock,
-                 COALESCE(share_lock, 0) AS share_lock,
-                 COALESCE(share_row_exclusive_lock, 0) AS share_row_exclusive_lock,
-                 COALESCE(share_update_exclusive_lock, 0) AS share_update_exclusive_lock
-            FROM public.crosstab(
-                  $$SELECT psa.datname AS database,
-                           lock.mode,
-                           count(lock.mode)
-                     FROM pg_locks AS lock
-                LEFT JOIN pg_stat_activity AS psa ON lock.pid = psa.pid
-                    WHERE psa.datname IN (%DATABASES%)
-                 GROUP BY lock.database, lock.mode, psa.datname
-                 ORDER BY database,mode$$,
-                 $$VALUES ('AccessExclusiveLock'::text),
-                          ('AccessShareLock'::text),
-                          ('ExclusiveLock'::text),
-                          ('RowExclusiveLock'::text),
-                          ('RowShareLock'::text),
-                          ('ShareLock'::text),
-                          ('ShareRowExclusiveLock'::text),
-                          ('ShareUpdateExclusiveLock'::text) $$
-           ) AS data (
-                 database text,
-                 access_exclusive_lock numeric,
-                 access_share_lock numeric,
-                 exclusive_lock numeric,
-                 row_exclusive_lock numeric,
-                 row_share_lock numeric,
-                 share_lock numeric,
-                 share_row_exclusive_lock numeric,
-                 share_update_exclusive_lock numeric
-          );`,
+	query: strings.Replace(lockQuery, "%OLDEST_WAIT%",
+		"max(CASE WHEN NOT L.granted THEN extract(epoch FROM now() - A.query_start) END) AS oldest_waiting_statement", 1),
+
+	dataModels: []struct {
+		lockBase
+		OldestWaitingStatement *float64 `db:"oldest_waiting_statement" metric_name:"db.locks.oldestWaitingStatementAgeInSeconds" source_type:"gauge"`
+	}{},
+}
+
+// lockDefinitionsOver14 uses the time the wait started, from pg_locks
+var lockDefinitionsOver14 = &QueryDefinition{
+	query: strings.Replace(lockQuery, "%OLDEST_WAIT%",
+		"max(extract(epoch FROM now() - L.waitstart)) AS oldest_wait", 1),
+
+	dataModels: []struct {
+		lockBase
+		OldestWait *float64 `db:"oldest_wait" metric_name:"db.locks.oldestWaitInSeconds" source_type:"gauge"`
+	}{},
+}
+
+// relationLockBase holds the locks held or awaited on a table, reported in a PostgresqlTableLockSample
+type relationLockBase struct {
+	databaseBase
+	schemaBase
+	tableBase
+	Locks               *int64 `db:"locks"                 metric_name:"table.locks.total"               source_type:"gauge"`
+	Granted             *int64 `db:"granted"               metric_name:"table.locks.granted"             source_type:"gauge"`
+	Waiting             *int64 `db:"waiting"               metric_name:"table.locks.waiting"             source_type:"gauge"`
+	AccessExclusiveLock *int64 `db:"access_exclusive_lock" metric_name:"table.locks.accessExclusiveLock" source_type:"gauge"`
+}
+
+// relationLockDefinition counts the relation locks on the collected tables of the current database. As for
+// lockDefinitions, the age of the oldest statement waiting for a lock is reported before PostgreSQL 14.
+var relationLockDefinition = &QueryDefinition{
+	query: `SELECT -- RELATION_LOCKS
+			current_database() AS database,
+			N.nspname AS schema_name,
+			C.relname AS table_name,
+			count(*) AS locks,
+			sum(CASE WHEN L.granted THEN 1 ELSE 0 END) AS granted,
+			sum(CASE WHEN NOT L.granted THEN 1 ELSE 0 END) AS waiting,
+			sum(CASE WHEN L.mode = 'AccessExclusiveLock' THEN 1 ELSE 0 END) AS access_exclusive_lock,
+			max(CASE WHEN NOT L.granted THEN extract(epoch FROM now() - A.query_start) END) AS oldest_waiting_statement
+		FROM pg_locks L
+		JOIN pg_class C ON C.oid = L.relation
+		JOIN pg_namespace N ON N.oid = C.relnamespace
+		LEFT JOIN pg_stat_activity A ON A.pid = L.pid
+		WHERE L.locktype = 'relation'
+			AND L.database = (SELECT oid FROM pg_database WHERE datname = current_database())
+			AND N.nspname || '.' || C.relname IN (%SCHEMA_TABLES%)
+		GROUP BY N.nspname, C.relname;`,
+
+	dataModels: []struct {
+		relationLockBase
+		OldestWaitingStatement *float64 `db:"oldest_waiting_statement" metric_name:"table.locks.oldestWaitingStatementAgeInSeconds" source_type:"gauge"`
+	}{},
+}
+
+var relationLockDefinitionOver14 = &QueryDefinition{
+	query: `SELECT -- RELATION_LOCKS_OVER14
+			current_database() AS database,
+			N.nspname AS schema_name,
+			C.relname AS table_name,
+			count(*) AS locks,
+			sum(CASE WHEN L.granted THEN 1 ELSE 0 END) AS granted,
+			sum(CASE WHEN NOT L.granted THEN 1 ELSE 0 END) AS waiting,
+			sum(CASE WHEN L.mode = 'AccessExclusiveLock' THEN 1 ELSE 0 END) AS access_exclusive_lock,
+			max(extract(epoch FROM now() - L.waitstart)) AS oldest_wait
+		FROM pg_locks L
+		JOIN pg_class C ON C.oid = L.relation
+		JOIN pg_namespace N ON N.oid = C.relnamespace
+		WHERE L.locktype = 'relation'
+			AND L.database = (SELECT oid FROM pg_database WHERE datname = current_database())
+			AND N.nspname || '.' || C.relname IN (%SCHEMA_TABLES%)
+		GROUP BY N.nspname, C.relname;`,
+
 	dataModels: []struct {
-		databaseBase
-		AccessExclusiveLock      *int64 `db:"access_exclusive_lock" metric_name:"db.locks.accessExclusiveLock" source_type:"gauge"`
-		AccessShareLock          *int64 `db:"access_share_lock" metric_name:"db.locks.accessShareLock" source_type:"gauge"`
-		ExclusiveLock            *int64 `db:"exclusive_lock" metric_name:"db.locks.exclusiveLock" source_type:"gauge"`
-		RowExclusiveLock         *int64 `db:"row_exclusive_lock" metric_name:"db.locks.rowExclusiveLock" source_type:"gauge"`
-		RowShareLock             *int64 `db:"row_share_lock" metric_name:"db.locks.rowShareLock" source_type:"gauge"`
-		ShareLock                *int64 `db:"share_lock" metric_name:"db.locks.shareLock" source_type:"gauge"`
-		ShareRowExclusiveLock    *int64 `db:"share_row_exclusive_lock" metric_name:"db.locks.shareRowExclusiveLock" source_type:"gauge"`
-		ShareUpdateExclusiveLock *int64 `db:"share_update_exclusive_lock" metric_name:"db.locks.shareUpdateExclusiveLock" source_type:"gauge"`
+		relationLockBase
+		OldestWait *float64 `db:"oldest_wait" metric_name:"table.locks.oldestWaitInSeconds" source_type:"gauge"`
 	}{},
 }
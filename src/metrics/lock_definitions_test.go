@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/newrelic/nri-postgresql/src/collection"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_generateLockDefinitions(t *testing.T) {
+	databases := collection.DatabaseList{"db1": {}}
+
+	version := semver.MustParse("13.2.0")
+	definitions := generateLockDefinitions(databases, &version)
+	assert.Len(t, definitions, 1)
+	assert.Contains(t, definitions[0].GetQuery(), "LOCKS_DEFINITION\n")
+	assert.Contains(t, definitions[0].GetQuery(), "AS oldest_waiting_statement\n")
+	assert.Contains(t, definitions[0].GetQuery(), "IN ('db1')")
+
+	version = semver.MustParse("14.0.0")
+	definitions = generateLockDefinitions(databases, &version)
+	assert.Len(t, definitions, 1)
+	assert.Contains(t, definitions[0].GetQuery(), "L.waitstart)) AS oldest_wait\n")
+	assert.NotContains(t, definitions[0].GetQuery(), "%OLDEST_WAIT%")
+
+	assert.Empty(t, generateLockDefinitions(collection.DatabaseList{}, &version))
+}
+
+func Test_generateRelationLockDefinitions(t *testing.T) {
+	schemaList := collection.SchemaList{"schema1": {"table1": {}}}
+
+	version := semver.MustParse("9.6.0")
+	definitions := generateRelationLockDefinitions(schemaList, &version)
+	assert.Len(t, definitions, 1)
+	assert.Contains(t, definitions[0].GetQuery(), "RELATION_LOCKS\n")
+	assert.Contains(t, definitions[0].GetQuery(), "IN ('schema1.table1')")
+
+	version = semver.MustParse("16.1.0")
+	definitions = generateRelationLockDefinitions(schemaList, &version)
+	assert.Len(t, definitions, 1)
+	assert.Contains(t, definitions[0].GetQuery(), "RELATION_LOCKS_OVER14")
+
+	assert.Empty(t, generateRelationLockDefinitions(collection.SchemaList{}, &version))
+}
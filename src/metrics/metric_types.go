@@ -3,6 +3,7 @@ package metrics
 import (
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 
 	"github.com/newrelic/nri-postgresql/src/collection"
@@ -90,3 +91,70 @@ func (qd QueryDefinition) insertSchemaTableIndexes(schemaList collection.SchemaL
 
 	return newIndexDef
 }
+
+func (qd QueryDefinition) insertSchemaFunctions(schemaFunctions map[string][]string) *QueryDefinition {
+	functions := make([]string, 0)
+	for schema, functionList := range schemaFunctions {
+		for _, function := range functionList {
+			// signatures are matched without whitespace, so "f(integer, text)" and "f(integer,text)" are the same
+			if strings.Contains(function, "(") {
+				function = strings.Join(strings.Fields(function), "")
+			}
+			functions = append(functions, fmt.Sprintf("'%s.%s'", schema, function))
+		}
+	}
+
+	if len(functions) == 0 {
+		return nil
+	}
+
+	functionsString := strings.Join(functions, ",")
+
+	newFunctionDef := &QueryDefinition{
+		dataModels: qd.dataModels,
+		query:      strings.ReplaceAll(qd.query, `%SCHEMA_FUNCTIONS%`, functionsString),
+	}
+
+	return newFunctionDef
+}
+
+func (qd QueryDefinition) insertLimit(limit int) *QueryDefinition {
+	return &QueryDefinition{
+		dataModels: qd.dataModels,
+		query:      strings.Replace(qd.query, `%LIMIT%`, strconv.Itoa(limit), 1),
+	}
+}
+
+func (qd QueryDefinition) insertSchemaNames(schemaList collection.SchemaList) *QueryDefinition {
+	schemas := make([]string, 0)
+	for schema := range schemaList {
+		schemas = append(schemas, fmt.Sprintf("'%s'", schema))
+	}
+
+	if len(schemas) == 0 {
+		return nil
+	}
+
+	schemasString := strings.Join(schemas, ",")
+
+	newSchemaDef := &QueryDefinition{
+		dataModels: qd.dataModels,
+		query:      strings.Replace(qd.query, `%SCHEMAS%`, schemasString, 1),
+	}
+
+	return newSchemaDef
+}
+
+func (qd QueryDefinition) insertMaxSize(maxSize int64) *QueryDefinition {
+	return &QueryDefinition{
+		dataModels: qd.dataModels,
+		query:      strings.Replace(qd.query, `%MAX_SIZE%`, strconv.FormatInt(maxSize, 10), 1),
+	}
+}
+
+func (qd QueryDefinition) insertRelationOID(oid int64) *QueryDefinition {
+	return &QueryDefinition{
+		dataModels: qd.dataModels,
+		query:      strings.Replace(qd.query, `%RELATION_OID%`, strconv.FormatInt(oid, 10), 1),
+	}
+}
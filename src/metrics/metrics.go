@@ -3,15 +3,21 @@ package metrics
 import (
 	"fmt"
 	"io/ioutil"
+	"math"
 	"reflect"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/blang/semver/v4"
 	"github.com/newrelic/infra-integrations-sdk/v3/data/attribute"
 	"github.com/newrelic/infra-integrations-sdk/v3/data/metric"
 	"github.com/newrelic/infra-integrations-sdk/v3/integration"
 	"github.com/newrelic/infra-integrations-sdk/v3/log"
+	"github.com/newrelic/infra-integrations-sdk/v3/persist"
 	"github.com/newrelic/nri-postgresql/src/collection"
 	"github.com/newrelic/nri-postgresql/src/connection"
 	yaml "gopkg.in/yaml.v3"
@@ -19,6 +25,8 @@ import (
 
 const (
 	versionQuery = `SHOW server_version`
+
+	bufferCacheStoreKey = "bufferCache:lastCollection"
 )
 
 // PopulateMetrics collects metrics for each type
@@ -27,7 +35,7 @@ func PopulateMetrics(
 	databaseList collection.DatabaseList,
 	instance *integration.Entity,
 	i *integration.Integration,
-	collectPgBouncer, collectDbLocks, collectBloat bool,
+	collectDbLocks, collectBloat bool,
 	customMetricsQuery string) {
 
 	con, err := ci.NewConnection(ci.DatabaseName())
@@ -44,25 +52,21 @@ func PopulateMetrics(
 	}
 
 	PopulateInstanceMetrics(instance, version, con)
+	PopulateIOMetrics(instance, version, con)
+	PopulateXminHorizonMetrics(instance, version, con)
 	PopulateDatabaseMetrics(databaseList, version, i, con, ci)
+	PopulateTablespaceMetrics(databaseList, version, i, con, ci)
+	PopulateConnectionMetrics(databaseList, version, instance, con)
 	if collectDbLocks {
 		PopulateDatabaseLockMetrics(databaseList, version, i, con, ci)
 	}
 	PopulateTableMetrics(databaseList, version, i, ci, collectBloat)
-	PopulateIndexMetrics(databaseList, i, ci)
+	PopulateIndexMetrics(databaseList, i, ci, collectBloat)
+	PopulateSequenceMetrics(databaseList, version, i, ci)
+	PopulateProgressMetrics(databaseList, version, i, instance, con, ci)
 	if customMetricsQuery != "" {
 		PopulateCustomMetrics(customMetricsQuery, i, con, ci, instance)
 	}
-
-	if collectPgBouncer {
-		con, err = ci.NewConnection("pgbouncer")
-		if err != nil {
-			log.Error("Error creating connection to pgbouncer database: %s", err)
-		} else {
-			defer con.Close()
-			PopulatePgBouncerMetrics(i, con, ci)
-		}
-	}
 }
 
 // PopulateCustomMetricsFromFile collects metrics defined by a custom config file
@@ -286,6 +290,93 @@ func PopulateInstanceMetrics(instanceEntity *integration.Entity, version *semver
 			log.Error("Could not parse metrics from instance query result: %s", err.Error())
 		}
 	}
+
+	populateSlruMetrics(instanceEntity, version, connection)
+}
+
+// populateSlruMetrics populates a PostgresqlSlruSample on the instance entity for each SLRU cache
+func populateSlruMetrics(instanceEntity *integration.Entity, version *semver.Version, connection *connection.PGSQLConnection) {
+	for _, queryDef := range generateSlruDefinitions(version) {
+		dataModels := queryDef.GetDataModels().(*[]slruMetrics)
+		if err := connection.Query(dataModels, queryDef.GetQuery()); err != nil {
+			log.Error("Could not execute SLRU query: %s", err.Error())
+			continue
+		}
+
+		for _, slru := range *dataModels {
+			if slru.Name == nil {
+				continue
+			}
+			metricSet := instanceEntity.NewMetricSet("PostgresqlSlruSample",
+				attribute.Attribute{Key: "displayName", Value: instanceEntity.Metadata.Name},
+				attribute.Attribute{Key: "entityName", Value: instanceEntity.Metadata.Namespace + ":" + instanceEntity.Metadata.Name},
+				attribute.Attribute{Key: "slru.name", Value: *slru.Name},
+			)
+
+			if err := metricSet.MarshalMetrics(slru); err != nil {
+				log.Error("Failed to populate instance entity with SLRU metrics: %s", err.Error())
+			}
+		}
+	}
+}
+
+// PopulateIOMetrics populates a PostgresqlIOSample on the instance entity for each backend type,
+// object and context of pg_stat_io that had any I/O
+func PopulateIOMetrics(instanceEntity *integration.Entity, version *semver.Version, connection *connection.PGSQLConnection) {
+	for _, queryDef := range generateIODefinitions(version) {
+		dataModels := queryDef.GetDataModels()
+		if err := connection.Query(dataModels, queryDef.GetQuery()); err != nil {
+			log.Error("Could not execute I/O query: %s", err.Error())
+			continue
+		}
+
+		v := reflect.Indirect(reflect.ValueOf(dataModels))
+		for i := 0; i < v.Len(); i++ {
+			row := v.Index(i).Interface()
+			attributes := []attribute.Attribute{
+				{Key: "displayName", Value: instanceEntity.Metadata.Name},
+				{Key: "entityName", Value: instanceEntity.Metadata.Namespace + ":" + instanceEntity.Metadata.Name},
+			}
+			if modeler, ok := row.(IOModeler); ok {
+				attributes = append(attributes, modeler.GetIOAttributes()...)
+			}
+			metricSet := instanceEntity.NewMetricSet("PostgresqlIOSample", attributes...)
+
+			if err := metricSet.MarshalMetrics(row); err != nil {
+				log.Error("Failed to populate instance entity with I/O metrics: %s", err.Error())
+			}
+		}
+	}
+}
+
+// PopulateXminHorizonMetrics populates the oldest xmin holders for an instance, which are
+// what prevents vacuum from removing dead rows
+func PopulateXminHorizonMetrics(instanceEntity *integration.Entity, version *semver.Version, connection *connection.PGSQLConnection) {
+	var metricSet *metric.Set
+	for _, queryDef := range generateXminHorizonDefinitions(version) {
+		dataModels := queryDef.GetDataModels()
+		if err := connection.Query(dataModels, queryDef.GetQuery()); err != nil {
+			log.Error("Could not execute xmin horizon query: %s", err.Error())
+			continue
+		}
+
+		vp := reflect.Indirect(reflect.ValueOf(dataModels))
+
+		// No holder of this type
+		if vp.Len() == 0 {
+			continue
+		}
+
+		if metricSet == nil {
+			metricSet = instanceEntity.NewMetricSet("PostgresqlXminHorizonSample",
+				attribute.Attribute{Key: "displayName", Value: instanceEntity.Metadata.Name},
+				attribute.Attribute{Key: "entityName", Value: instanceEntity.Metadata.Namespace + ":" + instanceEntity.Metadata.Name},
+			)
+		}
+		if err := metricSet.MarshalMetrics(vp.Index(0).Interface()); err != nil {
+			log.Error("Could not parse metrics from xmin horizon query result: %s", err.Error())
+		}
+	}
 }
 
 // PopulateDatabaseMetrics populates the metrics for a database
@@ -294,20 +385,167 @@ func PopulateDatabaseMetrics(databases collection.DatabaseList, version *semver.
 	processDatabaseDefinitions(databaseDefinitions, pgIntegration, connection, ci)
 }
 
-// PopulateDatabaseLockMetrics populates the lock metrics for a database
-func PopulateDatabaseLockMetrics(databases collection.DatabaseList, version *semver.Version, pgIntegration *integration.Integration, connection *connection.PGSQLConnection, ci connection.Info) {
-	if !connection.HaveExtensionInSchema("tablefunc", "public") {
-		log.Warn("Crosstab function not available; database lock metric gathering not possible.")
-		log.Warn("To enable database lock metrics, enable the 'tablefunc' extension on the public")
-		log.Warn("schema of your database. You can do so by:")
-		log.Warn("  1. Installing the postgresql contribs package for your OS; and")
-		log.Warn("  2. Run the query 'CREATE EXTENSION tablefunc;' against your database's public schema")
+// PopulateTablespaceMetrics populates a pg-tablespace entity for each tablespace. The largest relations
+// of a tablespace are only looked for in the collected databases.
+func PopulateTablespaceMetrics(databases collection.DatabaseList, version *semver.Version, pgIntegration *integration.Integration, connection *connection.PGSQLConnection, ci connection.Info) {
+	definition := generateTablespaceDefinition(version)
+	dataModels := definition.GetDataModels().(*[]tablespaceMetrics)
+	if err := connection.Query(dataModels, definition.GetQuery()); err != nil {
+		log.Error("Could not execute tablespace query: %s", err.Error())
 		return
 	}
 
-	lockDefinitions := generateLockDefinitions(databases)
+	largestRelations := collectTablespaceLargestRelations(databases, ci)
+
+	host, port := ci.HostPort()
+	hostIDAttribute := integration.NewIDAttribute("host", host)
+	portIDAttribute := integration.NewIDAttribute("port", port)
+	for _, tablespace := range *dataModels {
+		if tablespace.Tablespace == nil {
+			continue
+		}
+		name := *tablespace.Tablespace
+		if relations, ok := largestRelations[name]; ok {
+			joined := strings.Join(relations, ", ")
+			tablespace.LargestRelations = &joined
+		}
+
+		tablespaceEntity, err := pgIntegration.Entity(name, "pg-tablespace", hostIDAttribute, portIDAttribute)
+		if err != nil {
+			log.Error("Failed to get tablespace entity for name %s: %s", name, err.Error())
+			continue
+		}
+		metricSet := tablespaceEntity.NewMetricSet("PostgresqlTablespaceSample",
+			attribute.Attribute{Key: "displayName", Value: tablespaceEntity.Metadata.Name},
+			attribute.Attribute{Key: "entityName", Value: "tablespace:" + tablespaceEntity.Metadata.Name},
+		)
+
+		if err := metricSet.MarshalMetrics(tablespace); err != nil {
+			log.Error("Failed to populate tablespace entity with metrics: %s", err.Error())
+		}
+	}
+}
+
+// collectTablespaceLargestRelations returns, for each tablespace, the largest relations of the collected databases
+func collectTablespaceLargestRelations(databases collection.DatabaseList, ci connection.Info) map[string][]string {
+	relations := make([]tablespaceRelation, 0)
+	definition := generateTablespaceRelationsDefinition()
+	for database := range databases {
+		con, err := ci.NewConnection(database)
+		if err != nil {
+			log.Error("Failed to connect to database %s: %s", database, err.Error())
+			continue
+		}
+		defer con.Close()
+
+		dataModels := definition.GetDataModels().(*[]tablespaceRelation)
+		if err := con.Query(dataModels, definition.GetQuery()); err != nil {
+			log.Error("Could not execute tablespace relations query: %s", err.Error())
+			continue
+		}
+		relations = append(relations, *dataModels...)
+	}
+
+	sort.SliceStable(relations, func(i, j int) bool {
+		if relations[i].Size != relations[j].Size {
+			return relations[i].Size > relations[j].Size
+		}
+		return relations[i].Relation < relations[j].Relation
+	})
+
+	largestRelations := make(map[string][]string)
+	for _, relation := range relations {
+		if len(largestRelations[relation.Tablespace]) < tablespaceLargestRelations {
+			largestRelations[relation.Tablespace] = append(largestRelations[relation.Tablespace], relation.Relation)
+		}
+	}
+
+	return largestRelations
+}
+
+// PopulateConnectionMetrics populates the session state breakdown of pg_stat_activity
+// per database, user, application and backend type
+func PopulateConnectionMetrics(databases collection.DatabaseList, version *semver.Version, instanceEntity *integration.Entity, connection *connection.PGSQLConnection) {
+	for _, queryDef := range generateConnectionDefinitions(databases, version) {
+		dataModels := queryDef.GetDataModels()
+		if err := connection.Query(dataModels, queryDef.GetQuery()); err != nil {
+			log.Error("Could not execute connection query: %s", err.Error())
+			continue
+		}
+
+		// for each row in the response
+		v := reflect.Indirect(reflect.ValueOf(dataModels))
+		for i := 0; i < v.Len(); i++ {
+			metricSet := instanceEntity.NewMetricSet("PostgresqlConnectionSample",
+				attribute.Attribute{Key: "displayName", Value: instanceEntity.Metadata.Name},
+				attribute.Attribute{Key: "entityName", Value: instanceEntity.Metadata.Namespace + ":" + instanceEntity.Metadata.Name},
+			)
+
+			if err := metricSet.MarshalMetrics(v.Index(i).Interface()); err != nil {
+				log.Error("Failed to populate instance entity with connection metrics: %s", err.Error())
+			}
+		}
+	}
+}
+
+// PopulateDatabaseLockMetrics populates the lock metrics for a database, and the locks held or awaited
+// on each collected table
+func PopulateDatabaseLockMetrics(databases collection.DatabaseList, version *semver.Version, pgIntegration *integration.Integration, connection *connection.PGSQLConnection, ci connection.Info) {
+	lockDefinitions := generateLockDefinitions(databases, version)
 
 	processDatabaseDefinitions(lockDefinitions, pgIntegration, connection, ci)
+
+	for database, schemaList := range databases {
+		if len(schemaList) == 0 {
+			continue
+		}
+
+		con, err := ci.NewConnection(database)
+		if err != nil {
+			log.Error("Failed to connect to database %s: %s", database, err.Error())
+			continue
+		}
+		defer con.Close()
+
+		populateRelationLockMetrics(schemaList, version, con, pgIntegration, ci)
+	}
+}
+
+func populateRelationLockMetrics(schemaList collection.SchemaList, version *semver.Version, con *connection.PGSQLConnection, pgIntegration *integration.Integration, ci connection.Info) {
+	for _, definition := range generateRelationLockDefinitions(schemaList, version) {
+		dataModels := definition.GetDataModels()
+		if err := con.Query(dataModels, definition.GetQuery()); err != nil {
+			log.Error("Could not execute relation lock query: %s", err.Error())
+			continue
+		}
+
+		v := reflect.Indirect(reflect.ValueOf(dataModels))
+		for i := 0; i < v.Len(); i++ {
+			row := v.Index(i).Interface()
+			dbName, err := GetDatabaseName(row)
+			if err != nil {
+				log.Error("Unable to get database name: %s", err.Error())
+			}
+			schemaName, err := GetSchemaName(row)
+			if err != nil {
+				log.Error("Unable to get schema name: %s", err.Error())
+			}
+			tableName, err := GetTableName(row)
+			if err != nil {
+				log.Error("Unable to get table name: %s", err.Error())
+			}
+
+			metricSet, err := newTableOrDatabaseMetricSet("PostgresqlTableLockSample", dbName, schemaName, tableName, schemaList, pgIntegration, ci)
+			if err != nil {
+				log.Error("Failed to get entity for relation lock metrics: %s", err.Error())
+				continue
+			}
+
+			if err := metricSet.MarshalMetrics(row); err != nil {
+				log.Error("Failed to populate relation lock metrics: %s", err.Error())
+			}
+		}
+	}
 }
 
 func processDatabaseDefinitions(definitions []*QueryDefinition, pgIntegration *integration.Integration, connection *connection.PGSQLConnection, ci connection.Info) {
@@ -343,6 +581,7 @@ func processDatabaseDefinitions(definitions []*QueryDefinition, pgIntegration *i
 			if err := metricSet.MarshalMetrics(db); err != nil {
 				log.Error("Failed to database entity with metrics: %s", err.Error())
 			}
+			setHitRatio(metricSet, "db.bufferHitRatio", "db.bufferHitsPerSecond", "db.readsPerSecond")
 
 		}
 	}
@@ -363,6 +602,49 @@ func PopulateTableMetrics(databases collection.DatabaseList, version *semver.Ver
 		}
 		defer con.Close()
 		populateTableMetricsForDatabase(schemaList, version, con, pgIntegration, ci, collectBloat)
+		populateTableMaintenanceForDatabase(schemaList, version, con, pgIntegration, ci)
+	}
+}
+
+// populateTableMaintenanceForDatabase populates a PostgresqlTableMaintenanceSample with the vacuum and analyze
+// activity and the autovacuum readiness of each collected table
+func populateTableMaintenanceForDatabase(schemaList collection.SchemaList, version *semver.Version, con *connection.PGSQLConnection, pgIntegration *integration.Integration, ci connection.Info) {
+	for _, definition := range generateTableMaintenanceDefinitions(schemaList, version) {
+		dataModels := definition.GetDataModels()
+		if err := con.Query(dataModels, definition.GetQuery()); err != nil {
+			log.Error("Could not execute table maintenance query: %s", err.Error())
+			continue
+		}
+
+		v := reflect.Indirect(reflect.ValueOf(dataModels))
+		for i := 0; i < v.Len(); i++ {
+			row := v.Index(i).Interface()
+			dbName, err := GetDatabaseName(row)
+			if err != nil {
+				log.Error("Unable to get database name: %s", err.Error())
+				continue
+			}
+			schemaName, err := GetSchemaName(row)
+			if err != nil {
+				log.Error("Unable to get schema name: %s", err.Error())
+				continue
+			}
+			tableName, err := GetTableName(row)
+			if err != nil {
+				log.Error("Unable to get table name: %s", err.Error())
+				continue
+			}
+
+			metricSet, err := newTableOrDatabaseMetricSet("PostgresqlTableMaintenanceSample", dbName, schemaName, tableName, schemaList, pgIntegration, ci)
+			if err != nil {
+				log.Error("Failed to get entity for table maintenance metrics: %s", err.Error())
+				continue
+			}
+
+			if err := metricSet.MarshalMetrics(row); err != nil {
+				log.Error("Failed to populate table entity with maintenance metrics: %s", err.Error())
+			}
+		}
 	}
 }
 
@@ -414,13 +696,15 @@ func populateTableMetricsForDatabase(schemaList collection.SchemaList, version *
 			if err := metricSet.MarshalMetrics(row); err != nil {
 				log.Error("Failed to populate table entity with metrics: %s", err.Error())
 			}
+			setHitRatio(metricSet, "table.heapBlocksHitRatio", "table.heapBlocksHitPerSecond", "table.heapBlocksReadPerSecond")
+			setHitRatio(metricSet, "table.indexBlocksHitRatio", "table.indexBlocksHitPerSecond", "table.indexBlocksReadPerSecond")
 
 		}
 	}
 }
 
 // PopulateIndexMetrics populates the metrics for an index
-func PopulateIndexMetrics(databases collection.DatabaseList, pgIntegration *integration.Integration, ci connection.Info) {
+func PopulateIndexMetrics(databases collection.DatabaseList, pgIntegration *integration.Integration, ci connection.Info, collectBloat bool) {
 	for database, schemaList := range databases {
 		con, err := ci.NewConnection(database)
 		if err != nil {
@@ -428,12 +712,13 @@ func PopulateIndexMetrics(databases collection.DatabaseList, pgIntegration *inte
 			continue
 		}
 		defer con.Close()
-		populateIndexMetricsForDatabase(schemaList, con, pgIntegration, ci)
+		populateIndexMetricsForDatabase(schemaList, con, pgIntegration, ci, collectBloat)
+		populateIndexHealthForDatabase(schemaList, con, pgIntegration, ci)
 	}
 }
 
-func populateIndexMetricsForDatabase(schemaList collection.SchemaList, con *connection.PGSQLConnection, pgIntegration *integration.Integration, ci connection.Info) {
-	indexDefinitions := generateIndexDefinitions(schemaList)
+func populateIndexMetricsForDatabase(schemaList collection.SchemaList, con *connection.PGSQLConnection, pgIntegration *integration.Integration, ci connection.Info, collectBloat bool) {
+	indexDefinitions := generateIndexDefinitions(schemaList, collectBloat)
 
 	for _, definition := range indexDefinitions {
 
@@ -465,75 +750,997 @@ func populateIndexMetricsForDatabase(schemaList collection.SchemaList, con *conn
 				log.Error("Unable to get index name: %s", err.Error())
 			}
 
-			host, port := ci.HostPort()
-			hostIDAttribute := integration.NewIDAttribute("host", host)
-			portIDAttribute := integration.NewIDAttribute("port", port)
-			databaseIDAttribute := integration.NewIDAttribute("pg-database", dbName)
-			schemaIDAttribute := integration.NewIDAttribute("pg-schema", schemaName)
-			tableIDAttribute := integration.NewIDAttribute("pg-table", tableName)
-			indexEntity, err := pgIntegration.Entity(indexName, "pg-index", hostIDAttribute, portIDAttribute, databaseIDAttribute, schemaIDAttribute, tableIDAttribute)
+			metricSet, err := newIndexMetricSet("PostgresqlIndexSample", dbName, schemaName, tableName, indexName, pgIntegration, ci)
 			if err != nil {
-				log.Error("Failed to get table entity for index %s: %s", indexName, err.Error())
+				log.Error("Failed to get index entity for index %s: %s", indexName, err.Error())
+				continue
 			}
-			metricSet := indexEntity.NewMetricSet("PostgresqlIndexSample",
-				attribute.Attribute{Key: "displayName", Value: indexEntity.Metadata.Name},
-				attribute.Attribute{Key: "entityName", Value: "index:" + indexEntity.Metadata.Name},
-				attribute.Attribute{Key: "database", Value: dbName},
-				attribute.Attribute{Key: "schema", Value: schemaName},
-				attribute.Attribute{Key: "table", Value: tableName},
-			)
 
 			if err := metricSet.MarshalMetrics(row); err != nil {
 				log.Error("Failed to populate index entity with metrics: %s", err.Error())
 			}
+			setHitRatio(metricSet, "index.blocksHitRatio", "index.blocksHitPerSecond", "index.blocksReadPerSecond")
 
 		}
 
 	}
 }
 
-// PopulatePgBouncerMetrics populates pgbouncer metrics
-func PopulatePgBouncerMetrics(pgIntegration *integration.Integration, con *connection.PGSQLConnection, ci connection.Info) {
-	pgbouncerDefs := generatePgBouncerDefinitions()
+// PopulateProgressMetrics populates the progress of the maintenance operations in flight.
+// Operations on a table of the collection list are reported on the table entity, any other
+// operation is reported on its database entity and base backups on the instance entity.
+func PopulateProgressMetrics(databases collection.DatabaseList, version *semver.Version, pgIntegration *integration.Integration, instanceEntity *integration.Entity, con *connection.PGSQLConnection, ci connection.Info) {
+	for _, definition := range generateInstanceProgressDefinitions(version) {
+		dataModels := definition.GetDataModels()
+		if err := con.Query(dataModels, definition.GetQuery()); err != nil {
+			log.Error("Could not execute progress query: %s", err.Error())
+			continue
+		}
+
+		v := reflect.Indirect(reflect.ValueOf(dataModels))
+		for i := 0; i < v.Len(); i++ {
+			metricSet := instanceEntity.NewMetricSet("PostgresqlProgressSample",
+				attribute.Attribute{Key: "displayName", Value: instanceEntity.Metadata.Name},
+				attribute.Attribute{Key: "entityName", Value: instanceEntity.Metadata.Namespace + ":" + instanceEntity.Metadata.Name},
+			)
+
+			if err := metricSet.MarshalMetrics(v.Index(i).Interface()); err != nil {
+				log.Error("Failed to populate instance entity with progress metrics: %s", err.Error())
+			}
+		}
+	}
+
+	for database, schemaList := range databases {
+		// Create a new connection to the database
+		dbCon, err := ci.NewConnection(database)
+		if err != nil {
+			log.Error("Failed to connect to database %s: %s", database, err.Error())
+			continue
+		}
+		defer dbCon.Close()
+		populateProgressMetricsForDatabase(schemaList, version, dbCon, pgIntegration, ci)
+	}
+}
 
-	for _, definition := range pgbouncerDefs {
+func populateProgressMetricsForDatabase(schemaList collection.SchemaList, version *semver.Version, con *connection.PGSQLConnection, pgIntegration *integration.Integration, ci connection.Info) {
+	for _, definition := range generateRelationProgressDefinitions(version) {
 		dataModels := definition.GetDataModels()
-		// Use QueryUnsafe to support different PgBouncer versions with varying column sets
-		if err := con.QueryUnsafe(dataModels, definition.GetQuery()); err != nil {
-			log.Error("Could not execute index query: %s", err.Error())
-			return
+		if err := con.Query(dataModels, definition.GetQuery()); err != nil {
+			log.Error("Could not execute progress query: %s", err.Error())
+			continue
 		}
 
 		// for each row in the response
 		v := reflect.Indirect(reflect.ValueOf(dataModels))
 		for i := 0; i < v.Len(); i++ {
-			db := v.Index(i).Interface()
-			name, err := GetDatabaseName(db)
+			row := v.Index(i).Interface()
+			dbName, err := GetDatabaseName(row)
 			if err != nil {
 				log.Error("Unable to get database name: %s", err.Error())
 				continue
 			}
+			// The relation may have been dropped or the operation may not target one
+			schemaName, _ := GetSchemaName(row)
+			tableName, _ := GetTableName(row)
 
-			host, port := ci.HostPort()
-			hostIDAttribute := integration.NewIDAttribute("host", host)
-			portIDAttribute := integration.NewIDAttribute("port", port)
-			pgEntity, err := pgIntegration.Entity(name, "pgbouncer", hostIDAttribute, portIDAttribute)
+			metricSet, err := newTableOrDatabaseMetricSet("PostgresqlProgressSample", dbName, schemaName, tableName, schemaList, pgIntegration, ci)
 			if err != nil {
-				log.Error("Failed to get database entity for name %s: %s", name, err.Error())
+				log.Error("Failed to get entity for progress metrics: %s", err.Error())
+				continue
 			}
-			metricSet := pgEntity.NewMetricSet("PgBouncerSample",
-				attribute.Attribute{Key: "displayName", Value: name},
-				attribute.Attribute{Key: "entityName", Value: "pgbouncer:" + name},
-				attribute.Attribute{Key: "host", Value: host},
-			)
 
-			if err := metricSet.MarshalMetrics(db); err != nil {
-				log.Error("Failed to populate pgbouncer entity with metrics: %s", err.Error())
+			if err := metricSet.MarshalMetrics(row); err != nil {
+				log.Error("Failed to populate progress metrics: %s", err.Error())
 			}
 		}
 	}
 }
 
+func populateIndexHealthForDatabase(schemaList collection.SchemaList, con *connection.PGSQLConnection, pgIntegration *integration.Integration, ci connection.Info) {
+	for _, definition := range generateIndexHealthDefinitions(schemaList) {
+		dataModels := definition.GetDataModels()
+		if err := con.Query(dataModels, definition.GetQuery()); err != nil {
+			log.Error("Could not execute index health query: %s", err.Error())
+			return
+		}
+
+		// for each index with an issue
+		v := reflect.Indirect(reflect.ValueOf(dataModels))
+		for i := 0; i < v.Len(); i++ {
+			row := v.Index(i).Interface()
+			dbName, err := GetDatabaseName(row)
+			if err != nil {
+				log.Error("Unable to get database name: %s", err.Error())
+				continue
+			}
+			schemaName, err := GetSchemaName(row)
+			if err != nil {
+				log.Error("Unable to get schema name: %s", err.Error())
+				continue
+			}
+			tableName, err := GetTableName(row)
+			if err != nil {
+				log.Error("Unable to get table name: %s", err.Error())
+				continue
+			}
+			indexName, err := GetIndexName(row)
+			if err != nil {
+				log.Error("Unable to get index name: %s", err.Error())
+				continue
+			}
+
+			metricSet, err := newIndexMetricSet("PostgresqlIndexHealthSample", dbName, schemaName, tableName, indexName, pgIntegration, ci)
+			if err != nil {
+				log.Error("Failed to get index entity for index %s: %s", indexName, err.Error())
+				continue
+			}
+
+			if err := metricSet.MarshalMetrics(row); err != nil {
+				log.Error("Failed to populate index entity with health metrics: %s", err.Error())
+			}
+		}
+	}
+}
+
+func newIndexMetricSet(eventType, dbName, schemaName, tableName, indexName string, pgIntegration *integration.Integration, ci connection.Info) (*metric.Set, error) {
+	host, port := ci.HostPort()
+	hostIDAttribute := integration.NewIDAttribute("host", host)
+	portIDAttribute := integration.NewIDAttribute("port", port)
+	databaseIDAttribute := integration.NewIDAttribute("pg-database", dbName)
+	schemaIDAttribute := integration.NewIDAttribute("pg-schema", schemaName)
+	tableIDAttribute := integration.NewIDAttribute("pg-table", tableName)
+	indexEntity, err := pgIntegration.Entity(indexName, "pg-index", hostIDAttribute, portIDAttribute, databaseIDAttribute, schemaIDAttribute, tableIDAttribute)
+	if err != nil {
+		return nil, err
+	}
+
+	return indexEntity.NewMetricSet(eventType,
+		attribute.Attribute{Key: "displayName", Value: indexEntity.Metadata.Name},
+		attribute.Attribute{Key: "entityName", Value: "index:" + indexEntity.Metadata.Name},
+		attribute.Attribute{Key: "database", Value: dbName},
+		attribute.Attribute{Key: "schema", Value: schemaName},
+		attribute.Attribute{Key: "table", Value: tableName},
+	), nil
+}
+
+// setHitRatio sets the percentage of block accesses served from shared buffers during the interval.
+// It is computed from the hit and read rates of the metric set, so it reflects the interval and not
+// the lifetime of the counters. Nothing is set when no blocks were accessed, which is always the
+// case on the first run, or when the counters were reset.
+func setHitRatio(metricSet *metric.Set, ratioName, hitsName, readsName string) {
+	hits, ok := metricSet.Metrics[hitsName].(float64)
+	if !ok {
+		return
+	}
+	reads, ok := metricSet.Metrics[readsName].(float64)
+	if !ok || hits < 0 || reads < 0 || hits+reads == 0 {
+		return
+	}
+
+	if err := metricSet.SetMetric(ratioName, 100*hits/(hits+reads), metric.GAUGE); err != nil {
+		log.Error("Failed to set %s: %s", ratioName, err.Error())
+	}
+}
+
+// PopulateExactBloatMetrics measures the bloat of the collected tables and B-tree indexes with the
+// pgstattuple extension. Relations larger than maxSizeMb are skipped and at most relationsPerRun
+// relations are measured on each run, starting with the ones measured the longest ago, so all of
+// them are eventually measured without scanning them all at once.
+func PopulateExactBloatMetrics(databases collection.DatabaseList, maxSizeMb, relationsPerRun int, pgIntegration *integration.Integration, ci connection.Info, store persist.Storer) {
+	connections := make(map[string]*connection.PGSQLConnection)
+	candidates := make([]exactBloatCandidate, 0)
+	for database, schemaList := range databases {
+		con, err := ci.NewConnection(database)
+		if err != nil {
+			log.Error("Failed to connect to database %s: %s", database, err.Error())
+			continue
+		}
+		defer con.Close()
+
+		if !con.HaveExtensionInSchema("pgstattuple", "public") {
+			log.Warn("Exact bloat metrics are enabled but the pgstattuple extension is not installed in the public schema of database %s", database)
+			continue
+		}
+
+		version, err := CollectVersion(con)
+		if err != nil {
+			log.Error("Exact bloat collection failed for database %s: error collecting version number: %s", database, err.Error())
+			continue
+		}
+
+		connections[database] = con
+		candidates = append(candidates, collectExactBloatCandidates(schemaList, int64(maxSizeMb)*1024*1024, version, con, store)...)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].lastScanned != candidates[j].lastScanned {
+			return candidates[i].lastScanned < candidates[j].lastScanned
+		}
+		return candidates[i].storeKey() < candidates[j].storeKey()
+	})
+	if relationsPerRun >= 0 && len(candidates) > relationsPerRun {
+		candidates = candidates[:relationsPerRun]
+	}
+
+	for _, candidate := range candidates {
+		if err := populateExactBloatMetricsForRelation(candidate, databases[candidate.Database], connections[candidate.Database], pgIntegration, ci); err != nil {
+			log.Error("Could not measure exact bloat of %s: %s", candidate.storeKey(), err.Error())
+		}
+		// failed attempts are recorded too, so relations that can't be measured don't take the whole budget of every run
+		store.Set(candidate.storeKey(), candidate.OID)
+	}
+}
+
+func collectExactBloatCandidates(schemaList collection.SchemaList, maxSize int64, version *semver.Version, con *connection.PGSQLConnection, store persist.Storer) []exactBloatCandidate {
+	candidates := make([]exactBloatCandidate, 0)
+	for _, definition := range generateExactBloatCandidateDefinitions(schemaList, maxSize, version) {
+		dataModels := definition.GetDataModels().(*[]exactBloatCandidate)
+		if err := con.Query(dataModels, definition.GetQuery()); err != nil {
+			log.Error("Could not execute exact bloat candidates query: %s", err.Error())
+			continue
+		}
+
+		for _, candidate := range *dataModels {
+			var oid int64
+			if lastScanned, err := store.Get(candidate.storeKey(), &oid); err == nil {
+				candidate.lastScanned = lastScanned
+			}
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	return candidates
+}
+
+// populateExactBloatMetricsForRelation populates a PostgresqlExactBloatSample on the entity of the relation.
+// An error is returned when the relation could not be measured.
+func populateExactBloatMetricsForRelation(candidate exactBloatCandidate, schemaList collection.SchemaList, con *connection.PGSQLConnection, pgIntegration *integration.Integration, ci connection.Info) error {
+	definition := generateExactBloatDefinition(candidate)
+	dataModels := definition.GetDataModels()
+	if err := con.Query(dataModels, definition.GetQuery()); err != nil {
+		return err
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(dataModels))
+	for i := 0; i < v.Len(); i++ {
+		row := v.Index(i).Interface()
+
+		var metricSet *metric.Set
+		var err error
+		if candidate.Index != nil {
+			metricSet, err = newIndexMetricSet("PostgresqlExactBloatSample", candidate.Database, candidate.Schema, candidate.Table, *candidate.Index, pgIntegration, ci)
+		} else {
+			metricSet, err = newTableOrDatabaseMetricSet("PostgresqlExactBloatSample", candidate.Database, candidate.Schema, candidate.Table, schemaList, pgIntegration, ci)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get entity: %w", err)
+		}
+
+		if err := metricSet.MarshalMetrics(row); err != nil {
+			return fmt.Errorf("failed to populate metrics: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// PopulateBufferCacheMetrics reports, with the pg_buffercache extension, the shared buffers held by the
+// largest relationsPerDatabase collected tables and indexes of each database, and the shared buffers
+// used by each database on the instance entity. pg_buffercache reads every buffer header, so nothing
+// is collected when the last collection is less than intervalMinutes old.
+func PopulateBufferCacheMetrics(databases collection.DatabaseList, intervalMinutes, relationsPerDatabase int, pgIntegration *integration.Integration, instanceEntity *integration.Entity, ci connection.Info, store persist.Storer) {
+	var lastCollection int64
+	if collectedAt, err := store.Get(bufferCacheStoreKey, &lastCollection); err == nil && time.Now().Unix()-collectedAt < int64(intervalMinutes)*60 {
+		log.Debug("Skipping buffer cache metrics, last collected less than %d minutes ago", intervalMinutes)
+		return
+	}
+
+	// the collection time is only stored once something was collected, so failing runs are retried on the next one
+	collected := false
+	con, err := ci.NewConnection(ci.DatabaseName())
+	if err != nil {
+		log.Error("Failed to connect to database %s: %s", ci.DatabaseName(), err.Error())
+	} else {
+		defer con.Close()
+		if con.HaveExtensionInSchema("pg_buffercache", "public") {
+			collected = populateBufferCacheSummary(instanceEntity, con)
+		} else {
+			log.Warn("Buffer cache metrics are enabled but the pg_buffercache extension is not installed in the public schema of database %s", ci.DatabaseName())
+		}
+	}
+
+	for database, schemaList := range databases {
+		con, err := ci.NewConnection(database)
+		if err != nil {
+			log.Error("Failed to connect to database %s: %s", database, err.Error())
+			continue
+		}
+		defer con.Close()
+
+		if !con.HaveExtensionInSchema("pg_buffercache", "public") {
+			log.Warn("Buffer cache metrics are enabled but the pg_buffercache extension is not installed in the public schema of database %s", database)
+			continue
+		}
+
+		if populateBufferCacheMetricsForDatabase(schemaList, relationsPerDatabase, con, pgIntegration, ci) {
+			collected = true
+		}
+	}
+
+	if collected {
+		store.Set(bufferCacheStoreKey, time.Now().Unix())
+	}
+}
+
+// populateBufferCacheSummary returns false when the summary query failed
+func populateBufferCacheSummary(instanceEntity *integration.Entity, con *connection.PGSQLConnection) bool {
+	dataModels := bufferCacheSummaryDefinition.GetDataModels()
+	if err := con.Query(dataModels, bufferCacheSummaryDefinition.GetQuery()); err != nil {
+		log.Error("Could not execute buffer cache summary query: %s", err.Error())
+		return false
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(dataModels))
+	for i := 0; i < v.Len(); i++ {
+		metricSet := instanceEntity.NewMetricSet("PostgresqlBufferCacheSample",
+			attribute.Attribute{Key: "displayName", Value: instanceEntity.Metadata.Name},
+			attribute.Attribute{Key: "entityName", Value: instanceEntity.Metadata.Namespace + ":" + instanceEntity.Metadata.Name},
+		)
+
+		if err := metricSet.MarshalMetrics(v.Index(i).Interface()); err != nil {
+			log.Error("Failed to populate instance entity with buffer cache metrics: %s", err.Error())
+		}
+	}
+
+	return true
+}
+
+// populateBufferCacheMetricsForDatabase returns false when none of the buffer cache queries of the database succeeded
+func populateBufferCacheMetricsForDatabase(schemaList collection.SchemaList, relationsPerDatabase int, con *connection.PGSQLConnection, pgIntegration *integration.Integration, ci connection.Info) bool {
+	collected := false
+	for _, definition := range generateBufferCacheDefinitions(schemaList, relationsPerDatabase) {
+		dataModels := definition.GetDataModels()
+		if err := con.Query(dataModels, definition.GetQuery()); err != nil {
+			log.Error("Could not execute buffer cache query: %s", err.Error())
+			continue
+		}
+		collected = true
+
+		v := reflect.Indirect(reflect.ValueOf(dataModels))
+		for i := 0; i < v.Len(); i++ {
+			row := v.Index(i).Interface()
+			dbName, err := GetDatabaseName(row)
+			if err != nil {
+				log.Error("Unable to get database name: %s", err.Error())
+			}
+			schemaName, err := GetSchemaName(row)
+			if err != nil {
+				log.Error("Unable to get schema name: %s", err.Error())
+			}
+			tableName, err := GetTableName(row)
+			if err != nil {
+				log.Error("Unable to get table name: %s", err.Error())
+			}
+
+			var metricSet *metric.Set
+			if indexName, indexErr := GetIndexName(row); indexErr == nil {
+				metricSet, err = newIndexMetricSet("PostgresqlBufferCacheSample", dbName, schemaName, tableName, indexName, pgIntegration, ci)
+			} else {
+				metricSet, err = newTableOrDatabaseMetricSet("PostgresqlBufferCacheSample", dbName, schemaName, tableName, schemaList, pgIntegration, ci)
+			}
+			if err != nil {
+				log.Error("Failed to get entity for buffer cache metrics: %s", err.Error())
+				continue
+			}
+
+			if err := metricSet.MarshalMetrics(row); err != nil {
+				log.Error("Failed to populate buffer cache metrics: %s", err.Error())
+			}
+		}
+	}
+
+	return collected
+}
+
+// PopulateSequenceMetrics populates the metrics for the sequences of the collected schemas
+func PopulateSequenceMetrics(databases collection.DatabaseList, version *semver.Version, pgIntegration *integration.Integration, ci connection.Info) {
+	for database, schemaList := range databases {
+		con, err := ci.NewConnection(database)
+		if err != nil {
+			log.Error("Failed to connect to database %s: %s", database, err.Error())
+			continue
+		}
+		defer con.Close()
+		populateSequenceMetricsForDatabase(schemaList, version, con, pgIntegration, ci)
+	}
+}
+
+func populateSequenceMetricsForDatabase(schemaList collection.SchemaList, version *semver.Version, con *connection.PGSQLConnection, pgIntegration *integration.Integration, ci connection.Info) {
+	for _, definition := range generateSequenceDefinitions(schemaList, version) {
+		dataModels := definition.GetDataModels()
+		if err := con.Query(dataModels, definition.GetQuery()); err != nil {
+			log.Error("Could not execute sequence query: %s", err.Error())
+			continue
+		}
+
+		// for each row in the response
+		v := reflect.Indirect(reflect.ValueOf(dataModels))
+		for i := 0; i < v.Len(); i++ {
+			row := v.Index(i).Interface()
+			dbName, err := GetDatabaseName(row)
+			if err != nil {
+				log.Error("Unable to get database name: %s", err.Error())
+				continue
+			}
+			schemaName, err := GetSchemaName(row)
+			if err != nil {
+				log.Error("Unable to get schema name: %s", err.Error())
+				continue
+			}
+			// Sequences without an owning table are reported on the database
+			tableName, _ := GetTableName(row)
+
+			metricSet, err := newTableOrDatabaseMetricSet("PostgresqlSequenceSample", dbName, schemaName, tableName, schemaList, pgIntegration, ci)
+			if err != nil {
+				log.Error("Failed to get entity for sequence metrics: %s", err.Error())
+				continue
+			}
+
+			if err := metricSet.MarshalMetrics(row); err != nil {
+				log.Error("Failed to populate sequence metrics: %s", err.Error())
+			}
+		}
+	}
+}
+
+// newTableOrDatabaseMetricSet creates a metric set on the pg-table entity when the table is collected.
+// Otherwise it is created on the pg-database entity, with the schema and table as attributes if given.
+func newTableOrDatabaseMetricSet(eventType, dbName, schemaName, tableName string, schemaList collection.SchemaList, pgIntegration *integration.Integration, ci connection.Info) (*metric.Set, error) {
+	host, port := ci.HostPort()
+	hostIDAttribute := integration.NewIDAttribute("host", host)
+	portIDAttribute := integration.NewIDAttribute("port", port)
+
+	if _, ok := schemaList[schemaName][tableName]; ok {
+		databaseIDAttribute := integration.NewIDAttribute("pg-database", dbName)
+		schemaIDAttribute := integration.NewIDAttribute("pg-schema", schemaName)
+		tableEntity, err := pgIntegration.Entity(tableName, "pg-table", hostIDAttribute, portIDAttribute, databaseIDAttribute, schemaIDAttribute)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get table entity for table %s: %w", tableName, err)
+		}
+		return tableEntity.NewMetricSet(eventType,
+			attribute.Attribute{Key: "displayName", Value: tableEntity.Metadata.Name},
+			attribute.Attribute{Key: "entityName", Value: "table:" + tableEntity.Metadata.Name},
+			attribute.Attribute{Key: "database", Value: dbName},
+			attribute.Attribute{Key: "schema", Value: schemaName},
+		), nil
+	}
+
+	databaseEntity, err := pgIntegration.Entity(dbName, "pg-database", hostIDAttribute, portIDAttribute)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database entity for name %s: %w", dbName, err)
+	}
+	attributes := []attribute.Attribute{
+		{Key: "displayName", Value: databaseEntity.Metadata.Name},
+		{Key: "entityName", Value: "database:" + databaseEntity.Metadata.Name},
+	}
+	if tableName != "" {
+		attributes = append(attributes,
+			attribute.Attribute{Key: "schema", Value: schemaName},
+			attribute.Attribute{Key: "table", Value: tableName},
+		)
+	}
+	return databaseEntity.NewMetricSet(eventType, attributes...), nil
+}
+
+// PopulateFunctionMetrics populates the metrics for functions and procedures of each database
+func PopulateFunctionMetrics(databases collection.DatabaseList, functions collection.FunctionList, topN int, pgIntegration *integration.Integration, ci connection.Info) {
+	for database := range databases {
+		functionDefinitions := generateFunctionDefinitions(functions[database], topN)
+		if len(functionDefinitions) == 0 {
+			continue
+		}
+
+		con, err := ci.NewConnection(database)
+		if err != nil {
+			log.Error("Failed to create new connection to database %s: %s", database, err.Error())
+			continue
+		}
+		defer con.Close()
+		populateFunctionMetricsForDatabase(functionDefinitions, con, pgIntegration, ci)
+	}
+}
+
+func populateFunctionMetricsForDatabase(functionDefinitions []*QueryDefinition, con *connection.PGSQLConnection, pgIntegration *integration.Integration, ci connection.Info) {
+	for _, definition := range functionDefinitions {
+
+		dataModels := definition.GetDataModels()
+		if err := con.Query(dataModels, definition.GetQuery()); err != nil {
+			log.Error("Could not execute function query: %s", err.Error())
+			return
+		}
+
+		v := reflect.Indirect(reflect.ValueOf(dataModels))
+		for i := 0; i < v.Len(); i++ {
+			row := v.Index(i).Interface()
+			dbName, err := GetDatabaseName(row)
+			if err != nil {
+				log.Error("Unable to get database name: %s", err.Error())
+			}
+			schemaName, err := GetSchemaName(row)
+			if err != nil {
+				log.Error("Unable to get schema name: %s", err.Error())
+			}
+			functionName, err := GetFunctionName(row)
+			if err != nil {
+				log.Error("Unable to get function name: %s", err.Error())
+			}
+
+			host, port := ci.HostPort()
+			hostIDAttribute := integration.NewIDAttribute("host", host)
+			portIDAttribute := integration.NewIDAttribute("port", port)
+			databaseIDAttribute := integration.NewIDAttribute("pg-database", dbName)
+			schemaIDAttribute := integration.NewIDAttribute("pg-schema", schemaName)
+			functionEntity, err := pgIntegration.Entity(functionName, "pg-function", hostIDAttribute, portIDAttribute, databaseIDAttribute, schemaIDAttribute)
+			if err != nil {
+				log.Error("Failed to get function entity for function %s: %s", functionName, err.Error())
+				continue
+			}
+			metricSet := functionEntity.NewMetricSet("PostgresqlFunctionSample",
+				attribute.Attribute{Key: "displayName", Value: functionEntity.Metadata.Name},
+				attribute.Attribute{Key: "entityName", Value: "function:" + functionEntity.Metadata.Name},
+				attribute.Attribute{Key: "database", Value: dbName},
+				attribute.Attribute{Key: "schema", Value: schemaName},
+			)
+
+			if err := metricSet.MarshalMetrics(row); err != nil {
+				log.Error("Failed to populate function entity with metrics: %s", err.Error())
+			}
+		}
+	}
+}
+
+// PopulatePgBouncerMetrics populates the pgbouncer instance entity with the metrics of each database, pool,
+// connection and internal structure of PgBouncer
+func PopulatePgBouncerMetrics(pgIntegration *integration.Integration, con *connection.PGSQLConnection, ci connection.Info) {
+	instanceEntity, err := PgBouncerInstanceEntity(pgIntegration, ci)
+	if err != nil {
+		log.Error("Failed to get pgbouncer instance entity: %s", err.Error())
+		return
+	}
+
+	populatePgBouncerStatsMetrics(instanceEntity, con)
+	populatePgBouncerPoolMetrics(instanceEntity, con)
+	populatePgBouncerDatabaseMetrics(instanceEntity, con)
+	populatePgBouncerInstanceMetrics(instanceEntity, con)
+	populatePgBouncerMemMetrics(instanceEntity, con)
+	populatePgBouncerServerMetrics(instanceEntity, con)
+}
+
+// populatePgBouncerStatsMetrics populates a PgBouncerSample with the statistics of each database
+func populatePgBouncerStatsMetrics(instanceEntity *integration.Entity, con *connection.PGSQLConnection) {
+	dataModels := pgbouncerStatsDefinition.GetDataModels()
+	// Use QueryUnsafe to support different PgBouncer versions with varying column sets
+	if err := con.QueryUnsafe(dataModels, pgbouncerStatsDefinition.GetQuery()); err != nil {
+		log.Error("Could not execute pgbouncer stats query: %s", err.Error())
+		return
+	}
+
+	// for each row in the response
+	v := reflect.Indirect(reflect.ValueOf(dataModels))
+	for i := 0; i < v.Len(); i++ {
+		db := v.Index(i).Interface()
+		name, err := GetDatabaseName(db)
+		if err != nil {
+			log.Error("Unable to get database name: %s", err.Error())
+			continue
+		}
+
+		// the database attribute is part of the namespace so the rates of each database are computed separately
+		metricSet := newPgBouncerInstanceMetricSet("PgBouncerSample", instanceEntity,
+			attribute.Attribute{Key: "database", Value: name},
+		)
+
+		if err := metricSet.MarshalMetrics(db); err != nil {
+			log.Error("Failed to populate pgbouncer instance entity with stats metrics: %s", err.Error())
+		}
+	}
+}
+
+// populatePgBouncerPoolMetrics populates a PgBouncerPoolSample for each pool, identified by its database and user
+func populatePgBouncerPoolMetrics(instanceEntity *integration.Entity, con *connection.PGSQLConnection) {
+	dataModels := pgbouncerPoolsDefinition.GetDataModels().(*[]pgbouncerPool)
+	if err := con.QueryUnsafe(dataModels, pgbouncerPoolsDefinition.GetQuery()); err != nil {
+		log.Error("Could not execute pgbouncer pools query: %s", err.Error())
+		return
+	}
+
+	for _, pool := range *dataModels {
+		if pool.Database == nil || pool.User == nil {
+			continue
+		}
+		metricSet := newPgBouncerInstanceMetricSet("PgBouncerPoolSample", instanceEntity,
+			attribute.Attribute{Key: "database", Value: *pool.Database},
+			attribute.Attribute{Key: "user", Value: *pool.User},
+		)
+
+		if err := metricSet.MarshalMetrics(pool); err != nil {
+			log.Error("Failed to populate pgbouncer instance entity with pool metrics: %s", err.Error())
+		}
+		if pool.MaxWait != nil {
+			maxWait := waitInMilliseconds(pool.MaxWait, pool.MaxWaitUs)
+			if err := metricSet.SetMetric("pgbouncer.pools.maxwaitInMilliseconds", maxWait, metric.GAUGE); err != nil {
+				log.Error("Failed to populate pgbouncer instance entity with pool metrics: %s", err.Error())
+			}
+		}
+	}
+}
+
+// PgBouncerInstanceEntity returns the entity of the PgBouncer instance, which holds the metrics and
+// inventory not specific to a single database
+func PgBouncerInstanceEntity(pgIntegration *integration.Integration, ci connection.Info) (*integration.Entity, error) {
+	host, port := ci.HostPort()
+	return pgIntegration.Entity(fmt.Sprintf("%s:%s", host, port), "pgbouncer-instance")
+}
+
+// newPgBouncerInstanceMetricSet creates a metric set on the PgBouncer instance entity
+func newPgBouncerInstanceMetricSet(eventType string, instanceEntity *integration.Entity, attributes ...attribute.Attribute) *metric.Set {
+	attributes = append([]attribute.Attribute{
+		{Key: "displayName", Value: instanceEntity.Metadata.Name},
+		{Key: "entityName", Value: instanceEntity.Metadata.Namespace + ":" + instanceEntity.Metadata.Name},
+	}, attributes...)
+	return instanceEntity.NewMetricSet(eventType, attributes...)
+}
+
+// populatePgBouncerDatabaseMetrics populates a PgBouncerDatabaseSample for each database configured in PgBouncer
+func populatePgBouncerDatabaseMetrics(instanceEntity *integration.Entity, con *connection.PGSQLConnection) {
+	dataModels := pgbouncerDatabasesDefinition.GetDataModels().(*[]pgbouncerDatabase)
+	if err := con.QueryUnsafe(dataModels, pgbouncerDatabasesDefinition.GetQuery()); err != nil {
+		log.Error("Could not execute pgbouncer databases query: %s", err.Error())
+		return
+	}
+
+	for _, database := range *dataModels {
+		if database.Name == nil {
+			continue
+		}
+		metricSet := newPgBouncerInstanceMetricSet("PgBouncerDatabaseSample", instanceEntity,
+			attribute.Attribute{Key: "database", Value: *database.Name},
+		)
+
+		if err := metricSet.MarshalMetrics(database); err != nil {
+			log.Error("Failed to populate pgbouncer instance entity with database metrics: %s", err.Error())
+		}
+	}
+}
+
+// populatePgBouncerInstanceMetrics populates a PgBouncerInstanceSample with the size of the internal lists
+// of PgBouncer and the time the oldest waiting client has been waiting
+func populatePgBouncerInstanceMetrics(instanceEntity *integration.Entity, con *connection.PGSQLConnection) {
+	lists := pgbouncerListsDefinition.GetDataModels().(*[]pgbouncerList)
+	listsErr := con.QueryUnsafe(lists, pgbouncerListsDefinition.GetQuery())
+	if listsErr != nil {
+		log.Error("Could not execute pgbouncer lists query: %s", listsErr.Error())
+	}
+
+	clients := pgbouncerClientsDefinition.GetDataModels().(*[]pgbouncerConnection)
+	clientsErr := con.QueryUnsafe(clients, pgbouncerClientsDefinition.GetQuery())
+	if clientsErr != nil {
+		log.Error("Could not execute pgbouncer clients query: %s", clientsErr.Error())
+	}
+
+	if listsErr != nil && clientsErr != nil {
+		return
+	}
+
+	metricSet := newPgBouncerInstanceMetricSet("PgBouncerInstanceSample", instanceEntity)
+	for _, list := range *lists {
+		if err := metricSet.SetMetric("pgbouncer.lists."+snakeToCamelCase(list.List), list.Items, metric.GAUGE); err != nil {
+			log.Error("Failed to populate pgbouncer instance entity with list metrics: %s", err.Error())
+		}
+	}
+
+	if clientsErr != nil {
+		return
+	}
+
+	oldestWait := 0.0
+	for _, group := range groupPgBouncerConnections(*clients) {
+		oldestWait = math.Max(oldestWait, group.maxWait)
+		groupSet := newPgBouncerInstanceMetricSet("PgBouncerClientSample", instanceEntity, group.attributes()...)
+		if err := groupSet.SetMetric("pgbouncer.clients.connections", group.connections, metric.GAUGE); err != nil {
+			log.Error("Failed to populate pgbouncer instance entity with client metrics: %s", err.Error())
+		}
+		if group.waiting {
+			if err := groupSet.SetMetric("pgbouncer.clients.maxWaitInMilliseconds", group.maxWait, metric.GAUGE); err != nil {
+				log.Error("Failed to populate pgbouncer instance entity with client metrics: %s", err.Error())
+			}
+		}
+	}
+
+	if err := metricSet.SetMetric("pgbouncer.clients.oldestWaitInMilliseconds", oldestWait, metric.GAUGE); err != nil {
+		log.Error("Failed to populate pgbouncer instance entity with client metrics: %s", err.Error())
+	}
+}
+
+// populatePgBouncerMemMetrics populates a PgBouncerMemorySample for each internal memory cache of PgBouncer
+func populatePgBouncerMemMetrics(instanceEntity *integration.Entity, con *connection.PGSQLConnection) {
+	dataModels := pgbouncerMemDefinition.GetDataModels().(*[]pgbouncerMem)
+	if err := con.QueryUnsafe(dataModels, pgbouncerMemDefinition.GetQuery()); err != nil {
+		log.Error("Could not execute pgbouncer mem query: %s", err.Error())
+		return
+	}
+
+	for _, mem := range *dataModels {
+		if mem.Name == nil {
+			continue
+		}
+		metricSet := newPgBouncerInstanceMetricSet("PgBouncerMemorySample", instanceEntity,
+			attribute.Attribute{Key: "pgbouncer.mem.cache", Value: *mem.Name},
+		)
+
+		if err := metricSet.MarshalMetrics(mem); err != nil {
+			log.Error("Failed to populate pgbouncer instance entity with memory metrics: %s", err.Error())
+		}
+	}
+}
+
+// populatePgBouncerServerMetrics populates a PgBouncerServerSample with the number of server connections
+// of each database, user, application and state
+func populatePgBouncerServerMetrics(instanceEntity *integration.Entity, con *connection.PGSQLConnection) {
+	servers := pgbouncerServersDefinition.GetDataModels().(*[]pgbouncerConnection)
+	if err := con.QueryUnsafe(servers, pgbouncerServersDefinition.GetQuery()); err != nil {
+		log.Error("Could not execute pgbouncer servers query: %s", err.Error())
+		return
+	}
+
+	for _, group := range groupPgBouncerConnections(*servers) {
+		metricSet := newPgBouncerInstanceMetricSet("PgBouncerServerSample", instanceEntity, group.attributes()...)
+		if err := metricSet.SetMetric("pgbouncer.servers.connections", group.connections, metric.GAUGE); err != nil {
+			log.Error("Failed to populate pgbouncer instance entity with server metrics: %s", err.Error())
+		}
+	}
+}
+
+// pgbouncerConnectionGroup is the connections of SHOW CLIENTS or SHOW SERVERS sharing a database, user,
+// application and state
+type pgbouncerConnectionGroup struct {
+	database, user, application, state string
+	connections                        int64
+	waiting                            bool
+	maxWait                            float64
+}
+
+func (g pgbouncerConnectionGroup) attributes() []attribute.Attribute {
+	return []attribute.Attribute{
+		{Key: "database", Value: g.database},
+		{Key: "user", Value: g.user},
+		{Key: "application", Value: g.application},
+		{Key: "state", Value: g.state},
+	}
+}
+
+// groupPgBouncerConnections counts the connections of each database, user, application and state, and keeps
+// the longest wait in milliseconds of the waiting ones, whose state is waiting or waiting_cancel_req
+func groupPgBouncerConnections(connections []pgbouncerConnection) []*pgbouncerConnectionGroup {
+	groups := make([]*pgbouncerConnectionGroup, 0)
+	groupIndex := make(map[pgbouncerConnectionGroup]*pgbouncerConnectionGroup)
+	for _, c := range connections {
+		key := pgbouncerConnectionGroup{
+			database:    stringOrEmpty(c.Database),
+			user:        stringOrEmpty(c.User),
+			application: stringOrEmpty(c.ApplicationName),
+			state:       stringOrEmpty(c.State),
+		}
+		group, ok := groupIndex[key]
+		if !ok {
+			group = &pgbouncerConnectionGroup{database: key.database, user: key.user, application: key.application, state: key.state}
+			group.waiting = strings.HasPrefix(group.state, "waiting")
+			groupIndex[key] = group
+			groups = append(groups, group)
+		}
+
+		group.connections++
+		if group.waiting {
+			group.maxWait = math.Max(group.maxWait, waitInMilliseconds(c.Wait, c.WaitUs))
+		}
+	}
+
+	return groups
+}
+
+// waitInMilliseconds converts a PgBouncer wait, given in seconds plus a microsecond part, to milliseconds
+func waitInMilliseconds(seconds, microseconds *int64) float64 {
+	wait := 0.0
+	if seconds != nil {
+		wait += float64(*seconds) * 1000
+	}
+	if microseconds != nil {
+		wait += float64(*microseconds) / 1000
+	}
+	return wait
+}
+
+func stringOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// snakeToCamelCase converts names such as used_clients to usedClients
+func snakeToCamelCase(name string) string {
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// PopulatePgpoolMetrics populates the pgpool instance entity and a pgpool node entity for each backend node of Pgpool-II
+func PopulatePgpoolMetrics(pgIntegration *integration.Integration, con *connection.PGSQLConnection, ci connection.Info) {
+	instanceEntity, err := PgpoolInstanceEntity(pgIntegration, ci)
+	if err != nil {
+		log.Error("Failed to get pgpool instance entity: %s", err.Error())
+		return
+	}
+	instanceSet := instanceEntity.NewMetricSet("PgpoolInstanceSample",
+		attribute.Attribute{Key: "displayName", Value: instanceEntity.Metadata.Name},
+		attribute.Attribute{Key: "entityName", Value: instanceEntity.Metadata.Namespace + ":" + instanceEntity.Metadata.Name},
+	)
+
+	populatePgpoolNodesMetrics(pgIntegration, instanceEntity, instanceSet, con)
+
+	processes := pgpoolProcessesDefinition.GetDataModels().(*[]pgpoolProcess)
+	if err := con.QueryUnsafe(processes, pgpoolProcessesDefinition.GetQuery()); err != nil {
+		log.Error("Could not execute pgpool processes query: %s", err.Error())
+		return
+	}
+
+	withClient := 0
+	for _, process := range *processes {
+		if process.Database != nil && *process.Database != "" {
+			withClient++
+		}
+	}
+	setPgpoolMetric(instanceSet, "pgpool.processes.total", len(*processes))
+	setPgpoolMetric(instanceSet, "pgpool.processes.withClient", withClient)
+	setPgpoolMetric(instanceSet, "pgpool.processes.withoutClient", len(*processes)-withClient)
+}
+
+// populatePgpoolNodesMetrics populates the backend node entities and the number of nodes up and down
+func populatePgpoolNodesMetrics(pgIntegration *integration.Integration, instanceEntity *integration.Entity, instanceSet *metric.Set, con *connection.PGSQLConnection) {
+	nodes := pgpoolNodesDefinition.GetDataModels().(*[]pgpoolNode)
+	if err := con.QueryUnsafe(nodes, pgpoolNodesDefinition.GetQuery()); err != nil {
+		log.Error("Could not execute pgpool nodes query: %s", err.Error())
+		return
+	}
+
+	healthChecks := pgpoolHealthCheckStatsDefinition.GetDataModels().(*[]pgpoolHealthCheckStats)
+	if err := con.QueryUnsafe(healthChecks, pgpoolHealthCheckStatsDefinition.GetQuery()); err != nil {
+		log.Warn("Could not execute pgpool health check stats query, available from Pgpool-II 4.1: %s", err.Error())
+	}
+
+	pools := pgpoolPoolsDefinition.GetDataModels().(*[]pgpoolPool)
+	if err := con.QueryUnsafe(pools, pgpoolPoolsDefinition.GetQuery()); err != nil {
+		log.Error("Could not execute pgpool pools query: %s", err.Error())
+	}
+
+	nodesUp := 0
+	for _, node := range *nodes {
+		if node.NodeID == nil || node.Hostname == nil || node.Port == nil {
+			continue
+		}
+		up := node.Status != nil && (*node.Status == "up" || *node.Status == "waiting")
+		if up {
+			nodesUp++
+		}
+		populatePgpoolNodeMetrics(pgIntegration, instanceEntity, node, up, *healthChecks, *pools)
+	}
+
+	setPgpoolMetric(instanceSet, "pgpool.nodes.total", len(*nodes))
+	setPgpoolMetric(instanceSet, "pgpool.nodes.up", nodesUp)
+	setPgpoolMetric(instanceSet, "pgpool.nodes.down", len(*nodes)-nodesUp)
+}
+
+// PgpoolInstanceEntity returns the entity of the Pgpool-II instance
+func PgpoolInstanceEntity(pgIntegration *integration.Integration, ci connection.Info) (*integration.Entity, error) {
+	host, port := ci.HostPort()
+	return pgIntegration.Entity(fmt.Sprintf("%s:%s", host, port), "pgpool-instance")
+}
+
+// populatePgpoolNodeMetrics populates a PgpoolNodeSample on the entity of a backend node, with its health checks
+// and the backend connections cached for it by the child processes
+func populatePgpoolNodeMetrics(pgIntegration *integration.Integration, instanceEntity *integration.Entity, node pgpoolNode, up bool,
+	healthChecks []pgpoolHealthCheckStats, pools []pgpoolPool) {
+	nodeName := fmt.Sprintf("%s:%s", *node.Hostname, *node.Port)
+	pgpoolIDAttribute := integration.NewIDAttribute("pgpool", instanceEntity.Metadata.Name)
+	nodeEntity, err := pgIntegration.Entity(nodeName, "pgpool-node", pgpoolIDAttribute)
+	if err != nil {
+		log.Error("Failed to get pgpool node entity for name %s: %s", nodeName, err.Error())
+		return
+	}
+
+	// the node ID is part of the namespace so the rates of each node are computed separately
+	metricSet := nodeEntity.NewMetricSet("PgpoolNodeSample",
+		attribute.Attribute{Key: "displayName", Value: nodeEntity.Metadata.Name},
+		attribute.Attribute{Key: "entityName", Value: "pgpool-node:" + nodeEntity.Metadata.Name},
+		attribute.Attribute{Key: "pgpool", Value: instanceEntity.Metadata.Name},
+		attribute.Attribute{Key: "pgpool.node.id", Value: *node.NodeID},
+	)
+
+	if err := metricSet.MarshalMetrics(node); err != nil {
+		log.Error("Failed to populate pgpool node entity with metrics: %s", err.Error())
+	}
+
+	upValue := 0
+	if up {
+		upValue = 1
+	}
+	setPgpoolMetric(metricSet, "pgpool.node.up", upValue)
+
+	if name, delay, ok := parsePgpoolReplicationDelay(node.ReplicationDelay); ok {
+		setPgpoolMetric(metricSet, name, delay)
+	}
+
+	for _, healthCheck := range healthChecks {
+		if healthCheck.NodeID != nil && *healthCheck.NodeID == *node.NodeID {
+			if err := metricSet.MarshalMetrics(healthCheck); err != nil {
+				log.Error("Failed to populate pgpool node entity with health check metrics: %s", err.Error())
+			}
+		}
+	}
+
+	pooled, active := 0, 0
+	for _, pool := range pools {
+		if pool.BackendID == nil || *pool.BackendID != *node.NodeID {
+			continue
+		}
+		if pool.BackendPID != nil && *pool.BackendPID != "" && *pool.BackendPID != "0" {
+			pooled++
+		}
+		if pool.PoolConnected != nil && *pool.PoolConnected == "1" {
+			active++
+		}
+	}
+	setPgpoolMetric(metricSet, "pgpool.node.pooledConnections", pooled)
+	setPgpoolMetric(metricSet, "pgpool.node.activeConnections", active)
+}
+
+// parsePgpoolReplicationDelay parses the replication delay of a node and returns the metric it is reported
+// as. The delay is a bare number of bytes, or is followed by its unit when it is measured in seconds.
+func parsePgpoolReplicationDelay(replicationDelay *string) (string, float64, bool) {
+	if replicationDelay == nil {
+		return "", 0, false
+	}
+	fields := strings.Fields(*replicationDelay)
+	if len(fields) == 0 || len(fields) > 2 {
+		return "", 0, false
+	}
+	delay, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return "", 0, false
+	}
+	if len(fields) == 1 {
+		return "pgpool.node.replicationDelayInBytes", delay, true
+	}
+	switch strings.ToLower(fields[1]) {
+	case "byte", "bytes":
+		return "pgpool.node.replicationDelayInBytes", delay, true
+	case "s", "sec", "secs", "second", "seconds":
+		return "pgpool.node.replicationDelayInSeconds", delay, true
+	default:
+		return "", 0, false
+	}
+}
+
+func setPgpoolMetric(metricSet *metric.Set, name string, value interface{}) {
+	if err := metricSet.SetMetric(name, value, metric.GAUGE); err != nil {
+		log.Error("Failed to populate pgpool entity with metrics: %s", err.Error())
+	}
+}
+
 // PopulateCustomMetrics collects metrics from a custom query
 func PopulateCustomMetrics(customMetricsQuery string, pgIntegration *integration.Integration, con *connection.PGSQLConnection, ci connection.Info, instance *integration.Entity) {
 	rows, err := con.Queryx(customMetricsQuery)
@@ -1,12 +1,15 @@
 package metrics
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/blang/semver/v4"
+	"github.com/newrelic/infra-integrations-sdk/v3/data/metric"
 	"github.com/newrelic/infra-integrations-sdk/v3/integration"
+	"github.com/newrelic/infra-integrations-sdk/v3/persist"
 	"github.com/newrelic/nri-postgresql/src/collection"
 	"github.com/newrelic/nri-postgresql/src/connection"
 	"github.com/stretchr/testify/assert"
@@ -83,6 +86,64 @@ func TestPopulateInstanceMetrics_NoRows(t *testing.T) {
 	assert.Equal(t, expected, testEntity.Metrics[0].Metrics)
 }
 
+func TestPopulateXminHorizonMetrics(t *testing.T) {
+	testIntegration, _ := integration.New("test", "test")
+	testEntity, _ := testIntegration.Entity("testInstance", "instance")
+
+	version := semver.MustParse("16.0.0")
+
+	testConnection, mock := connection.CreateMockSQL(t)
+	mock.ExpectQuery(".*XMIN_ACTIVITY.*").
+		WillReturnRows(sqlmock.NewRows([]string{"pid", "xmin_age", "xmin_age_seconds"}).AddRow(42, 1000, 3600))
+	mock.ExpectQuery(".*XMIN_REPLICATION_SLOTS.*").
+		WillReturnRows(sqlmock.NewRows([]string{"slot_name", "xmin_age", "data_xmin_age", "catalog_xmin_age"}).AddRow("slot1", 5000, nil, 5000))
+	mock.ExpectQuery(".*XMIN_PREPARED_XACTS.*").
+		WillReturnRows(sqlmock.NewRows([]string{"gid", "xmin_age", "xmin_age_seconds"}))
+	mock.ExpectQuery(".*XMIN_STANDBY.*").
+		WillReturnRows(sqlmock.NewRows([]string{"pid", "application_name", "xmin_age"}).AddRow(7, "replica1", 200))
+
+	PopulateXminHorizonMetrics(testEntity, &version, testConnection)
+
+	expected := map[string]interface{}{
+		"xmin.activity.pid":                                 float64(42),
+		"xmin.activity.ageInTransactions":                   float64(1000),
+		"xmin.activity.ageInSeconds":                        float64(3600),
+		"xmin.replicationSlot.slotName":                     "slot1",
+		"xmin.replicationSlot.ageInTransactions":            float64(5000),
+		"xmin.replicationSlot.catalogXminAgeInTransactions": float64(5000),
+		"xmin.standby.pid":                                  float64(7),
+		"xmin.standby.applicationName":                      "replica1",
+		"xmin.standby.ageInTransactions":                    float64(200),
+		"displayName":                                       "testInstance",
+		"entityName":                                        "instance:testInstance",
+		"event_type":                                        "PostgresqlXminHorizonSample",
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, expected, testEntity.Metrics[0].Metrics)
+}
+
+func TestPopulateXminHorizonMetrics_NoHolder(t *testing.T) {
+	testIntegration, _ := integration.New("test", "test")
+	testEntity, _ := testIntegration.Entity("testInstance", "instance")
+
+	version := semver.MustParse("16.0.0")
+
+	testConnection, mock := connection.CreateMockSQL(t)
+	mock.ExpectQuery(".*XMIN_ACTIVITY.*").
+		WillReturnRows(sqlmock.NewRows([]string{"pid", "xmin_age", "xmin_age_seconds"}))
+	mock.ExpectQuery(".*XMIN_REPLICATION_SLOTS.*").WillReturnError(errors.New("permission denied"))
+	mock.ExpectQuery(".*XMIN_PREPARED_XACTS.*").
+		WillReturnRows(sqlmock.NewRows([]string{"gid", "xmin_age", "xmin_age_seconds"}))
+	mock.ExpectQuery(".*XMIN_STANDBY.*").
+		WillReturnRows(sqlmock.NewRows([]string{"pid", "application_name", "xmin_age"}))
+
+	PopulateXminHorizonMetrics(testEntity, &version, testConnection)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Empty(t, testEntity.Metrics)
+}
+
 func TestPopulateDatabaseMetrics(t *testing.T) {
 	testIntegration, _ := integration.New("test", "test")
 
@@ -132,19 +193,130 @@ func TestPopulateDatabaseMetrics(t *testing.T) {
 	assert.Equal(t, expected, dbEntity.Metrics[0].Metrics)
 }
 
-func TestPopulateDatabaseLockMetrics_WithTablefuncExtension(t *testing.T) {
+func TestPopulateDatabaseMetrics_PreparedTransactions(t *testing.T) {
 	testIntegration, _ := integration.New("test", "test")
 
 	version := semver.MustParse("9.0.0")
 	dbList := collection.DatabaseList{"test1": {}}
 
 	testConnection, mock := connection.CreateMockSQL(t)
+	mock.ExpectQuery(".*UNDER91.*").
+		WillReturnRows(sqlmock.NewRows([]string{"database"}))
+	mock.ExpectQuery(".*PREPARED_XACTS.*").
+		WillReturnRows(sqlmock.NewRows([]string{
+			"database",
+			"prepared_transactions",
+			"oldest_prepared_transaction_age",
+			"oldest_prepared_transaction_gid",
+			"oldest_prepared_transaction_owner",
+			"oldest_prepared_transaction_prepared_at",
+			"max_prepared_transactions",
+			"prepared_transactions_enabled",
+		}).AddRow("testDB", 2, 3600.5, "app-1:tx-42", "app", 1760000000, 10, true))
+
+	ci := &connection.MockInfo{}
+	PopulateDatabaseMetrics(dbList, &version, testIntegration, testConnection, ci)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	expected := map[string]interface{}{
+		"db.preparedTransactions":                  float64(2),
+		"db.oldestPreparedTransactionAgeInSeconds": 3600.5,
+		"db.oldestPreparedTransactionGid":          "app-1:tx-42",
+		"db.oldestPreparedTransactionOwner":        "app",
+		"db.oldestPreparedTransactionPreparedAt":   float64(1760000000),
+		"db.maxPreparedTransactions":               float64(10),
+		"db.preparedTransactionsEnabled":           float64(1),
+		"displayName":                              "testDB",
+		"entityName":                               "database:testDB",
+		"event_type":                               "PostgresqlDatabaseSample",
+	}
+
+	dbEntity, err := testIntegration.Entity("testDB", "pg-database", integration.NewIDAttribute("host", "testhost"), integration.NewIDAttribute("port", "1234"))
+	assert.Nil(t, err)
+	assert.Equal(t, expected, dbEntity.Metrics[0].Metrics)
+}
+
+func TestPopulateConnectionMetrics(t *testing.T) {
+	testIntegration, _ := integration.New("test", "test")
+	testEntity, _ := testIntegration.Entity("testInstance", "instance")
+
+	version := semver.MustParse("16.0.0")
+	dbList := collection.DatabaseList{"db1": {}}
+
+	testConnection, mock := connection.CreateMockSQL(t)
+	connectionRows := sqlmock.NewRows([]string{
+		"database", "user_name", "application_name", "backend_type",
+		"total", "active", "idle", "idle_in_transaction", "idle_in_transaction_aborted", "fastpath", "waiting_on_lock",
+		"max_transaction_seconds", "max_query_seconds", "max_idle_in_transaction_seconds",
+		"user_connection_limit", "user_connections", "user_connection_utilization",
+		"database_connection_limit", "database_connections", "database_connection_utilization",
+		"instance_connection_limit", "instance_connections", "instance_connection_utilization",
+	}).
+		AddRow("db1", "app_user", "web", "client backend", 10, 4, 3, 2, 1, 0, 1, 120, 30, 60, 20, 10, 50.0, -1, 10, nil, 97, 12, 12.37).
+		AddRow(nil, nil, "", "checkpointer", 1, 0, 0, 0, 0, 0, 0, nil, nil, nil, nil, nil, nil, nil, nil, nil, 97, 12, 12.37)
+	mock.ExpectQuery(".*CONNECTIONS_OVER16.*").WillReturnRows(connectionRows)
+
+	PopulateConnectionMetrics(dbList, &version, testEntity, testConnection)
+
+	expectedClient := map[string]interface{}{
+		"database":                             "db1",
+		"user":                                 "app_user",
+		"application":                          "web",
+		"backendType":                          "client backend",
+		"connections.total":                    float64(10),
+		"connections.active":                   float64(4),
+		"connections.idle":                     float64(3),
+		"connections.idleInTransaction":        float64(2),
+		"connections.idleInTransactionAborted": float64(1),
+		"connections.fastpath":                 float64(0),
+		"connections.waitingOnLock":            float64(1),
+		"connections.maxTransactionDurationInSeconds": float64(120),
+		"connections.maxQueryDurationInSeconds":       float64(30),
+		"connections.maxIdleInTransactionInSeconds":   float64(60),
+		"connections.userConnectionLimit":             float64(20),
+		"connections.userConnections":                 float64(10),
+		"connections.userUtilizationPercent":          float64(50),
+		"connections.databaseConnectionLimit":         float64(-1),
+		"connections.databaseConnections":             float64(10),
+		"connections.instanceConnectionLimit":         float64(97),
+		"connections.instanceConnections":             float64(12),
+		"connections.instanceUtilizationPercent":      float64(12.37),
+		"displayName":                                 "testInstance",
+		"entityName":                                  "instance:testInstance",
+		"event_type":                                  "PostgresqlConnectionSample",
+	}
+	expectedBackground := map[string]interface{}{
+		"application":                            "",
+		"backendType":                            "checkpointer",
+		"connections.total":                      float64(1),
+		"connections.active":                     float64(0),
+		"connections.idle":                       float64(0),
+		"connections.idleInTransaction":          float64(0),
+		"connections.idleInTransactionAborted":   float64(0),
+		"connections.fastpath":                   float64(0),
+		"connections.waitingOnLock":              float64(0),
+		"connections.instanceConnectionLimit":    float64(97),
+		"connections.instanceConnections":        float64(12),
+		"connections.instanceUtilizationPercent": float64(12.37),
+		"displayName":                            "testInstance",
+		"entityName":                             "instance:testInstance",
+		"event_type":                             "PostgresqlConnectionSample",
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Len(t, testEntity.Metrics, 2)
+	assert.Equal(t, expectedClient, testEntity.Metrics[0].Metrics)
+	assert.Equal(t, expectedBackground, testEntity.Metrics[1].Metrics)
+}
+
+func TestPopulateDatabaseLockMetrics(t *testing.T) {
+	testIntegration, _ := integration.New("test", "test")
+
+	version := semver.MustParse("9.0.0")
+	dbList := collection.DatabaseList{"test1": {}}
 
-	extensionRows := sqlmock.NewRows([]string{
-		"schema",
-		"extension",
-	}).AddRow("public", "tablefunc")
-	mock.ExpectQuery(".*EXTENSIONS_LIST.*").WillReturnRows(extensionRows)
+	testConnection, mock := connection.CreateMockSQL(t)
 
 	lockRows := sqlmock.NewRows([]string{
 		"database",
@@ -156,24 +328,34 @@ func TestPopulateDatabaseLockMetrics_WithTablefuncExtension(t *testing.T) {
 		"share_lock",
 		"share_row_exclusive_lock",
 		"share_update_exclusive_lock",
-	}).AddRow("testDB", 1, 2, 3, 4, 5, 6, 7, 8)
+		"access_exclusive_lock_waiting",
+		"granted",
+		"waiting",
+		"oldest_waiting_statement",
+	}).AddRow("testDB", 1, 2, 3, 4, 5, 6, 7, 8, 1, 34, 2, 12.5)
 	mock.ExpectQuery(".*LOCKS_DEFINITION.*").WillReturnRows(lockRows)
 
 	ci := &connection.MockInfo{}
 	PopulateDatabaseLockMetrics(dbList, &version, testIntegration, testConnection, ci)
 
+	assert.NoError(t, mock.ExpectationsWereMet())
+
 	expected := map[string]interface{}{
-		"db.locks.accessExclusiveLock":      float64(1),
-		"db.locks.accessShareLock":          float64(2),
-		"db.locks.exclusiveLock":            float64(3),
-		"db.locks.rowExclusiveLock":         float64(4),
-		"db.locks.rowShareLock":             float64(5),
-		"db.locks.shareLock":                float64(6),
-		"db.locks.shareRowExclusiveLock":    float64(7),
-		"db.locks.shareUpdateExclusiveLock": float64(8),
-		"displayName":                       "testDB",
-		"entityName":                        "database:testDB",
-		"event_type":                        "PostgresqlDatabaseSample",
+		"db.locks.accessExclusiveLock":                float64(1),
+		"db.locks.accessShareLock":                    float64(2),
+		"db.locks.exclusiveLock":                      float64(3),
+		"db.locks.rowExclusiveLock":                   float64(4),
+		"db.locks.rowShareLock":                       float64(5),
+		"db.locks.shareLock":                          float64(6),
+		"db.locks.shareRowExclusiveLock":              float64(7),
+		"db.locks.shareUpdateExclusiveLock":           float64(8),
+		"db.locks.accessExclusiveLockWaiting":         float64(1),
+		"db.locks.granted":                            float64(34),
+		"db.locks.waiting":                            float64(2),
+		"db.locks.oldestWaitingStatementAgeInSeconds": 12.5,
+		"displayName":                                 "testDB",
+		"entityName":                                  "database:testDB",
+		"event_type":                                  "PostgresqlDatabaseSample",
 	}
 
 	dbEntity, err := testIntegration.Entity("testDB", "pg-database", integration.NewIDAttribute("host", "testhost"), integration.NewIDAttribute("port", "1234"))
@@ -182,22 +364,51 @@ func TestPopulateDatabaseLockMetrics_WithTablefuncExtension(t *testing.T) {
 	assert.Equal(t, expected, dbEntity.Metrics[0].Metrics)
 }
 
-func TestPopulateDatabaseLockMetrics_WithoutTablefuncExtension(t *testing.T) {
+func TestPopulateDatabaseLockMetrics_RelationLocks(t *testing.T) {
 	testIntegration, _ := integration.New("test", "test")
 
-	version := semver.MustParse("9.0.0")
-	dbList := collection.DatabaseList{"test1": {}}
+	version := semver.MustParse("14.0.0")
+	dbList := collection.DatabaseList{
+		"db1": collection.SchemaList{
+			"schema1": collection.TableList{
+				"table1": []string{},
+			},
+		},
+	}
 
 	testConnection, mock := connection.CreateMockSQL(t)
-	extensionRows := sqlmock.NewRows([]string{"schema", "extension"})
-	mock.ExpectQuery(".*EXTENSIONS_LIST.*").WillReturnRows(extensionRows)
+	mock.ExpectQuery("(?s)LOCKS_DEFINITION.*L.waitstart").
+		WillReturnRows(sqlmock.NewRows([]string{"database", "access_exclusive_lock", "granted", "waiting"}).
+			AddRow("db1", 1, 3, 1))
+	mock.ExpectQuery(".*RELATION_LOCKS_OVER14.*'schema1.table1'.*").
+		WillReturnRows(sqlmock.NewRows([]string{"database", "schema_name", "table_name", "locks", "granted", "waiting", "access_exclusive_lock", "oldest_wait"}).
+			AddRow("db1", "schema1", "table1", 3, 2, 1, 1, 4.0))
 
 	ci := &connection.MockInfo{}
+	ci.On("NewConnection", "db1").Return(testConnection, nil)
 	PopulateDatabaseLockMetrics(dbList, &version, testIntegration, testConnection, ci)
-	dbEntity, err := testIntegration.Entity("testDB", "pg-database", integration.NewIDAttribute("host", "testhost"), integration.NewIDAttribute("port", "1234"))
 
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	host := integration.NewIDAttribute("host", "testhost")
+	port := integration.NewIDAttribute("port", "1234")
+	database := integration.NewIDAttribute("pg-database", "db1")
+	schema := integration.NewIDAttribute("pg-schema", "schema1")
+	tableEntity, err := testIntegration.Entity("table1", "pg-table", host, port, database, schema)
 	assert.Nil(t, err)
-	assert.Empty(t, dbEntity.Metrics)
+	assert.Len(t, tableEntity.Metrics, 1)
+	assert.Equal(t, map[string]interface{}{
+		"table.locks.total":               float64(3),
+		"table.locks.granted":             float64(2),
+		"table.locks.waiting":             float64(1),
+		"table.locks.accessExclusiveLock": float64(1),
+		"table.locks.oldestWaitInSeconds": float64(4),
+		"database":                        "db1",
+		"schema":                          "schema1",
+		"displayName":                     "table1",
+		"entityName":                      "table:table1",
+		"event_type":                      "PostgresqlTableLockSample",
+	}, tableEntity.Metrics[0].Metrics)
 }
 
 func Test_populateTableMetricsForDatabase(t *testing.T) {
@@ -250,10 +461,17 @@ func Test_populateTableMetricsForDatabase(t *testing.T) {
 		WillReturnRows(bloatRows)
 	mock.ExpectQuery(".*TABLEQUERY.*").
 		WillReturnRows(tableRows)
+	mock.ExpectQuery(".*TABLE_MAINTENANCE.*").
+		WillReturnRows(sqlmock.NewRows([]string{
+			"database", "schema_name", "table_name", "hot_updates", "hot_update_percent", "mod_since_analyze", "ins_since_vacuum",
+			"vacuum_count", "autovacuum_count", "analyze_count", "autoanalyze_count", "total_vacuum_time", "total_autovacuum_time",
+			"autovacuum_enabled", "vacuum_threshold", "insert_threshold", "vacuum_due", "analyze_threshold", "analyze_due",
+		}).AddRow("db1", "schema1", "table1", 10, 62.5, 30, nil, 1, 2, 3, 4, nil, nil, true, 60.0, nil, true, 35.0, false))
 
 	ci := &connection.MockInfo{}
 	version := semver.MustParse("12.0.0")
 	populateTableMetricsForDatabase(dbList["db1"], &version, testConnection, testIntegration, ci, true)
+	populateTableMaintenanceForDatabase(dbList["db1"], &version, testConnection, testIntegration, ci)
 
 	expectedBase := map[string]interface{}{
 		"table.totalSizeInBytes":                   float64(1),
@@ -293,6 +511,26 @@ func Test_populateTableMetricsForDatabase(t *testing.T) {
 		"event_type":             "PostgresqlTableSample",
 	}
 
+	expectedMaintenance := map[string]interface{}{
+		"table.hotUpdatesPerSecond":         float64(0),
+		"table.hotUpdatePercent":            62.5,
+		"table.rowsModifiedSinceAnalyze":    float64(30),
+		"table.vacuumsPerSecond":            float64(0),
+		"table.autoVacuumsPerSecond":        float64(0),
+		"table.analyzesPerSecond":           float64(0),
+		"table.autoAnalyzesPerSecond":       float64(0),
+		"table.autovacuum.enabled":          float64(1),
+		"table.autovacuum.vacuumThreshold":  float64(60),
+		"table.autovacuum.vacuumDue":        float64(1),
+		"table.autovacuum.analyzeThreshold": float64(35),
+		"table.autovacuum.analyzeDue":       float64(0),
+		"database":                          "db1",
+		"schema":                            "schema1",
+		"displayName":                       "table1",
+		"entityName":                        "table:table1",
+		"event_type":                        "PostgresqlTableMaintenanceSample",
+	}
+
 	id1 := integration.NewIDAttribute("pg-database", "db1")
 	id2 := integration.NewIDAttribute("pg-schema", "schema1")
 	id3 := integration.NewIDAttribute("host", "testhost")
@@ -300,7 +538,10 @@ func Test_populateTableMetricsForDatabase(t *testing.T) {
 	tableEntity, err := testIntegration.Entity("table1", "pg-table", id1, id2, id3, id4)
 	assert.Nil(t, err)
 	assert.Equal(t, expectedBloat, tableEntity.Metrics[0].Metrics)
+	assert.Len(t, tableEntity.Metrics, 3)
 	assert.Equal(t, expectedBase, tableEntity.Metrics[1].Metrics)
+	assert.Equal(t, expectedMaintenance, tableEntity.Metrics[2].Metrics)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestPopulateTableMetricsForDatabaseNoTables(t *testing.T) {
@@ -352,7 +593,11 @@ func TestPopulateIndexMetricsForDatabase(t *testing.T) {
 		"index_size",
 		"tuples_read",
 		"tuples_fetched",
-	}).AddRow("db1", "schema1", "table1", "index11", 1, 2, 3)
+		"scans",
+		"is_unique",
+		"is_valid",
+		"index_columns",
+	}).AddRow("db1", "schema1", "table1", "index11", 1, 2, 3, 4, true, true, "id, lower(name)")
 	indexRows2 := sqlmock.NewRows([]string{
 		"database",
 		"schema_name",
@@ -369,8 +614,8 @@ func TestPopulateIndexMetricsForDatabase(t *testing.T) {
 		WillReturnRows(indexRows2)
 
 	ci := &connection.MockInfo{}
-	populateIndexMetricsForDatabase(dbList["db1"], testConnection, testIntegration, ci)
-	populateIndexMetricsForDatabase(dbList["db2"], testConnection, testIntegration, ci)
+	populateIndexMetricsForDatabase(dbList["db1"], testConnection, testIntegration, ci, false)
+	populateIndexMetricsForDatabase(dbList["db2"], testConnection, testIntegration, ci, false)
 
 	expected := map[string]interface{}{
 		"database":                   "db1",
@@ -382,6 +627,10 @@ func TestPopulateIndexMetricsForDatabase(t *testing.T) {
 		"index.sizeInBytes":          float64(1),
 		"index.rowsReadPerSecond":    float64(0),
 		"index.rowsFetchedPerSecond": float64(0),
+		"index.scansPerSecond":       float64(0),
+		"index.isUnique":             float64(1),
+		"index.isValid":              float64(1),
+		"index.columns":              "id, lower(name)",
 	}
 	expected2 := map[string]interface{}{
 		"database":                   "db2",
@@ -413,6 +662,89 @@ func TestPopulateIndexMetricsForDatabase(t *testing.T) {
 	assert.Equal(t, expected2, indexEntity2.Metrics[0].Metrics)
 }
 
+func TestPopulateIndexMetrics_BloatQueryFails(t *testing.T) {
+	testIntegration, _ := integration.New("test", "test")
+
+	schemaList := collection.SchemaList{
+		"schema1": collection.TableList{
+			"table1": []string{"index1"},
+		},
+	}
+
+	testConnection, mock := connection.CreateMockSQL(t)
+	mock.ExpectQuery(".*INDEXQUERY.*").
+		WillReturnRows(sqlmock.NewRows([]string{"database", "schema_name", "table_name", "index_name", "index_size"}).
+			AddRow("db1", "schema1", "table1", "index1", 8192))
+	mock.ExpectQuery(".*INDEXBLOATQUERY.*").WillReturnError(errors.New("bloat estimate failed"))
+
+	ci := &connection.MockInfo{}
+	populateIndexMetricsForDatabase(schemaList, testConnection, testIntegration, ci, true)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	indexEntity, err := testIntegration.Entity("index1", "pg-index",
+		integration.NewIDAttribute("pg-database", "db1"),
+		integration.NewIDAttribute("pg-schema", "schema1"),
+		integration.NewIDAttribute("host", "testhost"),
+		integration.NewIDAttribute("port", "1234"),
+		integration.NewIDAttribute("pg-table", "table1"))
+	assert.NoError(t, err)
+	assert.Len(t, indexEntity.Metrics, 1)
+	assert.Equal(t, float64(8192), indexEntity.Metrics[0].Metrics["index.sizeInBytes"])
+}
+
+func TestPopulateIndexHealthForDatabase(t *testing.T) {
+	testIntegration, _ := integration.New("test", "test")
+
+	schemaList := collection.SchemaList{
+		"schema1": collection.TableList{
+			"table1": []string{"index1", "index2"},
+		},
+	}
+
+	testConnection, mock := connection.CreateMockSQL(t)
+	mock.ExpectQuery(".*INDEX_HEALTH.*").
+		WillReturnRows(sqlmock.NewRows([]string{
+			"database", "schema_name", "table_name", "index_name", "issue", "related_index", "index_size",
+		}).AddRow("db1", "schema1", "table1", "index1", "unused", nil, 8192).
+			AddRow("db1", "schema1", "table1", "index1", "redundant", "index2", 8192))
+
+	ci := &connection.MockInfo{}
+	populateIndexHealthForDatabase(schemaList, testConnection, testIntegration, ci)
+
+	expectedUnused := map[string]interface{}{
+		"indexHealth.issue":            "unused",
+		"indexHealth.indexSizeInBytes": float64(8192),
+		"database":                     "db1",
+		"schema":                       "schema1",
+		"table":                        "table1",
+		"displayName":                  "index1",
+		"entityName":                   "index:index1",
+		"event_type":                   "PostgresqlIndexHealthSample",
+	}
+	expectedRedundant := map[string]interface{}{
+		"indexHealth.issue":            "redundant",
+		"indexHealth.relatedIndex":     "index2",
+		"indexHealth.indexSizeInBytes": float64(8192),
+		"database":                     "db1",
+		"schema":                       "schema1",
+		"table":                        "table1",
+		"displayName":                  "index1",
+		"entityName":                   "index:index1",
+		"event_type":                   "PostgresqlIndexHealthSample",
+	}
+
+	indexEntity, err := testIntegration.Entity("index1", "pg-index",
+		integration.NewIDAttribute("host", "testhost"), integration.NewIDAttribute("port", "1234"),
+		integration.NewIDAttribute("pg-database", "db1"), integration.NewIDAttribute("pg-schema", "schema1"),
+		integration.NewIDAttribute("pg-table", "table1"))
+	assert.Nil(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Len(t, indexEntity.Metrics, 2)
+	assert.Equal(t, expectedUnused, indexEntity.Metrics[0].Metrics)
+	assert.Equal(t, expectedRedundant, indexEntity.Metrics[1].Metrics)
+}
+
 func TestPopulateIndexMetricsForDatabaseNoIndexes(t *testing.T) {
 	testIntegration, _ := integration.New("test", "test")
 
@@ -427,13 +759,580 @@ func TestPopulateIndexMetricsForDatabaseNoIndexes(t *testing.T) {
 	testConnection, _ := connection.CreateMockSQL(t)
 
 	ci := &connection.MockInfo{}
-	populateIndexMetricsForDatabase(dbList["db1"], testConnection, testIntegration, ci)
+	populateIndexMetricsForDatabase(dbList["db1"], testConnection, testIntegration, ci, false)
 
 	indexEntity, err := testIntegration.Entity("index1", "index")
 	assert.Nil(t, err)
 	assert.Equal(t, 0, len(indexEntity.Metrics))
 }
 
+func TestPopulateProgressMetricsForDatabase(t *testing.T) {
+	testIntegration, _ := integration.New("test", "test")
+
+	schemaList := collection.SchemaList{
+		"schema1": collection.TableList{
+			"table1": []string{},
+		},
+	}
+
+	testConnection, mock := connection.CreateMockSQL(t)
+	mock.ExpectQuery(".*PROGRESS_VACUUM.*").
+		WillReturnRows(sqlmock.NewRows([]string{
+			"database", "schema_name", "table_name", "pid", "command", "phase", "percent_complete", "elapsed_seconds",
+			"heap_blocks_total", "heap_blocks_scanned", "heap_blocks_vacuumed", "index_vacuum_count",
+		}).AddRow("db1", "schema1", "table1", 100, "AUTOVACUUM", "scanning heap", 25.0, 60, 400, 100, 50, 0))
+	mock.ExpectQuery(".*PROGRESS_CREATE_INDEX.*").
+		WillReturnRows(sqlmock.NewRows([]string{
+			"database", "schema_name", "table_name", "index_name", "pid", "command", "phase", "percent_complete", "elapsed_seconds",
+		}).AddRow("db1", "schema2", "table2", "index2", 101, "CREATE INDEX CONCURRENTLY", "building index: scanning table", 50.0, 30))
+	mock.ExpectQuery(".*PROGRESS_CLUSTER.*").
+		WillReturnRows(sqlmock.NewRows([]string{
+			"database", "schema_name", "table_name", "pid", "command", "phase", "percent_complete", "elapsed_seconds",
+		}))
+
+	ci := &connection.MockInfo{}
+	version := semver.MustParse("12.0.0")
+	populateProgressMetricsForDatabase(schemaList, &version, testConnection, testIntegration, ci)
+
+	expectedTable := map[string]interface{}{
+		"progress.pid":                       float64(100),
+		"progress.command":                   "AUTOVACUUM",
+		"progress.phase":                     "scanning heap",
+		"progress.percentComplete":           float64(25),
+		"progress.elapsedTimeInSeconds":      float64(60),
+		"progress.vacuum.heapBlocksTotal":    float64(400),
+		"progress.vacuum.heapBlocksScanned":  float64(100),
+		"progress.vacuum.heapBlocksVacuumed": float64(50),
+		"progress.vacuum.indexVacuumCount":   float64(0),
+		"database":                           "db1",
+		"schema":                             "schema1",
+		"displayName":                        "table1",
+		"entityName":                         "table:table1",
+		"event_type":                         "PostgresqlProgressSample",
+	}
+	expectedDatabase := map[string]interface{}{
+		"progress.pid":                  float64(101),
+		"progress.command":              "CREATE INDEX CONCURRENTLY",
+		"progress.phase":                "building index: scanning table",
+		"progress.percentComplete":      float64(50),
+		"progress.elapsedTimeInSeconds": float64(30),
+		"progress.index":                "index2",
+		"schema":                        "schema2",
+		"table":                         "table2",
+		"displayName":                   "db1",
+		"entityName":                    "database:db1",
+		"event_type":                    "PostgresqlProgressSample",
+	}
+
+	host := integration.NewIDAttribute("host", "testhost")
+	port := integration.NewIDAttribute("port", "1234")
+	tableEntity, err := testIntegration.Entity("table1", "pg-table", host, port,
+		integration.NewIDAttribute("pg-database", "db1"), integration.NewIDAttribute("pg-schema", "schema1"))
+	assert.Nil(t, err)
+	dbEntity, err := testIntegration.Entity("db1", "pg-database", host, port)
+	assert.Nil(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, expectedTable, tableEntity.Metrics[0].Metrics)
+	assert.Equal(t, expectedDatabase, dbEntity.Metrics[0].Metrics)
+}
+
+func TestPopulateExactBloatMetrics(t *testing.T) {
+	testIntegration, _ := integration.New("test", "test")
+
+	dbList := collection.DatabaseList{
+		"db1": collection.SchemaList{
+			"schema1": collection.TableList{
+				"table1": []string{"index1"},
+			},
+		},
+	}
+
+	testConnection, mock := connection.CreateMockSQL(t)
+	mock.ExpectQuery(".*EXTENSIONS_LIST.*").
+		WillReturnRows(sqlmock.NewRows([]string{"schema", "extension"}).AddRow("public", "pgstattuple"))
+	mock.ExpectQuery(".*server_version.*").WillReturnRows(sqlmock.NewRows([]string{"server_version"}).AddRow("16.1"))
+	mock.ExpectQuery(".*EXACT_BLOAT_TABLE_CANDIDATES.*").
+		WillReturnRows(sqlmock.NewRows([]string{"database", "schema_name", "table_name", "oid"}).
+			AddRow("db1", "schema1", "table1", 100))
+	mock.ExpectQuery(".*EXACT_BLOAT_INDEX_CANDIDATES.*").
+		WillReturnRows(sqlmock.NewRows([]string{"database", "schema_name", "table_name", "index_name", "oid"}).
+			AddRow("db1", "schema1", "table1", "index1", 101))
+	mock.ExpectQuery(".*EXACT_INDEX_BLOAT.*= 101;").
+		WillReturnRows(sqlmock.NewRows([]string{
+			"database", "schema_name", "table_name", "index_name",
+			"leaf_density", "leaf_fragmentation", "leaf_pages", "empty_pages", "deleted_pages",
+		}).AddRow("db1", "schema1", "table1", "index1", 62.5, 10.0, 40, 1, 2))
+	mock.ExpectClose()
+
+	ci := &connection.MockInfo{}
+	ci.On("NewConnection", "db1").Return(testConnection, nil)
+
+	// The table was measured in a previous run, so the index goes first
+	store := persist.NewInMemoryStore()
+	store.Set("exactBloat:db1.schema1.table1", int64(100))
+
+	PopulateExactBloatMetrics(dbList, 1024, 1, testIntegration, ci, store)
+
+	expected := map[string]interface{}{
+		"index.exactBloat.leafDensityPercent":       62.5,
+		"index.exactBloat.leafFragmentationPercent": float64(10),
+		"index.exactBloat.leafPages":                float64(40),
+		"index.exactBloat.emptyPages":               float64(1),
+		"index.exactBloat.deletedPages":             float64(2),
+		"database":                                  "db1",
+		"schema":                                    "schema1",
+		"table":                                     "table1",
+		"displayName":                               "index1",
+		"entityName":                                "index:index1",
+		"event_type":                                "PostgresqlExactBloatSample",
+	}
+
+	host := integration.NewIDAttribute("host", "testhost")
+	port := integration.NewIDAttribute("port", "1234")
+	database := integration.NewIDAttribute("pg-database", "db1")
+	schema := integration.NewIDAttribute("pg-schema", "schema1")
+	indexEntity, err := testIntegration.Entity("index1", "pg-index", host, port, database, schema, integration.NewIDAttribute("pg-table", "table1"))
+	assert.Nil(t, err)
+	tableEntity, err := testIntegration.Entity("table1", "pg-table", host, port, database, schema)
+	assert.Nil(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, expected, indexEntity.Metrics[0].Metrics)
+	assert.Empty(t, tableEntity.Metrics)
+
+	var oid int64
+	_, err = store.Get("exactBloat:db1.schema1.table1.index1", &oid)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(101), oid)
+}
+
+func TestPopulateExactBloatMetrics_FailedRelationMarked(t *testing.T) {
+	testIntegration, _ := integration.New("test", "test")
+
+	dbList := collection.DatabaseList{
+		"db1": collection.SchemaList{
+			"schema1": collection.TableList{
+				"table1": []string{},
+			},
+		},
+	}
+
+	testConnection, mock := connection.CreateMockSQL(t)
+	mock.ExpectQuery(".*EXTENSIONS_LIST.*").
+		WillReturnRows(sqlmock.NewRows([]string{"schema", "extension"}).AddRow("public", "pgstattuple"))
+	mock.ExpectQuery(".*server_version.*").WillReturnRows(sqlmock.NewRows([]string{"server_version"}).AddRow("16.1"))
+	mock.ExpectQuery(".*EXACT_BLOAT_TABLE_CANDIDATES.*").
+		WillReturnRows(sqlmock.NewRows([]string{"database", "schema_name", "table_name", "oid"}).
+			AddRow("db1", "schema1", "table1", 100))
+	mock.ExpectQuery(".*EXACT_TABLE_BLOAT.*= 100;").WillReturnError(errors.New("canceling statement due to lock timeout"))
+	mock.ExpectClose()
+
+	ci := &connection.MockInfo{}
+	ci.On("NewConnection", "db1").Return(testConnection, nil)
+
+	store := persist.NewInMemoryStore()
+	PopulateExactBloatMetrics(dbList, 1024, 1, testIntegration, ci, store)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+	for _, entity := range testIntegration.Entities {
+		assert.Empty(t, entity.Metrics)
+	}
+
+	// the attempt is recorded, so the table goes to the back of the rotation instead of being retried first
+	var oid int64
+	_, err := store.Get("exactBloat:db1.schema1.table1", &oid)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), oid)
+}
+
+func TestPopulateSlruMetrics(t *testing.T) {
+	testIntegration, _ := integration.New("test", "test")
+	instanceEntity, _ := testIntegration.Entity("testInstance", "pg-instance")
+
+	testConnection, mock := connection.CreateMockSQL(t)
+	mock.ExpectQuery(".*SLRU_130.*").
+		WillReturnRows(sqlmock.NewRows([]string{
+			"name", "blks_zeroed", "blks_hit", "blks_read", "blks_written", "blks_exists", "flushes", "truncates",
+		}).AddRow("Subtrans", 1, 2, 3, 4, 5, 6, 7).
+			AddRow("MultiXactOffset", 1, 2, 3, 4, 5, 6, 7))
+
+	version := semver.MustParse("13.0.0")
+	populateSlruMetrics(instanceEntity, &version, testConnection)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Len(t, instanceEntity.Metrics, 2)
+	assert.Equal(t, map[string]interface{}{
+		"slru.name":                   "Subtrans",
+		"slru.blocksZeroedPerSecond":  float64(0),
+		"slru.blocksHitPerSecond":     float64(0),
+		"slru.blocksReadPerSecond":    float64(0),
+		"slru.blocksWrittenPerSecond": float64(0),
+		"slru.blocksExistsPerSecond":  float64(0),
+		"slru.flushesPerSecond":       float64(0),
+		"slru.truncatesPerSecond":     float64(0),
+		"displayName":                 "testInstance",
+		"entityName":                  "pg-instance:testInstance",
+		"event_type":                  "PostgresqlSlruSample",
+	}, instanceEntity.Metrics[0].Metrics)
+	assert.Equal(t, "MultiXactOffset", instanceEntity.Metrics[1].Metrics["slru.name"])
+}
+
+func TestPopulateIOMetrics(t *testing.T) {
+	testIntegration, _ := integration.New("test", "test")
+	instanceEntity, _ := testIntegration.Entity("testInstance", "pg-instance")
+
+	testConnection, mock := connection.CreateMockSQL(t)
+	mock.ExpectQuery(".*IO_OVER16.*").
+		WillReturnRows(sqlmock.NewRows([]string{
+			"backend_type", "object", "context", "reads", "writes", "writebacks", "extends", "hits", "evictions", "reuses", "fsyncs",
+			"read_time", "write_time", "writeback_time", "extend_time", "fsync_time",
+		}).AddRow("client backend", "relation", "normal", 10, 20, 0, 5, 100, 3, nil, 0, nil, nil, nil, nil, nil).
+			AddRow("checkpointer", "relation", "normal", nil, 40, 40, nil, nil, nil, nil, 2, nil, nil, nil, nil, nil))
+
+	version := semver.MustParse("16.2.0")
+	PopulateIOMetrics(instanceEntity, &version, testConnection)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Len(t, instanceEntity.Metrics, 2)
+	assert.Equal(t, map[string]interface{}{
+		"io.backendType":         "client backend",
+		"io.object":              "relation",
+		"io.context":             "normal",
+		"io.readsPerSecond":      float64(0),
+		"io.writesPerSecond":     float64(0),
+		"io.writebacksPerSecond": float64(0),
+		"io.extendsPerSecond":    float64(0),
+		"io.hitsPerSecond":       float64(0),
+		"io.evictionsPerSecond":  float64(0),
+		"io.fsyncsPerSecond":     float64(0),
+		"displayName":            "testInstance",
+		"entityName":             "pg-instance:testInstance",
+		"event_type":             "PostgresqlIOSample",
+	}, instanceEntity.Metrics[0].Metrics)
+	assert.Equal(t, "checkpointer", instanceEntity.Metrics[1].Metrics["io.backendType"])
+	assert.NotContains(t, instanceEntity.Metrics[1].Metrics, "io.readsPerSecond")
+}
+
+func TestPopulateTablespaceMetrics(t *testing.T) {
+	testIntegration, _ := integration.New("test", "test")
+
+	dbList := collection.DatabaseList{
+		"db1": collection.SchemaList{},
+		"db2": collection.SchemaList{},
+	}
+
+	testConnection, mock := connection.CreateMockSQL(t)
+	mock.ExpectQuery(".*TABLESPACES_OVER92.*").
+		WillReturnRows(sqlmock.NewRows([]string{
+			"tablespace_name", "location", "owner", "databases", "size", "seq_page_cost", "random_page_cost",
+		}).AddRow("pg_default", nil, "postgres", "db1, postgres", 1000, 1.0, 4.0).
+			AddRow("fast", "/mnt/ssd", "admin", nil, nil, 1.0, 1.1))
+	mock.ExpectQuery(".*TABLESPACE_RELATIONS.*").
+		WillReturnRows(sqlmock.NewRows([]string{"tablespace_name", "relation_name", "size"}).
+			AddRow("pg_default", "db.public.big", 500).
+			AddRow("fast", "db.public.hot", 300))
+	mock.ExpectQuery(".*TABLESPACE_RELATIONS.*").
+		WillReturnRows(sqlmock.NewRows([]string{"tablespace_name", "relation_name", "size"}).
+			AddRow("pg_default", "db.public.bigger", 600))
+
+	ci := &connection.MockInfo{}
+	ci.On("NewConnection", tmock.Anything).Return(testConnection, nil)
+
+	version := semver.MustParse("16.0.0")
+	PopulateTablespaceMetrics(dbList, &version, testIntegration, testConnection, ci)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	host := integration.NewIDAttribute("host", "testhost")
+	port := integration.NewIDAttribute("port", "1234")
+	defaultEntity, err := testIntegration.Entity("pg_default", "pg-tablespace", host, port)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"tablespace.owner":            "postgres",
+		"tablespace.databases":        "db1, postgres",
+		"tablespace.largestRelations": "db.public.bigger, db.public.big",
+		"tablespace.sizeInBytes":      float64(1000),
+		"tablespace.seqPageCost":      float64(1),
+		"tablespace.randomPageCost":   float64(4),
+		"displayName":                 "pg_default",
+		"entityName":                  "tablespace:pg_default",
+		"event_type":                  "PostgresqlTablespaceSample",
+	}, defaultEntity.Metrics[0].Metrics)
+
+	fastEntity, err := testIntegration.Entity("fast", "pg-tablespace", host, port)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"tablespace.location":         "/mnt/ssd",
+		"tablespace.owner":            "admin",
+		"tablespace.largestRelations": "db.public.hot",
+		"tablespace.seqPageCost":      float64(1),
+		"tablespace.randomPageCost":   1.1,
+		"displayName":                 "fast",
+		"entityName":                  "tablespace:fast",
+		"event_type":                  "PostgresqlTablespaceSample",
+	}, fastEntity.Metrics[0].Metrics)
+}
+
+func TestPopulateBufferCacheMetrics(t *testing.T) {
+	testIntegration, _ := integration.New("test", "test")
+	instanceEntity, _ := testIntegration.Entity("testInstance", "pg-instance")
+
+	dbList := collection.DatabaseList{
+		"db1": collection.SchemaList{
+			"schema1": collection.TableList{
+				"table1": []string{"index1"},
+			},
+		},
+	}
+
+	usageColumns := []string{"usage_count_0", "usage_count_1", "usage_count_2", "usage_count_3", "usage_count_4", "usage_count_5"}
+	testConnection, mock := connection.CreateMockSQL(t)
+	mock.ExpectQuery(".*EXTENSIONS_LIST.*").
+		WillReturnRows(sqlmock.NewRows([]string{"schema", "extension"}).AddRow("public", "pg_buffercache"))
+	mock.ExpectQuery(".*BUFFERCACHE_SUMMARY.*").
+		WillReturnRows(sqlmock.NewRows(append([]string{"database", "buffers", "shared_buffers_percent", "dirty_buffers"}, usageColumns...)).
+			AddRow("db1", 600, 37.5, 10, 100, 100, 100, 100, 100, 100).
+			AddRow("(unused)", 1000, 62.5, 0, nil, nil, nil, nil, nil, nil))
+	mock.ExpectQuery(".*EXTENSIONS_LIST.*").
+		WillReturnRows(sqlmock.NewRows([]string{"schema", "extension"}).AddRow("public", "pg_buffercache"))
+	mock.ExpectQuery(".*BUFFERCACHE_TABLES.*LIMIT 20;").
+		WillReturnRows(sqlmock.NewRows(append([]string{"database", "schema_name", "table_name", "buffers", "shared_buffers_percent", "dirty_buffers"}, usageColumns...)).
+			AddRow("db1", "schema1", "table1", 400, 25.0, 8, 0, 50, 50, 100, 100, 100))
+	mock.ExpectQuery(".*BUFFERCACHE_INDEXES.*LIMIT 20;").
+		WillReturnRows(sqlmock.NewRows(append([]string{"database", "schema_name", "table_name", "index_name", "buffers", "shared_buffers_percent", "dirty_buffers"}, usageColumns...)).
+			AddRow("db1", "schema1", "table1", "index1", 160, 10.0, 0, 0, 0, 0, 0, 0, 160))
+
+	ci := &connection.MockInfo{}
+	ci.On("NewConnection", "postgres").Return(testConnection, nil)
+	ci.On("NewConnection", "db1").Return(testConnection, nil)
+
+	store := persist.NewInMemoryStore()
+	PopulateBufferCacheMetrics(dbList, 15, 20, testIntegration, instanceEntity, ci, store)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	assert.Len(t, instanceEntity.Metrics, 2)
+	assert.Equal(t, map[string]interface{}{
+		"database":                         "db1",
+		"bufferCache.buffers":              float64(600),
+		"bufferCache.sharedBuffersPercent": 37.5,
+		"bufferCache.dirtyBuffers":         float64(10),
+		"bufferCache.usageCount0Buffers":   float64(100),
+		"bufferCache.usageCount1Buffers":   float64(100),
+		"bufferCache.usageCount2Buffers":   float64(100),
+		"bufferCache.usageCount3Buffers":   float64(100),
+		"bufferCache.usageCount4Buffers":   float64(100),
+		"bufferCache.usageCount5Buffers":   float64(100),
+		"displayName":                      "testInstance",
+		"entityName":                       "pg-instance:testInstance",
+		"event_type":                       "PostgresqlBufferCacheSample",
+	}, instanceEntity.Metrics[0].Metrics)
+
+	host := integration.NewIDAttribute("host", "testhost")
+	port := integration.NewIDAttribute("port", "1234")
+	database := integration.NewIDAttribute("pg-database", "db1")
+	schema := integration.NewIDAttribute("pg-schema", "schema1")
+	tableEntity, err := testIntegration.Entity("table1", "pg-table", host, port, database, schema)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"bufferCache.buffers":              float64(400),
+		"bufferCache.sharedBuffersPercent": float64(25),
+		"bufferCache.dirtyBuffers":         float64(8),
+		"bufferCache.usageCount0Buffers":   float64(0),
+		"bufferCache.usageCount1Buffers":   float64(50),
+		"bufferCache.usageCount2Buffers":   float64(50),
+		"bufferCache.usageCount3Buffers":   float64(100),
+		"bufferCache.usageCount4Buffers":   float64(100),
+		"bufferCache.usageCount5Buffers":   float64(100),
+		"database":                         "db1",
+		"schema":                           "schema1",
+		"displayName":                      "table1",
+		"entityName":                       "table:table1",
+		"event_type":                       "PostgresqlBufferCacheSample",
+	}, tableEntity.Metrics[0].Metrics)
+
+	indexEntity, err := testIntegration.Entity("index1", "pg-index", host, port, database, schema, integration.NewIDAttribute("pg-table", "table1"))
+	assert.Nil(t, err)
+	assert.Equal(t, float64(160), indexEntity.Metrics[0].Metrics["bufferCache.usageCount5Buffers"])
+	assert.Equal(t, "PostgresqlBufferCacheSample", indexEntity.Metrics[0].Metrics["event_type"])
+
+	// A second run within the interval collects nothing
+	PopulateBufferCacheMetrics(dbList, 15, 20, testIntegration, instanceEntity, ci, store)
+	assert.Len(t, instanceEntity.Metrics, 2)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPopulateBufferCacheMetrics_NothingCollected(t *testing.T) {
+	testIntegration, _ := integration.New("test", "test")
+	instanceEntity, _ := testIntegration.Entity("testInstance", "pg-instance")
+
+	dbList := collection.DatabaseList{
+		"db1": collection.SchemaList{
+			"schema1": collection.TableList{
+				"table1": []string{},
+			},
+		},
+	}
+
+	testConnection, mock := connection.CreateMockSQL(t)
+	mock.ExpectQuery(".*EXTENSIONS_LIST.*").
+		WillReturnRows(sqlmock.NewRows([]string{"schema", "extension"}).AddRow("public", "pg_buffercache"))
+	mock.ExpectQuery(".*BUFFERCACHE_SUMMARY.*").WillReturnError(errors.New("summary failed"))
+	mock.ExpectQuery(".*EXTENSIONS_LIST.*").
+		WillReturnRows(sqlmock.NewRows([]string{"schema", "extension"}).AddRow("public", "pg_buffercache"))
+	mock.ExpectQuery(".*BUFFERCACHE_TABLES.*").WillReturnError(errors.New("tables failed"))
+
+	ci := &connection.MockInfo{}
+	ci.On("NewConnection", "postgres").Return(testConnection, nil)
+	ci.On("NewConnection", "db1").Return(testConnection, nil)
+
+	store := persist.NewInMemoryStore()
+	PopulateBufferCacheMetrics(dbList, 15, 20, testIntegration, instanceEntity, ci, store)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Empty(t, instanceEntity.Metrics)
+
+	// nothing was collected, so the next run doesn't wait for the interval
+	var lastCollection int64
+	_, err := store.Get(bufferCacheStoreKey, &lastCollection)
+	assert.ErrorIs(t, err, persist.ErrNotFound)
+}
+
+func TestPopulateSequenceMetricsForDatabase(t *testing.T) {
+	testIntegration, _ := integration.New("test", "test")
+
+	schemaList := collection.SchemaList{
+		"schema1": collection.TableList{
+			"table1": []string{},
+		},
+	}
+
+	testConnection, mock := connection.CreateMockSQL(t)
+	mock.ExpectQuery(".*SEQUENCES_OVER10.*").
+		WillReturnRows(sqlmock.NewRows([]string{
+			"database", "schema_name", "table_name", "sequence_schema", "sequence_name", "column_name", "data_type", "column_type",
+			"last_value", "min_value", "max_value", "increment", "cycle", "percent_used", "limited_by_column_type",
+		}).AddRow("db1", "schema1", "table1", "schema1", "table1_id_seq", "id", "bigint", "integer",
+			1073741824, 1, int64(9223372036854775807), 1, false, 50.0, true).
+			AddRow("db1", "schema1", nil, "schema1", "free_seq", nil, "integer", nil,
+				nil, 1, 2147483647, 1, true, nil, false))
+
+	ci := &connection.MockInfo{}
+	version := semver.MustParse("10.0.0")
+	populateSequenceMetricsForDatabase(schemaList, &version, testConnection, testIntegration, ci)
+
+	expectedTable := map[string]interface{}{
+		"sequence.schema":              "schema1",
+		"sequence.name":                "table1_id_seq",
+		"sequence.column":              "id",
+		"sequence.dataType":            "bigint",
+		"sequence.columnType":          "integer",
+		"sequence.lastValue":           float64(1073741824),
+		"sequence.minValue":            float64(1),
+		"sequence.maxValue":            float64(9223372036854775807),
+		"sequence.increment":           float64(1),
+		"sequence.cycle":               float64(0),
+		"sequence.percentUsed":         float64(50),
+		"sequence.limitedByColumnType": float64(1),
+		"database":                     "db1",
+		"schema":                       "schema1",
+		"displayName":                  "table1",
+		"entityName":                   "table:table1",
+		"event_type":                   "PostgresqlSequenceSample",
+	}
+	expectedDatabase := map[string]interface{}{
+		"sequence.schema":              "schema1",
+		"sequence.name":                "free_seq",
+		"sequence.dataType":            "integer",
+		"sequence.minValue":            float64(1),
+		"sequence.maxValue":            float64(2147483647),
+		"sequence.increment":           float64(1),
+		"sequence.cycle":               float64(1),
+		"sequence.limitedByColumnType": float64(0),
+		"displayName":                  "db1",
+		"entityName":                   "database:db1",
+		"event_type":                   "PostgresqlSequenceSample",
+	}
+
+	host := integration.NewIDAttribute("host", "testhost")
+	port := integration.NewIDAttribute("port", "1234")
+	tableEntity, err := testIntegration.Entity("table1", "pg-table", host, port,
+		integration.NewIDAttribute("pg-database", "db1"), integration.NewIDAttribute("pg-schema", "schema1"))
+	assert.Nil(t, err)
+	dbEntity, err := testIntegration.Entity("db1", "pg-database", host, port)
+	assert.Nil(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, expectedTable, tableEntity.Metrics[0].Metrics)
+	assert.Equal(t, expectedDatabase, dbEntity.Metrics[0].Metrics)
+}
+
+func TestPopulateFunctionMetricsForDatabase(t *testing.T) {
+	testIntegration, _ := integration.New("test", "test")
+
+	testConnection, mock := connection.CreateMockSQL(t)
+	functionRows := sqlmock.NewRows([]string{
+		"database",
+		"schema_name",
+		"function_name",
+		"calls",
+		"total_time",
+		"self_time",
+		"average_time",
+	}).AddRow("db1", "schema1", "my_function(integer)", 4, 10.0, 6.0, 2.5).
+		AddRow("db1", "schema1", "never_called()", 0, 0.0, 0.0, nil)
+
+	mock.ExpectQuery(".*FUNCTIONTOPQUERY.*").
+		WillReturnRows(functionRows)
+
+	ci := &connection.MockInfo{}
+	populateFunctionMetricsForDatabase(generateFunctionDefinitions(nil, 20), testConnection, testIntegration, ci)
+
+	expected := map[string]interface{}{
+		"database":                "db1",
+		"schema":                  "schema1",
+		"displayName":             "my_function(integer)",
+		"entityName":              "function:my_function(integer)",
+		"event_type":              "PostgresqlFunctionSample",
+		"function.callsPerSecond": float64(0),
+		"function.totalTimeInMillisecondsPerSecond": float64(0),
+		"function.selfTimeInMillisecondsPerSecond":  float64(0),
+		"function.averageDurationInMilliseconds":    2.5,
+	}
+
+	id1 := integration.NewIDAttribute("pg-database", "db1")
+	id2 := integration.NewIDAttribute("pg-schema", "schema1")
+	id3 := integration.NewIDAttribute("host", "testhost")
+	id4 := integration.NewIDAttribute("port", "1234")
+	functionEntity, err := testIntegration.Entity("my_function(integer)", "pg-function", id1, id2, id3, id4)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, functionEntity.Metrics[0].Metrics)
+
+	uncalledEntity, err := testIntegration.Entity("never_called()", "pg-function", id1, id2, id3, id4)
+	assert.Nil(t, err)
+	assert.NotContains(t, uncalledEntity.Metrics[0].Metrics, "function.averageDurationInMilliseconds")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetHitRatio(t *testing.T) {
+	testIntegration, _ := integration.New("test", "test")
+	testEntity, _ := testIntegration.Entity("testTable", "pg-table")
+
+	metricSet := testEntity.NewMetricSet("PostgresqlTableSample")
+	assert.NoError(t, metricSet.SetMetric("table.heapBlocksHitPerSecond", 90.0, metric.GAUGE))
+	assert.NoError(t, metricSet.SetMetric("table.heapBlocksReadPerSecond", 10.0, metric.GAUGE))
+	assert.NoError(t, metricSet.SetMetric("table.indexBlocksHitPerSecond", 0.0, metric.GAUGE))
+	assert.NoError(t, metricSet.SetMetric("table.indexBlocksReadPerSecond", 0.0, metric.GAUGE))
+
+	setHitRatio(metricSet, "table.heapBlocksHitRatio", "table.heapBlocksHitPerSecond", "table.heapBlocksReadPerSecond")
+	setHitRatio(metricSet, "table.indexBlocksHitRatio", "table.indexBlocksHitPerSecond", "table.indexBlocksReadPerSecond")
+	setHitRatio(metricSet, "table.toastBlocksHitRatio", "table.toastBlocksHitPerSecond", "table.toastBlocksReadPerSecond")
+
+	assert.Equal(t, float64(90), metricSet.Metrics["table.heapBlocksHitRatio"])
+	assert.NotContains(t, metricSet.Metrics, "table.indexBlocksHitRatio")
+	assert.NotContains(t, metricSet.Metrics, "table.toastBlocksHitRatio")
+}
+
 func TestPopulatePgBouncerMetrics(t *testing.T) {
 
 	pgbouncerPriorTo23StatsRows := func() *sqlmock.Rows {
@@ -470,10 +1369,10 @@ func TestPopulatePgBouncerMetrics(t *testing.T) {
 			"pgbouncer.stats.avgBytesIn":                                      float64(11),
 			"pgbouncer.stats.avgBytesOut":                                     float64(12),
 			"pgbouncer.stats.avgQueryDurationInMilliseconds":                  float64(13),
-			"displayName": "testDB",
-			"entityName":  "pgbouncer:testDB",
+			"displayName": "testhost:1234",
+			"entityName":  "pgbouncer-instance:testhost:1234",
 			"event_type":  "PgBouncerSample",
-			"host":        "testhost",
+			"database":    "testDB",
 		}
 	}
 
@@ -510,12 +1409,14 @@ func TestPopulatePgBouncerMetrics(t *testing.T) {
 				"pgbouncer.pools.serverConnectionsUsed":    float64(5),
 				"pgbouncer.pools.serverConnectionsTested":  float64(6),
 				"pgbouncer.pools.serverConnectionsLogin":   float64(7),
-				"pgbouncer.pools.maxwaitInMilliseconds":    float64(8),
+				"pgbouncer.pools.maxwaitInMilliseconds":    8000.009,
 				"pgbouncer.pools.user":                     "testUser",
-				"displayName":                              "testDB",
-				"entityName":                               "pgbouncer:testDB",
-				"event_type":                               "PgBouncerSample",
-				"host":                                     "testhost",
+				"pgbouncer.pools.poolMode":                 "testMode",
+				"user":                                     "testUser",
+				"displayName":                              "testhost:1234",
+				"entityName":                               "pgbouncer-instance:testhost:1234",
+				"event_type":                               "PgBouncerPoolSample",
+				"database":                                 "testDB",
 			},
 		},
 		{
@@ -545,13 +1446,15 @@ func TestPopulatePgBouncerMetrics(t *testing.T) {
 				"pgbouncer.pools.serverConnectionsUsed":      float64(5),
 				"pgbouncer.pools.serverConnectionsTested":    float64(6),
 				"pgbouncer.pools.serverConnectionsLogin":     float64(7),
-				"pgbouncer.pools.maxwaitInMilliseconds":      float64(8),
-				"displayName":                                "testDB",
-				"entityName":                                 "pgbouncer:testDB",
-				"event_type":                                 "PgBouncerSample",
-				"host":                                       "testhost",
+				"pgbouncer.pools.maxwaitInMilliseconds":      8000.009,
+				"displayName":                                "testhost:1234",
+				"entityName":                                 "pgbouncer-instance:testhost:1234",
+				"event_type":                                 "PgBouncerPoolSample",
+				"database":                                   "testDB",
 				"pgbouncer.pools.clientConnectionsCancelReq": float64(10),
 				"pgbouncer.pools.user":                       "testUser",
+				"pgbouncer.pools.poolMode":                   "testMode",
+				"user":                                       "testUser",
 			},
 		},
 		{
@@ -584,16 +1487,18 @@ func TestPopulatePgBouncerMetrics(t *testing.T) {
 				"pgbouncer.pools.serverConnectionsUsed":             float64(5),
 				"pgbouncer.pools.serverConnectionsTested":           float64(6),
 				"pgbouncer.pools.serverConnectionsLogin":            float64(7),
-				"pgbouncer.pools.maxwaitInMilliseconds":             float64(8),
-				"displayName":                                       "testDB",
-				"entityName":                                        "pgbouncer:testDB",
-				"event_type":                                        "PgBouncerSample",
-				"host":                                              "testhost",
+				"pgbouncer.pools.maxwaitInMilliseconds":             8000.009,
+				"displayName":                                       "testhost:1234",
+				"entityName":                                        "pgbouncer-instance:testhost:1234",
+				"event_type":                                        "PgBouncerPoolSample",
+				"database":                                          "testDB",
 				"pgbouncer.pools.clientConnectionsWaitingCancelReq": float64(10),
 				"pgbouncer.pools.clientConnectionsActiveCancelReq":  float64(11),
 				"pgbouncer.pools.serverConnectionsActiveCancel":     float64(12),
 				"pgbouncer.pools.serverConnectionsBeingCancel":      float64(13),
 				"pgbouncer.pools.user":                              "testUser",
+				"pgbouncer.pools.poolMode":                          "testMode",
+				"user":                                              "testUser",
 			},
 		},
 		{
@@ -643,16 +1548,18 @@ func TestPopulatePgBouncerMetrics(t *testing.T) {
 				"pgbouncer.pools.serverConnectionsUsed":             float64(5),
 				"pgbouncer.pools.serverConnectionsTested":           float64(6),
 				"pgbouncer.pools.serverConnectionsLogin":            float64(7),
-				"pgbouncer.pools.maxwaitInMilliseconds":             float64(8),
-				"displayName":                                       "testDB",
-				"entityName":                                        "pgbouncer:testDB",
-				"event_type":                                        "PgBouncerSample",
-				"host":                                              "testhost",
+				"pgbouncer.pools.maxwaitInMilliseconds":             8000.009,
+				"displayName":                                       "testhost:1234",
+				"entityName":                                        "pgbouncer-instance:testhost:1234",
+				"event_type":                                        "PgBouncerPoolSample",
+				"database":                                          "testDB",
 				"pgbouncer.pools.clientConnectionsWaitingCancelReq": float64(10),
 				"pgbouncer.pools.clientConnectionsActiveCancelReq":  float64(11),
 				"pgbouncer.pools.serverConnectionsActiveCancel":     float64(12),
 				"pgbouncer.pools.serverConnectionsBeingCancel":      float64(13),
 				"pgbouncer.pools.user":                              "testUser",
+				"pgbouncer.pools.poolMode":                          "testMode",
+				"user":                                              "testUser",
 			},
 			expectedStats: map[string]interface{}{
 				"pgbouncer.stats.transactionsPerSecond":                           float64(0),
@@ -669,10 +1576,10 @@ func TestPopulatePgBouncerMetrics(t *testing.T) {
 				"pgbouncer.stats.avgQueryDurationInMilliseconds":                  float64(13),
 				"pgbouncer.stats.totalServerAssignmentCount":                      float64(15),
 				"pgbouncer.stats.avgServerAssignmentCount":                        float64(16),
-				"displayName": "testDB",
-				"entityName":  "pgbouncer:testDB",
+				"displayName": "testhost:1234",
+				"entityName":  "pgbouncer-instance:testhost:1234",
 				"event_type":  "PgBouncerSample",
-				"host":        "testhost",
+				"database":    "testDB",
 			},
 		},
 	}
@@ -694,9 +1601,7 @@ func TestPopulatePgBouncerMetrics(t *testing.T) {
 			ci := &connection.MockInfo{}
 			PopulatePgBouncerMetrics(testIntegration, testConnection, ci)
 
-			id3 := integration.NewIDAttribute("host", "testhost")
-			id4 := integration.NewIDAttribute("port", "1234")
-			pbEntity, err := testIntegration.Entity("testDB", "pgbouncer", id3, id4)
+			pbEntity, err := testIntegration.Entity("testhost:1234", "pgbouncer-instance")
 			assert.Nil(t, err)
 			assert.Equal(t, len(pbEntity.Metrics), 2)
 			assert.Equal(t, testCase.expectedStats, pbEntity.Metrics[0].Metrics)
@@ -706,6 +1611,154 @@ func TestPopulatePgBouncerMetrics(t *testing.T) {
 
 }
 
+func TestPopulatePgBouncerMetrics_PoolPerUser(t *testing.T) {
+	testIntegration, _ := integration.New("test", "test")
+
+	testConnection, mock := connection.CreateMockSQL(t)
+
+	mock.ExpectQuery("SHOW STATS;").WillReturnError(errors.New("stats failed"))
+	mock.ExpectQuery("SHOW POOLS;").
+		WillReturnRows(sqlmock.NewRows([]string{"database", "user", "cl_active", "cl_waiting", "maxwait", "maxwait_us", "pool_mode"}).
+			AddRow("testDB", "app", 1, 0, 0, 0, "transaction").
+			AddRow("testDB", "reporting", 0, 3, 0, 250, "session"))
+
+	ci := &connection.MockInfo{}
+	PopulatePgBouncerMetrics(testIntegration, testConnection, ci)
+
+	instanceEntity, err := testIntegration.Entity("testhost:1234", "pgbouncer-instance")
+	assert.NoError(t, err)
+	assert.Len(t, instanceEntity.Metrics, 2)
+
+	for i, expected := range []map[string]interface{}{
+		{
+			"user":                     "app",
+			"pgbouncer.pools.user":     "app",
+			"pgbouncer.pools.poolMode": "transaction",
+			"pgbouncer.pools.clientConnectionsActive":  float64(1),
+			"pgbouncer.pools.clientConnectionsWaiting": float64(0),
+			"pgbouncer.pools.maxwaitInMilliseconds":    float64(0),
+		},
+		{
+			"user":                     "reporting",
+			"pgbouncer.pools.user":     "reporting",
+			"pgbouncer.pools.poolMode": "session",
+			"pgbouncer.pools.clientConnectionsActive":  float64(0),
+			"pgbouncer.pools.clientConnectionsWaiting": float64(3),
+			"pgbouncer.pools.maxwaitInMilliseconds":    0.25,
+		},
+	} {
+		expected["event_type"] = "PgBouncerPoolSample"
+		expected["database"] = "testDB"
+		expected["displayName"] = "testhost:1234"
+		expected["entityName"] = "pgbouncer-instance:testhost:1234"
+		assert.Equal(t, expected, instanceEntity.Metrics[i].Metrics)
+	}
+}
+
+func TestPopulatePgBouncerMetrics_InstanceViews(t *testing.T) {
+	testIntegration, _ := integration.New("test", "test")
+
+	testConnection, mock := connection.CreateMockSQL(t)
+
+	mock.ExpectQuery("SHOW STATS;").WillReturnError(errors.New("stats failed"))
+	mock.ExpectQuery("SHOW POOLS;").WillReturnError(errors.New("pools failed"))
+	mock.ExpectQuery("SHOW DATABASES;").
+		WillReturnRows(sqlmock.NewRows([]string{
+			"name", "host", "port", "database", "force_user", "pool_size", "min_pool_size", "reserve_pool",
+			"pool_mode", "max_connections", "current_connections", "paused", "disabled",
+		}).AddRow("testDB", "localhost", 5432, "postgres", nil, 20, 0, 5, "transaction", 100, 7, 0, 1))
+	mock.ExpectQuery("SHOW LISTS;").
+		WillReturnRows(sqlmock.NewRows([]string{"list", "items"}).
+			AddRow("databases", 2).
+			AddRow("used_clients", 4))
+	mock.ExpectQuery("SHOW CLIENTS;").
+		WillReturnRows(sqlmock.NewRows([]string{"type", "user", "database", "state", "application_name", "wait", "wait_us"}).
+			AddRow("C", "app", "testDB", "active", "api", 0, 0).
+			AddRow("C", "app", "testDB", "active", "api", 0, 0).
+			AddRow("C", "app", "testDB", "waiting", "api", 1, 500).
+			AddRow("C", "app", "testDB", "waiting", "api", 0, 2500))
+	mock.ExpectQuery("SHOW MEM;").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "size", "used", "free", "memtotal"}).
+			AddRow("user_cache", 360, 3, 8, 3960))
+	mock.ExpectQuery("SHOW SERVERS;").
+		WillReturnRows(sqlmock.NewRows([]string{"type", "user", "database", "state", "application_name", "wait", "wait_us"}).
+			AddRow("S", "app", "testDB", "idle", "", 0, 0))
+
+	ci := &connection.MockInfo{}
+	PopulatePgBouncerMetrics(testIntegration, testConnection, ci)
+
+	instanceEntity, err := testIntegration.Entity("testhost:1234", "pgbouncer-instance")
+	assert.NoError(t, err)
+	assert.Len(t, instanceEntity.Metrics, 6)
+
+	commonAttributes := map[string]interface{}{
+		"displayName": "testhost:1234",
+		"entityName":  "pgbouncer-instance:testhost:1234",
+	}
+	withCommonAttributes := func(metrics map[string]interface{}) map[string]interface{} {
+		for k, v := range commonAttributes {
+			metrics[k] = v
+		}
+		return metrics
+	}
+
+	assert.Equal(t, withCommonAttributes(map[string]interface{}{
+		"event_type":                            "PgBouncerDatabaseSample",
+		"database":                              "testDB",
+		"pgbouncer.database.host":               "localhost",
+		"pgbouncer.database.serverDatabase":     "postgres",
+		"pgbouncer.database.poolMode":           "transaction",
+		"pgbouncer.database.poolSize":           float64(20),
+		"pgbouncer.database.minPoolSize":        float64(0),
+		"pgbouncer.database.reservePoolSize":    float64(5),
+		"pgbouncer.database.maxConnections":     float64(100),
+		"pgbouncer.database.currentConnections": float64(7),
+		"pgbouncer.database.paused":             float64(0),
+		"pgbouncer.database.disabled":           float64(1),
+	}), instanceEntity.Metrics[0].Metrics)
+	assert.Equal(t, withCommonAttributes(map[string]interface{}{
+		"event_type":                                 "PgBouncerInstanceSample",
+		"pgbouncer.lists.databases":                  float64(2),
+		"pgbouncer.lists.usedClients":                float64(4),
+		"pgbouncer.clients.oldestWaitInMilliseconds": 1000.5,
+	}), instanceEntity.Metrics[1].Metrics)
+	assert.Equal(t, withCommonAttributes(map[string]interface{}{
+		"event_type":                    "PgBouncerClientSample",
+		"database":                      "testDB",
+		"user":                          "app",
+		"application":                   "api",
+		"state":                         "active",
+		"pgbouncer.clients.connections": float64(2),
+	}), instanceEntity.Metrics[2].Metrics)
+	assert.Equal(t, withCommonAttributes(map[string]interface{}{
+		"event_type":                    "PgBouncerClientSample",
+		"database":                      "testDB",
+		"user":                          "app",
+		"application":                   "api",
+		"state":                         "waiting",
+		"pgbouncer.clients.connections": float64(2),
+		"pgbouncer.clients.maxWaitInMilliseconds": 1000.5,
+	}), instanceEntity.Metrics[3].Metrics)
+	assert.Equal(t, withCommonAttributes(map[string]interface{}{
+		"event_type":                    "PgBouncerMemorySample",
+		"pgbouncer.mem.cache":           "user_cache",
+		"pgbouncer.mem.itemSizeInBytes": float64(360),
+		"pgbouncer.mem.usedItems":       float64(3),
+		"pgbouncer.mem.freeItems":       float64(8),
+		"pgbouncer.mem.totalInBytes":    float64(3960),
+	}), instanceEntity.Metrics[4].Metrics)
+	assert.Equal(t, withCommonAttributes(map[string]interface{}{
+		"event_type":                    "PgBouncerServerSample",
+		"database":                      "testDB",
+		"user":                          "app",
+		"application":                   "",
+		"state":                         "idle",
+		"pgbouncer.servers.connections": float64(1),
+	}), instanceEntity.Metrics[5].Metrics)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestPopulateMetrics(t *testing.T) {
 	testIntegration, _ := integration.New("test", "test")
 
@@ -729,7 +1782,7 @@ func TestPopulateMetrics(t *testing.T) {
 
 	instance, _ := testIntegration.Entity("testInstance", "instance")
 
-	PopulateMetrics(ci, dbList, instance, testIntegration, true, true, true, "")
+	PopulateMetrics(ci, dbList, instance, testIntegration, true, true, "")
 }
 
 func TestPopulateCustomMetricsFromFile(t *testing.T) {
@@ -770,3 +1823,156 @@ queries:
 	assert.Equal(t, float64(0.064), metricSet["float_metric"])
 	assert.Equal(t, "test-string", metricSet["string_metric"])
 }
+
+func TestPopulatePgpoolMetrics(t *testing.T) {
+	testIntegration, _ := integration.New("test", "test")
+
+	testConnection, mock := connection.CreateMockSQL(t)
+
+	// Pgpool-II returns every column of its SHOW commands as text
+	mock.ExpectQuery("SHOW POOL_NODES;").
+		WillReturnRows(sqlmock.NewRows([]string{
+			"node_id", "hostname", "port", "status", "pg_status", "lb_weight", "role", "pg_role", "select_cnt",
+			"load_balance_node", "replication_delay", "replication_state", "replication_sync_state", "last_status_change",
+		}).
+			AddRow("0", "pg-0", "5432", "up", "up", "0.500000", "primary", "primary", "12", "false", "0", "", "", "2026-10-01 10:00:00").
+			AddRow("1", "pg-1", "5432", "down", "down", "0.500000", "standby", "unknown", "3", "true", "1.5 second", "streaming", "async", "2026-10-01 11:00:00"))
+	mock.ExpectQuery("SHOW POOL_HEALTH_CHECK_STATS;").
+		WillReturnRows(sqlmock.NewRows([]string{
+			"node_id", "hostname", "port", "status", "total_count", "success_count", "fail_count", "skip_count", "retry_count",
+			"max_retry_count", "average_duration", "max_duration", "last_health_check", "last_failed_health_check",
+		}).
+			AddRow("0", "pg-0", "5432", "up", "10", "10", "0", "0", "0", "0", "1.500000", "3", "2026-10-01 12:00:00", "").
+			AddRow("1", "pg-1", "5432", "down", "10", "7", "3", "0", "6", "2", "900.000000", "2000", "2026-10-01 12:00:00", "2026-10-01 12:00:00"))
+	mock.ExpectQuery("SHOW POOL_POOLS;").
+		WillReturnRows(sqlmock.NewRows([]string{"pool_pid", "pool_id", "backend_id", "database", "username", "pool_backendpid", "pool_connected"}).
+			AddRow("100", "0", "0", "app", "app", "2000", "1").
+			AddRow("100", "0", "1", "app", "app", "0", "0").
+			AddRow("101", "0", "0", "app", "app", "2001", "0"))
+	mock.ExpectQuery("SHOW POOL_PROCESSES;").
+		WillReturnRows(sqlmock.NewRows([]string{"pool_pid", "start_time", "database", "username", "pool_counter", "status"}).
+			AddRow("100", "2026-10-01 10:00:00", "app", "app", "1", "Idle").
+			AddRow("101", "2026-10-01 10:00:00", "", "", "", "Wait for connection"))
+
+	ci := &connection.MockInfo{}
+	PopulatePgpoolMetrics(testIntegration, testConnection, ci)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	instanceEntity, err := testIntegration.Entity("testhost:1234", "pgpool-instance")
+	assert.NoError(t, err)
+	assert.Len(t, instanceEntity.Metrics, 1)
+	assert.Equal(t, map[string]interface{}{
+		"event_type":                     "PgpoolInstanceSample",
+		"displayName":                    "testhost:1234",
+		"entityName":                     "pgpool-instance:testhost:1234",
+		"pgpool.nodes.total":             float64(2),
+		"pgpool.nodes.up":                float64(1),
+		"pgpool.nodes.down":              float64(1),
+		"pgpool.processes.total":         float64(2),
+		"pgpool.processes.withClient":    float64(1),
+		"pgpool.processes.withoutClient": float64(1),
+	}, instanceEntity.Metrics[0].Metrics)
+
+	pgpoolIDAttribute := integration.NewIDAttribute("pgpool", "testhost:1234")
+	primaryEntity, err := testIntegration.Entity("pg-0:5432", "pgpool-node", pgpoolIDAttribute)
+	assert.NoError(t, err)
+	assert.Len(t, primaryEntity.Metrics, 1)
+	assert.Equal(t, map[string]interface{}{
+		"event_type":                                   "PgpoolNodeSample",
+		"displayName":                                  "pg-0:5432",
+		"entityName":                                   "pgpool-node:pg-0:5432",
+		"pgpool":                                       "testhost:1234",
+		"pgpool.node.id":                               "0",
+		"pgpool.node.status":                           "up",
+		"pgpool.node.pgStatus":                         "up",
+		"pgpool.node.loadBalanceWeight":                0.5,
+		"pgpool.node.role":                             "primary",
+		"pgpool.node.pgRole":                           "primary",
+		"pgpool.node.selectsPerSecond":                 float64(0),
+		"pgpool.node.loadBalanceNode":                  float64(0),
+		"pgpool.node.replicationState":                 "",
+		"pgpool.node.replicationSyncState":             "",
+		"pgpool.node.lastStatusChange":                 "2026-10-01 10:00:00",
+		"pgpool.node.up":                               float64(1),
+		"pgpool.node.replicationDelayInBytes":          float64(0),
+		"pgpool.node.pooledConnections":                float64(2),
+		"pgpool.node.activeConnections":                float64(1),
+		"pgpool.healthCheck.checksPerSecond":           float64(0),
+		"pgpool.healthCheck.successesPerSecond":        float64(0),
+		"pgpool.healthCheck.failuresPerSecond":         float64(0),
+		"pgpool.healthCheck.skipsPerSecond":            float64(0),
+		"pgpool.healthCheck.retriesPerSecond":          float64(0),
+		"pgpool.healthCheck.maxRetries":                float64(0),
+		"pgpool.healthCheck.avgDurationInMilliseconds": 1.5,
+		"pgpool.healthCheck.maxDurationInMilliseconds": float64(3),
+		"pgpool.healthCheck.lastHealthCheck":           "2026-10-01 12:00:00",
+		"pgpool.healthCheck.lastFailedHealthCheck":     "",
+	}, primaryEntity.Metrics[0].Metrics)
+
+	standbyEntity, err := testIntegration.Entity("pg-1:5432", "pgpool-node", pgpoolIDAttribute)
+	assert.NoError(t, err)
+	assert.Len(t, standbyEntity.Metrics, 1)
+	standby := standbyEntity.Metrics[0].Metrics
+	assert.Equal(t, "standby", standby["pgpool.node.role"])
+	assert.Equal(t, float64(0), standby["pgpool.node.up"])
+	assert.Equal(t, float64(1), standby["pgpool.node.loadBalanceNode"])
+	assert.Equal(t, 1.5, standby["pgpool.node.replicationDelayInSeconds"])
+	assert.NotContains(t, standby, "pgpool.node.replicationDelayInBytes")
+	assert.Equal(t, float64(0), standby["pgpool.node.pooledConnections"])
+	assert.Equal(t, float64(900), standby["pgpool.healthCheck.avgDurationInMilliseconds"])
+}
+
+func TestPopulatePgpoolMetrics_BeforeHealthCheckStats(t *testing.T) {
+	testIntegration, _ := integration.New("test", "test")
+
+	testConnection, mock := connection.CreateMockSQL(t)
+
+	mock.ExpectQuery("SHOW POOL_NODES;").
+		WillReturnRows(sqlmock.NewRows([]string{"node_id", "hostname", "port", "status", "lb_weight", "role", "select_cnt", "load_balance_node", "replication_delay"}).
+			AddRow("0", "pg-0", "5432", "waiting", "1.000000", "primary", "0", "true", "0"))
+	mock.ExpectQuery("SHOW POOL_HEALTH_CHECK_STATS;").WillReturnError(errors.New(`unknown SHOW command "pool_health_check_stats"`))
+	mock.ExpectQuery("SHOW POOL_POOLS;").WillReturnError(errors.New("pools failed"))
+	mock.ExpectQuery("SHOW POOL_PROCESSES;").WillReturnError(errors.New("processes failed"))
+
+	ci := &connection.MockInfo{}
+	PopulatePgpoolMetrics(testIntegration, testConnection, ci)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	nodeEntity, err := testIntegration.Entity("pg-0:5432", "pgpool-node", integration.NewIDAttribute("pgpool", "testhost:1234"))
+	assert.NoError(t, err)
+	assert.Len(t, nodeEntity.Metrics, 1)
+	node := nodeEntity.Metrics[0].Metrics
+	assert.Equal(t, float64(1), node["pgpool.node.up"])
+	assert.Equal(t, float64(0), node["pgpool.node.pooledConnections"])
+	assert.NotContains(t, node, "pgpool.healthCheck.checksPerSecond")
+}
+
+func TestParsePgpoolReplicationDelay(t *testing.T) {
+	bytes, seconds, minutes, notANumber, empty := "1024", "0.250000 second", "3 minutes", "none", ""
+
+	testCases := []struct {
+		name             string
+		replicationDelay *string
+		expectedName     string
+		expectedDelay    float64
+		expectedOk       bool
+	}{
+		{"bytes", &bytes, "pgpool.node.replicationDelayInBytes", 1024, true},
+		{"seconds", &seconds, "pgpool.node.replicationDelayInSeconds", 0.25, true},
+		{"unknown unit", &minutes, "", 0, false},
+		{"not a number", &notANumber, "", 0, false},
+		{"empty", &empty, "", 0, false},
+		{"null", nil, "", 0, false},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			name, delay, ok := parsePgpoolReplicationDelay(testCase.replicationDelay)
+			assert.Equal(t, testCase.expectedName, name)
+			assert.Equal(t, testCase.expectedDelay, delay)
+			assert.Equal(t, testCase.expectedOk, ok)
+		})
+	}
+}
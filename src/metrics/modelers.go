@@ -136,3 +136,36 @@ func GetIndexName(dataModel interface{}) (string, error) {
 
 	return name, nil
 }
+
+// FunctionModeler represents something with a function field
+type FunctionModeler interface {
+	GetFunctionName() (string, error)
+}
+
+type functionBase struct {
+	Function *string `db:"function_name"`
+}
+
+// GetFunctionName returns the function signature
+func (d functionBase) GetFunctionName() (string, error) {
+	if d.Function == nil {
+		return "", errors.New("function name not returned")
+	}
+	return *d.Function, nil
+}
+
+// GetFunctionName returns the function signature
+func GetFunctionName(dataModel interface{}) (string, error) {
+	v := reflect.ValueOf(dataModel)
+	modeler, ok := v.Interface().(FunctionModeler)
+	if !ok {
+		return "", errors.New("data model does not implement FunctionModeler interface")
+	}
+
+	name, err := modeler.GetFunctionName()
+	if err != nil {
+		return "", err
+	}
+
+	return name, nil
+}
@@ -1,13 +1,5 @@
 package metrics
 
-func generatePgBouncerDefinitions() []*QueryDefinition {
-	queryDefinitions := make([]*QueryDefinition, 2)
-	queryDefinitions[0] = pgbouncerStatsDefinition
-	queryDefinitions[1] = pgbouncerPoolsDefinition
-
-	return queryDefinitions
-}
-
 var pgbouncerStatsDefinition = &QueryDefinition{
 	query: `SHOW STATS;`,
 
@@ -38,23 +30,104 @@ var pgbouncerStatsDefinition = &QueryDefinition{
 var pgbouncerPoolsDefinition = &QueryDefinition{
 	query: `SHOW POOLS;`,
 
-	dataModels: []struct {
-		databaseBase
-		User               *string `db:"user"                  metric_name:"pgbouncer.pools.user"                          source_type:"attribute"`
-		ClCancelReq        *int64  `db:"cl_cancel_req"         metric_name:"pgbouncer.pools.clientConnectionsCancelReq"        source_type:"gauge"` // removed in v1.18
-		ClActive           *int64  `db:"cl_active"             metric_name:"pgbouncer.pools.clientConnectionsActive"           source_type:"gauge"`
-		ClWaiting          *int64  `db:"cl_waiting"            metric_name:"pgbouncer.pools.clientConnectionsWaiting"          source_type:"gauge"`
-		ClWaitingCancelReq *int64  `db:"cl_waiting_cancel_req" metric_name:"pgbouncer.pools.clientConnectionsWaitingCancelReq" source_type:"gauge"` // added in v1.18
-		ClActiveCancelReq  *int64  `db:"cl_active_cancel_req"  metric_name:"pgbouncer.pools.clientConnectionsActiveCancelReq"  source_type:"gauge"` // added in v1.18
-		SvActiveCancel     *int64  `db:"sv_active_cancel"      metric_name:"pgbouncer.pools.serverConnectionsActiveCancel"     source_type:"gauge"` // added in v1.18
-		SvBeingCancel      *int64  `db:"sv_being_canceled"     metric_name:"pgbouncer.pools.serverConnectionsBeingCancel"      source_type:"gauge"` // added in v1.18
-		SvActive           *int64  `db:"sv_active"             metric_name:"pgbouncer.pools.serverConnectionsActive"           source_type:"gauge"`
-		SvIdle             *int64  `db:"sv_idle"               metric_name:"pgbouncer.pools.serverConnectionsIdle"             source_type:"gauge"`
-		SvUsed             *int64  `db:"sv_used"               metric_name:"pgbouncer.pools.serverConnectionsUsed"             source_type:"gauge"`
-		SvTested           *int64  `db:"sv_tested"             metric_name:"pgbouncer.pools.serverConnectionsTested"           source_type:"gauge"`
-		SvLogin            *int64  `db:"sv_login"              metric_name:"pgbouncer.pools.serverConnectionsLogin"            source_type:"gauge"`
-		MaxWait            *int64  `db:"maxwait"               metric_name:"pgbouncer.pools.maxwaitInMilliseconds"             source_type:"gauge"`
-		MaxWaitUs          *int64  `db:"maxwait_us"`
-		PoolMode           *string `db:"pool_mode"`
-	}{},
+	dataModels: []pgbouncerPool{},
+}
+
+// pgbouncerPool is a pool of SHOW POOLS, of which there is one per database and user. MaxWait is the wait
+// of the oldest waiting client in seconds and MaxWaitUs its microsecond part, which are reported together
+// as pgbouncer.pools.maxwaitInMilliseconds.
+type pgbouncerPool struct {
+	databaseBase
+	User               *string `db:"user"                  metric_name:"pgbouncer.pools.user"                          source_type:"attribute"`
+	ClCancelReq        *int64  `db:"cl_cancel_req"         metric_name:"pgbouncer.pools.clientConnectionsCancelReq"        source_type:"gauge"` // removed in v1.18
+	ClActive           *int64  `db:"cl_active"             metric_name:"pgbouncer.pools.clientConnectionsActive"           source_type:"gauge"`
+	ClWaiting          *int64  `db:"cl_waiting"            metric_name:"pgbouncer.pools.clientConnectionsWaiting"          source_type:"gauge"`
+	ClWaitingCancelReq *int64  `db:"cl_waiting_cancel_req" metric_name:"pgbouncer.pools.clientConnectionsWaitingCancelReq" source_type:"gauge"` // added in v1.18
+	ClActiveCancelReq  *int64  `db:"cl_active_cancel_req"  metric_name:"pgbouncer.pools.clientConnectionsActiveCancelReq"  source_type:"gauge"` // added in v1.18
+	SvActiveCancel     *int64  `db:"sv_active_cancel"      metric_name:"pgbouncer.pools.serverConnectionsActiveCancel"     source_type:"gauge"` // added in v1.18
+	SvBeingCancel      *int64  `db:"sv_being_canceled"     metric_name:"pgbouncer.pools.serverConnectionsBeingCancel"      source_type:"gauge"` // added in v1.18
+	SvActive           *int64  `db:"sv_active"             metric_name:"pgbouncer.pools.serverConnectionsActive"           source_type:"gauge"`
+	SvIdle             *int64  `db:"sv_idle"               metric_name:"pgbouncer.pools.serverConnectionsIdle"             source_type:"gauge"`
+	SvUsed             *int64  `db:"sv_used"               metric_name:"pgbouncer.pools.serverConnectionsUsed"             source_type:"gauge"`
+	SvTested           *int64  `db:"sv_tested"             metric_name:"pgbouncer.pools.serverConnectionsTested"           source_type:"gauge"`
+	SvLogin            *int64  `db:"sv_login"              metric_name:"pgbouncer.pools.serverConnectionsLogin"            source_type:"gauge"`
+	MaxWait            *int64  `db:"maxwait"`
+	MaxWaitUs          *int64  `db:"maxwait_us"`
+	PoolMode           *string `db:"pool_mode"             metric_name:"pgbouncer.pools.poolMode"                      source_type:"attribute"`
+}
+
+var pgbouncerDatabasesDefinition = &QueryDefinition{
+	query: `SHOW DATABASES;`,
+
+	dataModels: []pgbouncerDatabase{},
+}
+
+var pgbouncerListsDefinition = &QueryDefinition{
+	query: `SHOW LISTS;`,
+
+	dataModels: []pgbouncerList{},
+}
+
+var pgbouncerMemDefinition = &QueryDefinition{
+	query: `SHOW MEM;`,
+
+	dataModels: []pgbouncerMem{},
+}
+
+var pgbouncerClientsDefinition = &QueryDefinition{
+	query: `SHOW CLIENTS;`,
+
+	dataModels: []pgbouncerConnection{},
+}
+
+var pgbouncerServersDefinition = &QueryDefinition{
+	query: `SHOW SERVERS;`,
+
+	dataModels: []pgbouncerConnection{},
+}
+
+// pgbouncerDatabase is a database configured in PgBouncer. Name is the database clients connect to and
+// Database the one PgBouncer connects to on the server.
+type pgbouncerDatabase struct {
+	Name                     *string `db:"name"`
+	Host                     *string `db:"host"                       metric_name:"pgbouncer.database.host"                     source_type:"attribute"`
+	Database                 *string `db:"database"                   metric_name:"pgbouncer.database.serverDatabase"           source_type:"attribute"`
+	ForceUser                *string `db:"force_user"                 metric_name:"pgbouncer.database.forceUser"                source_type:"attribute"`
+	PoolMode                 *string `db:"pool_mode"                  metric_name:"pgbouncer.database.poolMode"                 source_type:"attribute"`
+	PoolSize                 *int64  `db:"pool_size"                  metric_name:"pgbouncer.database.poolSize"                 source_type:"gauge"`
+	MinPoolSize              *int64  `db:"min_pool_size"              metric_name:"pgbouncer.database.minPoolSize"              source_type:"gauge"`
+	ReservePool              *int64  `db:"reserve_pool"               metric_name:"pgbouncer.database.reservePoolSize"          source_type:"gauge"` // renamed reserve_pool_size in v1.24
+	ReservePoolSize          *int64  `db:"reserve_pool_size"          metric_name:"pgbouncer.database.reservePoolSize"          source_type:"gauge"` // added in v1.24
+	MaxConnections           *int64  `db:"max_connections"            metric_name:"pgbouncer.database.maxConnections"           source_type:"gauge"`
+	CurrentConnections       *int64  `db:"current_connections"        metric_name:"pgbouncer.database.currentConnections"       source_type:"gauge"`
+	MaxClientConnections     *int64  `db:"max_client_connections"     metric_name:"pgbouncer.database.maxClientConnections"     source_type:"gauge"` // added in v1.24
+	CurrentClientConnections *int64  `db:"current_client_connections" metric_name:"pgbouncer.database.currentClientConnections" source_type:"gauge"` // added in v1.24
+	Paused                   *int64  `db:"paused"                     metric_name:"pgbouncer.database.paused"                   source_type:"gauge"`
+	Disabled                 *int64  `db:"disabled"                   metric_name:"pgbouncer.database.disabled"                 source_type:"gauge"`
+}
+
+// pgbouncerList is the number of items of one of the internal lists of PgBouncer
+type pgbouncerList struct {
+	List  string `db:"list"`
+	Items int64  `db:"items"`
+}
+
+// pgbouncerMem is one of the internal memory caches of PgBouncer
+type pgbouncerMem struct {
+	Name     *string `db:"name"`
+	Size     *int64  `db:"size"     metric_name:"pgbouncer.mem.itemSizeInBytes" source_type:"gauge"`
+	Used     *int64  `db:"used"     metric_name:"pgbouncer.mem.usedItems"       source_type:"gauge"`
+	Free     *int64  `db:"free"     metric_name:"pgbouncer.mem.freeItems"       source_type:"gauge"`
+	MemTotal *int64  `db:"memtotal" metric_name:"pgbouncer.mem.totalInBytes"    source_type:"gauge"`
+}
+
+// pgbouncerConnection is a client or server connection. Wait is the time a waiting client has been waiting,
+// in seconds, and WaitUs its microsecond part.
+type pgbouncerConnection struct {
+	User            *string `db:"user"`
+	Database        *string `db:"database"`
+	State           *string `db:"state"`
+	ApplicationName *string `db:"application_name"`
+	Wait            *int64  `db:"wait"`
+	WaitUs          *int64  `db:"wait_us"`
 }
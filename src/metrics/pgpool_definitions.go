@@ -0,0 +1,80 @@
+package metrics
+
+// The SHOW commands of Pgpool-II return every column as text, and columns were added in most
+// releases, so they are read with QueryUnsafe into fields of the types their text can be scanned to.
+
+var pgpoolNodesDefinition = &QueryDefinition{
+	query: `SHOW POOL_NODES;`,
+
+	dataModels: []pgpoolNode{},
+}
+
+var pgpoolHealthCheckStatsDefinition = &QueryDefinition{
+	query: `SHOW POOL_HEALTH_CHECK_STATS;`,
+
+	dataModels: []pgpoolHealthCheckStats{},
+}
+
+var pgpoolProcessesDefinition = &QueryDefinition{
+	query: `SHOW POOL_PROCESSES;`,
+
+	dataModels: []pgpoolProcess{},
+}
+
+var pgpoolPoolsDefinition = &QueryDefinition{
+	query: `SHOW POOL_POOLS;`,
+
+	dataModels: []pgpoolPool{},
+}
+
+// pgpoolNode is a backend node of SHOW POOL_NODES. ReplicationDelay is in bytes, or in seconds followed by
+// its unit when delay_threshold_by_time is set, so it is parsed rather than scanned.
+type pgpoolNode struct {
+	NodeID               *string  `db:"node_id"`
+	Hostname             *string  `db:"hostname"`
+	Port                 *string  `db:"port"`
+	Status               *string  `db:"status"                 metric_name:"pgpool.node.status"               source_type:"attribute"`
+	PgStatus             *string  `db:"pg_status"              metric_name:"pgpool.node.pgStatus"             source_type:"attribute"` // added in v4.3
+	LbWeight             *float64 `db:"lb_weight"              metric_name:"pgpool.node.loadBalanceWeight"    source_type:"gauge"`
+	Role                 *string  `db:"role"                   metric_name:"pgpool.node.role"                 source_type:"attribute"`
+	PgRole               *string  `db:"pg_role"                metric_name:"pgpool.node.pgRole"               source_type:"attribute"` // added in v4.3
+	SelectCount          *int64   `db:"select_cnt"             metric_name:"pgpool.node.selectsPerSecond"     source_type:"rate"`
+	LoadBalanceNode      *bool    `db:"load_balance_node"      metric_name:"pgpool.node.loadBalanceNode"      source_type:"gauge"`
+	ReplicationDelay     *string  `db:"replication_delay"`
+	ReplicationState     *string  `db:"replication_state"      metric_name:"pgpool.node.replicationState"     source_type:"attribute"` // added in v4.1
+	ReplicationSyncState *string  `db:"replication_sync_state" metric_name:"pgpool.node.replicationSyncState" source_type:"attribute"` // added in v4.1
+	LastStatusChange     *string  `db:"last_status_change"     metric_name:"pgpool.node.lastStatusChange"     source_type:"attribute"` // added in v4.1
+}
+
+// pgpoolHealthCheckStats is the health check history of a backend node, added in v4.1. The durations
+// are in milliseconds.
+type pgpoolHealthCheckStats struct {
+	NodeID          *string  `db:"node_id"`
+	TotalCount      *int64   `db:"total_count"              metric_name:"pgpool.healthCheck.checksPerSecond"           source_type:"rate"`
+	SuccessCount    *int64   `db:"success_count"            metric_name:"pgpool.healthCheck.successesPerSecond"        source_type:"rate"`
+	FailCount       *int64   `db:"fail_count"               metric_name:"pgpool.healthCheck.failuresPerSecond"         source_type:"rate"`
+	SkipCount       *int64   `db:"skip_count"               metric_name:"pgpool.healthCheck.skipsPerSecond"            source_type:"rate"`
+	RetryCount      *int64   `db:"retry_count"              metric_name:"pgpool.healthCheck.retriesPerSecond"          source_type:"rate"`
+	MaxRetryCount   *int64   `db:"max_retry_count"          metric_name:"pgpool.healthCheck.maxRetries"                source_type:"gauge"`
+	AverageDuration *float64 `db:"average_duration"         metric_name:"pgpool.healthCheck.avgDurationInMilliseconds" source_type:"gauge"`
+	MaxDuration     *int64   `db:"max_duration"             metric_name:"pgpool.healthCheck.maxDurationInMilliseconds" source_type:"gauge"`
+	LastHealthCheck *string  `db:"last_health_check"        metric_name:"pgpool.healthCheck.lastHealthCheck"           source_type:"attribute"`
+	LastFailedCheck *string  `db:"last_failed_health_check" metric_name:"pgpool.healthCheck.lastFailedHealthCheck"     source_type:"attribute"`
+}
+
+// pgpoolProcess is a Pgpool-II child process of SHOW POOL_PROCESSES. Database is empty while the
+// process has no client connected.
+type pgpoolProcess struct {
+	PID      *string `db:"pool_pid"`
+	Database *string `db:"database"`
+}
+
+// pgpoolPool is a connection cache entry of a child process for a backend node of SHOW POOL_POOLS.
+// PoolConnected is 1 while the backend connection is in use by a client.
+type pgpoolPool struct {
+	PID           *string `db:"pool_pid"`
+	BackendID     *string `db:"backend_id"`
+	Database      *string `db:"database"`
+	BackendPID    *string `db:"pool_backendpid"`
+	PoolConnected *string `db:"pool_connected"`
+}
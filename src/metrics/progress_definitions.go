@@ -0,0 +1,201 @@
+package metrics
+
+import (
+	"github.com/blang/semver/v4"
+)
+
+// relationProgressDefinitions are the pg_stat_progress_* views whose operations target a relation.
+// They are run against each collected database and only return operations running on it.
+var relationProgressDefinitions = []VersionDefinition{
+	{
+		minVersion:       semver.MustParse("9.6.0"),
+		queryDefinitions: []*QueryDefinition{progressVacuumDefinition},
+	},
+	{
+		minVersion:       semver.MustParse("12.0.0"),
+		queryDefinitions: []*QueryDefinition{progressCreateIndexDefinition, progressClusterDefinition},
+	},
+	{
+		minVersion:       semver.MustParse("13.0.0"),
+		queryDefinitions: []*QueryDefinition{progressAnalyzeDefinition},
+	},
+	{
+		minVersion:       semver.MustParse("14.0.0"),
+		queryDefinitions: []*QueryDefinition{progressCopyDefinition},
+	},
+}
+
+func generateRelationProgressDefinitions(version *semver.Version) []*QueryDefinition {
+	queryDefinitions := make([]*QueryDefinition, 0)
+	for _, versionDef := range relationProgressDefinitions {
+		if version.GE(versionDef.minVersion) {
+			queryDefinitions = append(queryDefinitions, versionDef.queryDefinitions...)
+		}
+	}
+
+	return queryDefinitions
+}
+
+func generateInstanceProgressDefinitions(version *semver.Version) []*QueryDefinition {
+	queryDefinitions := make([]*QueryDefinition, 0, 1)
+	if version.GE(semver.MustParse("13.0.0")) {
+		queryDefinitions = append(queryDefinitions, progressBaseBackupDefinition)
+	}
+
+	return queryDefinitions
+}
+
+type progressBase struct {
+	databaseBase
+	schemaBase
+	tableBase
+	Pid             *int64   `db:"pid"              metric_name:"progress.pid"                  source_type:"gauge"`
+	Command         *string  `db:"command"          metric_name:"progress.command"              source_type:"attribute"`
+	Phase           *string  `db:"phase"            metric_name:"progress.phase"                source_type:"attribute"`
+	PercentComplete *float64 `db:"percent_complete" metric_name:"progress.percentComplete"      source_type:"gauge"`
+	ElapsedSeconds  *int64   `db:"elapsed_seconds"  metric_name:"progress.elapsedTimeInSeconds" source_type:"gauge"`
+}
+
+var progressVacuumDefinition = &QueryDefinition{
+	query: `SELECT -- PROGRESS_VACUUM
+		P.datname AS database,
+		N.nspname AS schema_name,
+		C.relname AS table_name,
+		P.pid AS pid,
+		CASE WHEN A.query LIKE 'autovacuum:%' THEN 'AUTOVACUUM' ELSE 'VACUUM' END AS command,
+		P.phase AS phase,
+		CASE WHEN P.heap_blks_total > 0 THEN 100.0 * P.heap_blks_scanned / P.heap_blks_total END AS percent_complete,
+		cast(extract(epoch FROM (now() - A.query_start)) AS bigint) AS elapsed_seconds,
+		P.heap_blks_total AS heap_blocks_total,
+		P.heap_blks_scanned AS heap_blocks_scanned,
+		P.heap_blks_vacuumed AS heap_blocks_vacuumed,
+		P.index_vacuum_count AS index_vacuum_count
+		FROM pg_stat_progress_vacuum P
+		LEFT JOIN pg_stat_activity A ON A.pid = P.pid
+		LEFT JOIN pg_class C ON C.oid = P.relid
+		LEFT JOIN pg_namespace N ON N.oid = C.relnamespace
+		WHERE P.datname = current_database();`,
+
+	dataModels: []struct {
+		progressBase
+		HeapBlocksTotal    *int64 `db:"heap_blocks_total"    metric_name:"progress.vacuum.heapBlocksTotal"    source_type:"gauge"`
+		HeapBlocksScanned  *int64 `db:"heap_blocks_scanned"  metric_name:"progress.vacuum.heapBlocksScanned"  source_type:"gauge"`
+		HeapBlocksVacuumed *int64 `db:"heap_blocks_vacuumed" metric_name:"progress.vacuum.heapBlocksVacuumed" source_type:"gauge"`
+		IndexVacuumCount   *int64 `db:"index_vacuum_count"   metric_name:"progress.vacuum.indexVacuumCount"   source_type:"gauge"`
+	}{},
+}
+
+var progressAnalyzeDefinition = &QueryDefinition{
+	query: `SELECT -- PROGRESS_ANALYZE
+		P.datname AS database,
+		N.nspname AS schema_name,
+		C.relname AS table_name,
+		P.pid AS pid,
+		'ANALYZE' AS command,
+		P.phase AS phase,
+		CASE WHEN P.sample_blks_total > 0 THEN 100.0 * P.sample_blks_scanned / P.sample_blks_total END AS percent_complete,
+		cast(extract(epoch FROM (now() - A.query_start)) AS bigint) AS elapsed_seconds
+		FROM pg_stat_progress_analyze P
+		LEFT JOIN pg_stat_activity A ON A.pid = P.pid
+		LEFT JOIN pg_class C ON C.oid = P.relid
+		LEFT JOIN pg_namespace N ON N.oid = C.relnamespace
+		WHERE P.datname = current_database();`,
+
+	dataModels: []struct {
+		progressBase
+	}{},
+}
+
+var progressCreateIndexDefinition = &QueryDefinition{
+	query: `SELECT -- PROGRESS_CREATE_INDEX
+		P.datname AS database,
+		N.nspname AS schema_name,
+		C.relname AS table_name,
+		I.relname AS index_name,
+		P.pid AS pid,
+		P.command AS command,
+		P.phase AS phase,
+		CASE
+			WHEN P.blocks_total > 0 THEN 100.0 * P.blocks_done / P.blocks_total
+			WHEN P.tuples_total > 0 THEN 100.0 * P.tuples_done / P.tuples_total
+		END AS percent_complete,
+		cast(extract(epoch FROM (now() - A.query_start)) AS bigint) AS elapsed_seconds
+		FROM pg_stat_progress_create_index P
+		LEFT JOIN pg_stat_activity A ON A.pid = P.pid
+		LEFT JOIN pg_class C ON C.oid = P.relid
+		LEFT JOIN pg_class I ON I.oid = P.index_relid
+		LEFT JOIN pg_namespace N ON N.oid = C.relnamespace
+		WHERE P.datname = current_database();`,
+
+	dataModels: []struct {
+		progressBase
+		Index *string `db:"index_name" metric_name:"progress.index" source_type:"attribute"`
+	}{},
+}
+
+var progressClusterDefinition = &QueryDefinition{
+	query: `SELECT -- PROGRESS_CLUSTER
+		P.datname AS database,
+		N.nspname AS schema_name,
+		C.relname AS table_name,
+		P.pid AS pid,
+		P.command AS command,
+		P.phase AS phase,
+		CASE WHEN P.heap_blks_total > 0 THEN 100.0 * P.heap_blks_scanned / P.heap_blks_total END AS percent_complete,
+		cast(extract(epoch FROM (now() - A.query_start)) AS bigint) AS elapsed_seconds
+		FROM pg_stat_progress_cluster P
+		LEFT JOIN pg_stat_activity A ON A.pid = P.pid
+		LEFT JOIN pg_class C ON C.oid = P.relid
+		LEFT JOIN pg_namespace N ON N.oid = C.relnamespace
+		WHERE P.datname = current_database();`,
+
+	dataModels: []struct {
+		progressBase
+	}{},
+}
+
+// progressCopyDefinition has no phase; a relid of 0 means the COPY source is a query.
+var progressCopyDefinition = &QueryDefinition{
+	query: `SELECT -- PROGRESS_COPY
+		P.datname AS database,
+		N.nspname AS schema_name,
+		C.relname AS table_name,
+		P.pid AS pid,
+		P.command AS command,
+		CASE WHEN P.bytes_total > 0 THEN 100.0 * P.bytes_processed / P.bytes_total END AS percent_complete,
+		cast(extract(epoch FROM (now() - A.query_start)) AS bigint) AS elapsed_seconds,
+		P.tuples_processed AS tuples_processed
+		FROM pg_stat_progress_copy P
+		LEFT JOIN pg_stat_activity A ON A.pid = P.pid
+		LEFT JOIN pg_class C ON C.oid = P.relid
+		LEFT JOIN pg_namespace N ON N.oid = C.relnamespace
+		WHERE P.datname = current_database();`,
+
+	dataModels: []struct {
+		progressBase
+		TuplesProcessed *int64 `db:"tuples_processed" metric_name:"progress.copy.tuplesProcessed" source_type:"gauge"`
+	}{},
+}
+
+// progressBaseBackupDefinition reports base backups, which are not tied to a database or relation.
+// backup_total is NULL when the size estimation is disabled.
+var progressBaseBackupDefinition = &QueryDefinition{
+	query: `SELECT -- PROGRESS_BASEBACKUP
+		P.pid AS pid,
+		'BASE_BACKUP' AS command,
+		P.phase AS phase,
+		CASE WHEN P.backup_total > 0 THEN 100.0 * P.backup_streamed / P.backup_total END AS percent_complete,
+		cast(extract(epoch FROM (now() - A.backend_start)) AS bigint) AS elapsed_seconds,
+		P.backup_streamed AS backup_streamed
+		FROM pg_stat_progress_basebackup P
+		LEFT JOIN pg_stat_activity A ON A.pid = P.pid;`,
+
+	dataModels: []struct {
+		Pid             *int64   `db:"pid"              metric_name:"progress.pid"                      source_type:"gauge"`
+		Command         *string  `db:"command"          metric_name:"progress.command"                  source_type:"attribute"`
+		Phase           *string  `db:"phase"            metric_name:"progress.phase"                    source_type:"attribute"`
+		PercentComplete *float64 `db:"percent_complete" metric_name:"progress.percentComplete"          source_type:"gauge"`
+		ElapsedSeconds  *int64   `db:"elapsed_seconds"  metric_name:"progress.elapsedTimeInSeconds"     source_type:"gauge"`
+		BackupStreamed  *int64   `db:"backup_streamed"  metric_name:"progress.basebackup.streamedBytes" source_type:"gauge"`
+	}{},
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_generateRelationProgressDefinitions(t *testing.T) {
+	tests := []struct {
+		name            string
+		version         string
+		expectedQueries []*QueryDefinition
+	}{
+		{
+			name:            "PostgreSQL 9.5",
+			version:         "9.5.0",
+			expectedQueries: []*QueryDefinition{},
+		},
+		{
+			name:            "PostgreSQL 9.6",
+			version:         "9.6.0",
+			expectedQueries: []*QueryDefinition{progressVacuumDefinition},
+		},
+		{
+			name:            "PostgreSQL 12",
+			version:         "12.3.0",
+			expectedQueries: []*QueryDefinition{progressVacuumDefinition, progressCreateIndexDefinition, progressClusterDefinition},
+		},
+		{
+			name:    "PostgreSQL 14",
+			version: "14.0.0",
+			expectedQueries: []*QueryDefinition{
+				progressVacuumDefinition,
+				progressCreateIndexDefinition,
+				progressClusterDefinition,
+				progressAnalyzeDefinition,
+				progressCopyDefinition,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version := semver.MustParse(tt.version)
+			assert.Equal(t, tt.expectedQueries, generateRelationProgressDefinitions(&version))
+		})
+	}
+}
+
+func Test_generateInstanceProgressDefinitions(t *testing.T) {
+	v12 := semver.MustParse("12.0.0")
+	v13 := semver.MustParse("13.0.0")
+
+	assert.Empty(t, generateInstanceProgressDefinitions(&v12))
+	assert.Equal(t, []*QueryDefinition{progressBaseBackupDefinition}, generateInstanceProgressDefinitions(&v13))
+}
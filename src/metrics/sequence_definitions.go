@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"github.com/blang/semver/v4"
+	"github.com/newrelic/nri-postgresql/src/collection"
+)
+
+func generateSequenceDefinitions(schemaList collection.SchemaList, version *semver.Version) []*QueryDefinition {
+	queryDefinitions := make([]*QueryDefinition, 0, 1)
+
+	definition := sequenceDefinition
+	if version.GE(semver.MustParse("10.0.0")) {
+		definition = sequenceDefinitionOver10
+	}
+
+	if def := definition.insertSchemaNames(schemaList); def != nil {
+		queryDefinitions = append(queryDefinitions, def)
+	}
+
+	return queryDefinitions
+}
+
+// sequenceBase reports a sequence against its owning table, found through the pg_depend entry
+// created by serial and identity columns or OWNED BY. The schema is the one of the owning table,
+// or of the sequence when it has no owner.
+// A sequence owned by a smallint or integer column runs out at the column limit, so the usage
+// percentage is computed against it and limitedByColumnType flags a sequence type wider than the column.
+type sequenceBase struct {
+	databaseBase
+	schemaBase
+	tableBase
+	SequenceSchema      *string  `db:"sequence_schema"        metric_name:"sequence.schema"              source_type:"attribute"`
+	Sequence            *string  `db:"sequence_name"          metric_name:"sequence.name"                source_type:"attribute"`
+	Column              *string  `db:"column_name"            metric_name:"sequence.column"              source_type:"attribute"`
+	DataType            *string  `db:"data_type"              metric_name:"sequence.dataType"            source_type:"attribute"`
+	ColumnType          *string  `db:"column_type"            metric_name:"sequence.columnType"          source_type:"attribute"`
+	LastValue           *int64   `db:"last_value"             metric_name:"sequence.lastValue"           source_type:"gauge"`
+	MinValue            *int64   `db:"min_value"              metric_name:"sequence.minValue"            source_type:"gauge"`
+	MaxValue            *int64   `db:"max_value"              metric_name:"sequence.maxValue"            source_type:"gauge"`
+	Increment           *int64   `db:"increment"              metric_name:"sequence.increment"           source_type:"gauge"`
+	Cycle               *bool    `db:"cycle"                  metric_name:"sequence.cycle"               source_type:"gauge"`
+	PercentUsed         *float64 `db:"percent_used"           metric_name:"sequence.percentUsed"         source_type:"gauge"`
+	LimitedByColumnType *bool    `db:"limited_by_column_type" metric_name:"sequence.limitedByColumnType" source_type:"gauge"`
+}
+
+// sequenceDefinitionOver10 reads pg_sequences, where last_value is NULL for sequences that were never
+// used or that the user is not allowed to read.
+var sequenceDefinitionOver10 = &QueryDefinition{
+	query: `SELECT -- SEQUENCES_OVER10
+		database, schema_name, table_name, sequence_schema, sequence_name, column_name, data_type, column_type,
+		last_value, min_value, max_value, increment, cycle,
+		CASE
+			WHEN increment > 0 THEN 100.0 * (last_value::numeric - min_value) / nullif(max_limit::numeric - min_value, 0)
+			ELSE 100.0 * (max_value::numeric - last_value) / nullif(max_value::numeric - min_limit, 0)
+		END AS percent_used,
+		max_limit < max_value OR min_limit > min_value AS limited_by_column_type
+		FROM (
+			SELECT Q.*,
+				CASE column_type WHEN 'smallint' THEN least(max_value, 32767) WHEN 'integer' THEN least(max_value, 2147483647) ELSE max_value END AS max_limit,
+				CASE column_type WHEN 'smallint' THEN greatest(min_value, -32768) WHEN 'integer' THEN greatest(min_value, -2147483648) ELSE min_value END AS min_limit
+			FROM (
+				SELECT
+					current_database() AS database,
+					coalesce(TN.nspname, S.schemaname) AS schema_name,
+					T.relname AS table_name,
+					S.schemaname AS sequence_schema,
+					S.sequencename AS sequence_name,
+					A.attname AS column_name,
+					S.data_type::text AS data_type,
+					A.atttypid::regtype::text AS column_type,
+					S.last_value AS last_value,
+					S.min_value AS min_value,
+					S.max_value AS max_value,
+					S.increment_by AS increment,
+					S.cycle AS cycle
+				FROM pg_sequences S
+				JOIN pg_namespace N ON N.nspname = S.schemaname
+				JOIN pg_class C ON C.relnamespace = N.oid AND C.relname = S.sequencename
+				LEFT JOIN pg_depend D ON D.classid = 'pg_class'::regclass AND D.objid = C.oid
+					AND D.refclassid = 'pg_class'::regclass AND D.deptype IN ('a', 'i')
+				LEFT JOIN pg_class T ON T.oid = D.refobjid
+				LEFT JOIN pg_namespace TN ON TN.oid = T.relnamespace
+				LEFT JOIN pg_attribute A ON A.attrelid = D.refobjid AND A.attnum = D.refobjsubid
+				WHERE S.schemaname IN (%SCHEMAS%)
+			) Q
+		) L;`,
+
+	dataModels: []struct {
+		sequenceBase
+	}{},
+}
+
+// sequenceDefinition is used before pg_sequences existed, when sequences are always bigint and their
+// state can only be read from the sequence relation itself. query_to_xml reads each of them without
+// needing a query per sequence.
+var sequenceDefinition = &QueryDefinition{
+	query: `SELECT -- SEQUENCES
+		database, schema_name, table_name, sequence_schema, sequence_name, column_name, data_type, column_type,
+		last_value, min_value, max_value, increment, cycle,
+		CASE
+			WHEN increment > 0 THEN 100.0 * (last_value::numeric - min_value) / nullif(max_limit::numeric - min_value, 0)
+			ELSE 100.0 * (max_value::numeric - last_value) / nullif(max_value::numeric - min_limit, 0)
+		END AS percent_used,
+		max_limit < max_value OR min_limit > min_value AS limited_by_column_type
+		FROM (
+			SELECT Q.*,
+				CASE column_type WHEN 'smallint' THEN least(max_value, 32767) WHEN 'integer' THEN least(max_value, 2147483647) ELSE max_value END AS max_limit,
+				CASE column_type WHEN 'smallint' THEN greatest(min_value, -32768) WHEN 'integer' THEN greatest(min_value, -2147483648) ELSE min_value END AS min_limit
+			FROM (
+				SELECT
+					database, schema_name, table_name, sequence_schema, sequence_name, column_name, data_type, column_type,
+					CASE WHEN (xpath('/row/is_called/text()', X))[1]::text = 'true'
+						THEN (xpath('/row/last_value/text()', X))[1]::text::bigint END AS last_value,
+					(xpath('/row/min_value/text()', X))[1]::text::bigint AS min_value,
+					(xpath('/row/max_value/text()', X))[1]::text::bigint AS max_value,
+					(xpath('/row/increment_by/text()', X))[1]::text::bigint AS increment,
+					(xpath('/row/is_cycled/text()', X))[1]::text = 'true' AS cycle
+				FROM (
+					SELECT
+						current_database() AS database,
+						coalesce(TN.nspname, N.nspname) AS schema_name,
+						T.relname AS table_name,
+						N.nspname AS sequence_schema,
+						C.relname AS sequence_name,
+						A.attname AS column_name,
+						'bigint'::text AS data_type,
+						A.atttypid::regtype::text AS column_type,
+						query_to_xml(format('SELECT last_value, min_value, max_value, increment_by, is_cycled, is_called FROM %I.%I',
+							N.nspname, C.relname), false, true, '') AS X
+					FROM pg_class C
+					JOIN pg_namespace N ON N.oid = C.relnamespace
+					LEFT JOIN pg_depend D ON D.classid = 'pg_class'::regclass AND D.objid = C.oid
+						AND D.refclassid = 'pg_class'::regclass AND D.deptype = 'a'
+					LEFT JOIN pg_class T ON T.oid = D.refobjid
+					LEFT JOIN pg_namespace TN ON TN.oid = T.relnamespace
+					LEFT JOIN pg_attribute A ON A.attrelid = D.refobjid AND A.attnum = D.refobjsubid
+					WHERE C.relkind = 'S' AND N.nspname IN (%SCHEMAS%) AND has_sequence_privilege(C.oid, 'SELECT')
+				) R
+			) Q
+		) L;`,
+
+	dataModels: []struct {
+		sequenceBase
+	}{},
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/newrelic/nri-postgresql/src/collection"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_generateSequenceDefinitions(t *testing.T) {
+	schemaList := collection.SchemaList{
+		"schema1": collection.TableList{
+			"table1": []string{},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		version       string
+		schemaList    collection.SchemaList
+		expectedQuery string
+	}{
+		{
+			name:          "PostgreSQL 9.6",
+			version:       "9.6.0",
+			schemaList:    schemaList,
+			expectedQuery: "-- SEQUENCES\n",
+		},
+		{
+			name:          "PostgreSQL 10",
+			version:       "10.0.0",
+			schemaList:    schemaList,
+			expectedQuery: "-- SEQUENCES_OVER10",
+		},
+		{
+			name:       "No schemas",
+			version:    "10.0.0",
+			schemaList: collection.SchemaList{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			version := semver.MustParse(tc.version)
+			queryDefinitions := generateSequenceDefinitions(tc.schemaList, &version)
+			if tc.expectedQuery == "" {
+				assert.Empty(t, queryDefinitions)
+				return
+			}
+			assert.Len(t, queryDefinitions, 1)
+			assert.Contains(t, queryDefinitions[0].GetQuery(), tc.expectedQuery)
+			assert.Contains(t, queryDefinitions[0].GetQuery(), "IN ('schema1')")
+			assert.False(t, strings.Contains(queryDefinitions[0].GetQuery(), "%SCHEMAS%"))
+		})
+	}
+}
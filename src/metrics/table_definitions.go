@@ -35,6 +35,8 @@ var tableDefinition = &QueryDefinition{
 			stat.relname as table_name,
 			pg_total_relation_size(c.oid), -- table.totalSizeInBytes
 			pg_indexes_size(c.oid), -- table.indexSizeInBytes
+			heap_blks_read, -- table.heapBlocksReadPerSecond
+			heap_blks_hit, -- table.heapBlocksHitPerSecond
 			idx_blks_read, -- table.indexBlocksRead
 			idx_blks_hit, -- table.indexBlocksHit
 			toast_blks_read, --table.indexToastBlocksRead
@@ -51,7 +53,9 @@ var tableDefinition = &QueryDefinition{
 			n_tup_upd, -- table.rowsUpdatedPerSecond
 			n_tup_del, -- table.rowsDeletedPerSecond
 			n_live_tup, -- table.liveRows
-			n_dead_tup -- table.deadRows
+			n_dead_tup, -- table.deadRows
+			(SELECT spcname FROM pg_tablespace WHERE oid = coalesce(nullif(c.reltablespace, 0),
+				(SELECT dattablespace FROM pg_database WHERE datname = current_database()))) as tablespace -- table.tablespace
 		FROM pg_statio_user_tables as statio
 		JOIN pg_stat_user_tables as stat
 			ON stat.relid=statio.relid
@@ -69,6 +73,8 @@ var tableDefinition = &QueryDefinition{
 		IndexSize                *int64   `db:"pg_indexes_size"        metric_name:"table.indexSizeInBytes"                   source_type:"gauge"`
 		LiveRows                 *int64   `db:"n_live_tup"             metric_name:"table.liveRows"                           source_type:"gauge"`
 		DeadRows                 *int64   `db:"n_dead_tup"             metric_name:"table.deadRows"                           source_type:"gauge"`
+		HeapBlocksReadPerSecond  *float32 `db:"heap_blks_read"         metric_name:"table.heapBlocksReadPerSecond"            source_type:"rate"`
+		HeapBlocksHitPerSecond   *float32 `db:"heap_blks_hit"          metric_name:"table.heapBlocksHitPerSecond"             source_type:"rate"`
 		IndexBlocksReadPerSecond *float32 `db:"idx_blks_read"          metric_name:"table.indexBlocksReadPerSecond"           source_type:"rate"`
 		IndexBlocksHitPerSecond  *float32 `db:"idx_blks_hit"           metric_name:"table.indexBlocksHitPerSecond"            source_type:"rate"`
 		ToastBlocksReadPerSecond *float32 `db:"toast_blks_read"        metric_name:"table.indexToastBlocksReadPerSecond"      source_type:"rate"`
@@ -84,6 +90,7 @@ var tableDefinition = &QueryDefinition{
 		RowsInserted             *float32 `db:"n_tup_ins"              metric_name:"table.rowsInsertedPerSecond"              source_type:"rate"`
 		RowsUpdated              *float32 `db:"n_tup_upd"              metric_name:"table.rowsUpdatedPerSecond"               source_type:"rate"`
 		RowsDeleted              *float32 `db:"n_tup_del"              metric_name:"table.rowsDeletedPerSecond"               source_type:"rate"`
+		Tablespace               *string  `db:"tablespace"             metric_name:"table.tablespace"                         source_type:"attribute"`
 	}{},
 }
 
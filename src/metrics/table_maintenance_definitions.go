@@ -0,0 +1,171 @@
+package metrics
+
+import (
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/newrelic/nri-postgresql/src/collection"
+)
+
+// tableMaintenanceDefinitions are ordered from the newest version, only the first applicable one is used
+var tableMaintenanceDefinitions = []VersionDefinition{
+	{
+		minVersion:       semver.MustParse("18.0.0"),
+		queryDefinitions: []*QueryDefinition{tableMaintenanceDefinitionOver18},
+	},
+	{
+		minVersion:       semver.MustParse("13.0.0"),
+		queryDefinitions: []*QueryDefinition{tableMaintenanceDefinitionOver13},
+	},
+	{
+		minVersion:       semver.MustParse("9.4.0"),
+		queryDefinitions: []*QueryDefinition{tableMaintenanceDefinitionOver94},
+	},
+}
+
+func generateTableMaintenanceDefinitions(schemaList collection.SchemaList, version *semver.Version) []*QueryDefinition {
+	queryDefinitions := make([]*QueryDefinition, 0, 1)
+	for _, versionDef := range tableMaintenanceDefinitions {
+		if version.GE(versionDef.minVersion) {
+			for _, definition := range versionDef.queryDefinitions {
+				if def := definition.insertSchemaTables(schemaList); def != nil {
+					queryDefinitions = append(queryDefinitions, def)
+				}
+			}
+			break
+		}
+	}
+
+	return queryDefinitions
+}
+
+// tableMaintenanceBase holds the maintenance activity of a table and its effective autovacuum thresholds,
+// computed from the global settings overridden by the table reloptions. A table is due for vacuum or analyze
+// once autovacuum would pick it up, which never happens while autovacuum is disabled globally or for the table.
+//
+// These columns are reported in their own PostgresqlTableMaintenanceSample rather than added to tableDefinition:
+// tableDefinition is the same query on every version, while these columns depend on the version, and a second
+// query reported as PostgresqlTableSample would split each table sample into several partial events.
+type tableMaintenanceBase struct {
+	databaseBase
+	schemaBase
+	tableBase
+	HotUpdates          *int64   `db:"hot_updates"           metric_name:"table.hotUpdatesPerSecond"                   source_type:"rate"`
+	HotUpdatePercent    *float64 `db:"hot_update_percent"    metric_name:"table.hotUpdatePercent"                      source_type:"gauge"`
+	ModSinceAnalyze     *int64   `db:"mod_since_analyze"     metric_name:"table.rowsModifiedSinceAnalyze"              source_type:"gauge"`
+	InsSinceVacuum      *int64   `db:"ins_since_vacuum"      metric_name:"table.rowsInsertedSinceVacuum"               source_type:"gauge"` // added in v13
+	Vacuums             *int64   `db:"vacuum_count"          metric_name:"table.vacuumsPerSecond"                      source_type:"rate"`
+	AutoVacuums         *int64   `db:"autovacuum_count"      metric_name:"table.autoVacuumsPerSecond"                  source_type:"rate"`
+	Analyzes            *int64   `db:"analyze_count"         metric_name:"table.analyzesPerSecond"                     source_type:"rate"`
+	AutoAnalyzes        *int64   `db:"autoanalyze_count"     metric_name:"table.autoAnalyzesPerSecond"                 source_type:"rate"`
+	TotalVacuumTime     *float64 `db:"total_vacuum_time"     metric_name:"table.vacuumTimeInMillisecondsPerSecond"     source_type:"rate"` // added in v18
+	TotalAutovacuumTime *float64 `db:"total_autovacuum_time" metric_name:"table.autoVacuumTimeInMillisecondsPerSecond" source_type:"rate"` // added in v18
+	AutovacuumEnabled   *bool    `db:"autovacuum_enabled"    metric_name:"table.autovacuum.enabled"                    source_type:"gauge"`
+	VacuumThreshold     *float64 `db:"vacuum_threshold"      metric_name:"table.autovacuum.vacuumThreshold"            source_type:"gauge"`
+	InsertThreshold     *float64 `db:"insert_threshold"      metric_name:"table.autovacuum.insertThreshold"            source_type:"gauge"` // added in v13
+	VacuumDue           *bool    `db:"vacuum_due"            metric_name:"table.autovacuum.vacuumDue"                  source_type:"gauge"`
+	AnalyzeThreshold    *float64 `db:"analyze_threshold"     metric_name:"table.autovacuum.analyzeThreshold"           source_type:"gauge"`
+	AnalyzeDue          *bool    `db:"analyze_due"           metric_name:"table.autovacuum.analyzeDue"                 source_type:"gauge"`
+}
+
+// tableMaintenanceQuery is shared by every version, which only differ by the pg_stat_user_tables columns
+// inserted at %VERSION_COLUMNS%. The settings are read from pg_settings, where the ones missing from older
+// versions are null: the insert threshold (13+), with which autovacuum vacuums insert-only tables, is then
+// null, as it is when a negative value disables it, and so is the cap of the vacuum threshold (18+).
+const tableMaintenanceQuery = `SELECT -- TABLE_MAINTENANCE
+			current_database() AS database,
+			schema_name, table_name, hot_updates, hot_update_percent, mod_since_analyze, ins_since_vacuum,
+			vacuum_count, autovacuum_count, analyze_count, autoanalyze_count,
+			total_vacuum_time, total_autovacuum_time,
+			autovacuum_enabled,
+			vacuum_threshold,
+			insert_threshold,
+			autovacuum_enabled AND (dead_rows > vacuum_threshold
+				OR coalesce(ins_since_vacuum > insert_threshold, false)) AS vacuum_due,
+			analyze_threshold,
+			autovacuum_enabled AND mod_since_analyze > analyze_threshold AS analyze_due
+		FROM (
+			SELECT
+				S.schemaname AS schema_name,
+				S.relname AS table_name,
+				S.n_tup_hot_upd AS hot_updates,
+				CASE WHEN S.n_tup_upd > 0 THEN 100.0 * S.n_tup_hot_upd / S.n_tup_upd END AS hot_update_percent,
+				S.n_dead_tup AS dead_rows,
+				S.n_mod_since_analyze AS mod_since_analyze,
+				S.vacuum_count AS vacuum_count,
+				S.autovacuum_count AS autovacuum_count,
+				S.analyze_count AS analyze_count,
+				S.autoanalyze_count AS autoanalyze_count,
+				%VERSION_COLUMNS%,
+				G.enabled AND coalesce(O.enabled, true) AS autovacuum_enabled,
+				least(coalesce(O.vacuum_threshold, G.vacuum_threshold)
+						+ coalesce(O.vacuum_scale_factor, G.vacuum_scale_factor) * greatest(C.reltuples, 0),
+					CASE WHEN coalesce(O.vacuum_max_threshold, G.vacuum_max_threshold) >= 0
+						THEN coalesce(O.vacuum_max_threshold, G.vacuum_max_threshold) END) AS vacuum_threshold,
+				CASE WHEN coalesce(O.insert_threshold, G.insert_threshold) >= 0
+					THEN coalesce(O.insert_threshold, G.insert_threshold)
+						+ coalesce(O.insert_scale_factor, G.insert_scale_factor) * greatest(C.reltuples, 0)
+				END AS insert_threshold,
+				coalesce(O.analyze_threshold, G.analyze_threshold)
+					+ coalesce(O.analyze_scale_factor, G.analyze_scale_factor) * greatest(C.reltuples, 0) AS analyze_threshold
+			FROM pg_stat_user_tables S
+			JOIN pg_class C ON C.oid = S.relid
+			CROSS JOIN (
+				SELECT
+					max(CASE WHEN name = 'autovacuum' THEN setting END)::bool AS enabled,
+					max(CASE WHEN name = 'autovacuum_vacuum_threshold' THEN setting END)::float8 AS vacuum_threshold,
+					max(CASE WHEN name = 'autovacuum_vacuum_max_threshold' THEN setting END)::float8 AS vacuum_max_threshold,
+					max(CASE WHEN name = 'autovacuum_vacuum_scale_factor' THEN setting END)::float8 AS vacuum_scale_factor,
+					max(CASE WHEN name = 'autovacuum_vacuum_insert_threshold' THEN setting END)::float8 AS insert_threshold,
+					max(CASE WHEN name = 'autovacuum_vacuum_insert_scale_factor' THEN setting END)::float8 AS insert_scale_factor,
+					max(CASE WHEN name = 'autovacuum_analyze_threshold' THEN setting END)::float8 AS analyze_threshold,
+					max(CASE WHEN name = 'autovacuum_analyze_scale_factor' THEN setting END)::float8 AS analyze_scale_factor
+				FROM pg_settings
+				WHERE name LIKE 'autovacuum%'
+			) G
+			LEFT JOIN (
+				SELECT R.oid,
+					max(CASE WHEN P.option_name = 'autovacuum_enabled' THEN P.option_value END)::bool AS enabled,
+					max(CASE WHEN P.option_name = 'autovacuum_vacuum_threshold' THEN P.option_value END)::float8 AS vacuum_threshold,
+					max(CASE WHEN P.option_name = 'autovacuum_vacuum_max_threshold' THEN P.option_value END)::float8 AS vacuum_max_threshold,
+					max(CASE WHEN P.option_name = 'autovacuum_vacuum_scale_factor' THEN P.option_value END)::float8 AS vacuum_scale_factor,
+					max(CASE WHEN P.option_name = 'autovacuum_vacuum_insert_threshold' THEN P.option_value END)::float8 AS insert_threshold,
+					max(CASE WHEN P.option_name = 'autovacuum_vacuum_insert_scale_factor' THEN P.option_value END)::float8 AS insert_scale_factor,
+					max(CASE WHEN P.option_name = 'autovacuum_analyze_threshold' THEN P.option_value END)::float8 AS analyze_threshold,
+					max(CASE WHEN P.option_name = 'autovacuum_analyze_scale_factor' THEN P.option_value END)::float8 AS analyze_scale_factor
+				FROM pg_class R, pg_options_to_table(R.reloptions) P
+				WHERE R.reloptions IS NOT NULL
+				GROUP BY R.oid
+			) O ON O.oid = C.oid
+			WHERE S.schemaname || '.' || S.relname IN (%SCHEMA_TABLES%)
+		) T;`
+
+var tableMaintenanceDefinitionOver94 = &QueryDefinition{
+	query: strings.Replace(tableMaintenanceQuery, "%VERSION_COLUMNS%", `NULL::bigint AS ins_since_vacuum,
+				NULL::float8 AS total_vacuum_time,
+				NULL::float8 AS total_autovacuum_time`, 1),
+
+	dataModels: []struct {
+		tableMaintenanceBase
+	}{},
+}
+
+var tableMaintenanceDefinitionOver13 = &QueryDefinition{
+	query: strings.Replace(tableMaintenanceQuery, "%VERSION_COLUMNS%", `S.n_ins_since_vacuum AS ins_since_vacuum,
+				NULL::float8 AS total_vacuum_time,
+				NULL::float8 AS total_autovacuum_time`, 1),
+
+	dataModels: []struct {
+		tableMaintenanceBase
+	}{},
+}
+
+var tableMaintenanceDefinitionOver18 = &QueryDefinition{
+	query: strings.Replace(tableMaintenanceQuery, "%VERSION_COLUMNS%", `S.n_ins_since_vacuum AS ins_since_vacuum,
+				S.total_vacuum_time AS total_vacuum_time,
+				S.total_autovacuum_time AS total_autovacuum_time`, 1),
+
+	dataModels: []struct {
+		tableMaintenanceBase
+	}{},
+}
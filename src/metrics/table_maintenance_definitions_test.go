@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/newrelic/nri-postgresql/src/collection"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_generateTableMaintenanceDefinitions(t *testing.T) {
+	schemaList := collection.SchemaList{
+		"schema1": collection.TableList{
+			"table1": []string{},
+		},
+	}
+
+	tests := []struct {
+		version         string
+		expectedColumns []string
+	}{
+		{version: "9.3.0"},
+		{version: "9.4.0", expectedColumns: []string{"NULL::bigint AS ins_since_vacuum", "NULL::float8 AS total_vacuum_time"}},
+		{version: "12.5.0", expectedColumns: []string{"NULL::bigint AS ins_since_vacuum", "NULL::float8 AS total_vacuum_time"}},
+		{version: "13.0.0", expectedColumns: []string{"S.n_ins_since_vacuum AS ins_since_vacuum", "NULL::float8 AS total_vacuum_time"}},
+		{version: "17.2.0", expectedColumns: []string{"S.n_ins_since_vacuum AS ins_since_vacuum", "NULL::float8 AS total_vacuum_time"}},
+		{version: "18.0.0", expectedColumns: []string{"S.n_ins_since_vacuum AS ins_since_vacuum", "S.total_vacuum_time AS total_vacuum_time"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.version, func(t *testing.T) {
+			version := semver.MustParse(tc.version)
+			queryDefinitions := generateTableMaintenanceDefinitions(schemaList, &version)
+			if tc.expectedColumns == nil {
+				assert.Empty(t, queryDefinitions)
+				return
+			}
+			assert.Len(t, queryDefinitions, 1)
+			for _, column := range tc.expectedColumns {
+				assert.Contains(t, queryDefinitions[0].GetQuery(), column)
+			}
+			assert.Contains(t, queryDefinitions[0].GetQuery(), "IN ('schema1.table1')")
+			assert.NotContains(t, queryDefinitions[0].GetQuery(), "%VERSION_COLUMNS%")
+		})
+	}
+}
+
+func Test_tableMaintenanceQuery_DueOnlyWithAutovacuum(t *testing.T) {
+	assert.Contains(t, tableMaintenanceQuery, "autovacuum_enabled AND (dead_rows > vacuum_threshold")
+	assert.Contains(t, tableMaintenanceQuery, "autovacuum_enabled AND mod_since_analyze > analyze_threshold AS analyze_due")
+}
@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"github.com/blang/semver/v4"
+)
+
+// tablespaceLargestRelations is the number of relations reported as the largest ones of a tablespace
+const tablespaceLargestRelations = 5
+
+func generateTablespaceDefinition(version *semver.Version) *QueryDefinition {
+	if version.GE(semver.MustParse("9.2.0")) {
+		return tablespaceDefinitionOver92
+	}
+
+	return tablespaceDefinition
+}
+
+func generateTablespaceRelationsDefinition() *QueryDefinition {
+	return tablespaceRelationsDefinition.insertLimit(tablespaceLargestRelations)
+}
+
+// tablespaceMetrics is a tablespace. LargestRelations is not returned by the query, it is filled
+// from the relations of each collected database.
+type tablespaceMetrics struct {
+	Tablespace       *string  `db:"tablespace_name"`
+	Location         *string  `db:"location"          metric_name:"tablespace.location"         source_type:"attribute"`
+	Owner            *string  `db:"owner"             metric_name:"tablespace.owner"            source_type:"attribute"`
+	Databases        *string  `db:"databases"         metric_name:"tablespace.databases"        source_type:"attribute"`
+	LargestRelations *string  `db:"largest_relations" metric_name:"tablespace.largestRelations" source_type:"attribute"`
+	Size             *int64   `db:"size"              metric_name:"tablespace.sizeInBytes"      source_type:"gauge"`
+	SeqPageCost      *float64 `db:"seq_page_cost"     metric_name:"tablespace.seqPageCost"      source_type:"gauge"`
+	RandomPageCost   *float64 `db:"random_page_cost"  metric_name:"tablespace.randomPageCost"   source_type:"gauge"`
+}
+
+// tablespaceRelation is a relation stored in a tablespace, named database.schema.relation
+type tablespaceRelation struct {
+	Tablespace string `db:"tablespace_name"`
+	Relation   string `db:"relation_name"`
+	Size       int64  `db:"size"`
+}
+
+// tablespaceDefinitionOver92 reads the location with pg_tablespace_location, which is empty for the
+// pg_default and pg_global tablespaces stored in the data directory. pg_tablespace_size needs the
+// CREATE privilege on the tablespace or pg_read_all_stats, except for the default tablespace of the
+// current database, so the size is NULL otherwise. The page costs are the ones set on the tablespace
+// or, when not set, the server ones.
+var tablespaceDefinitionOver92 = &QueryDefinition{
+	query: `SELECT -- TABLESPACES_OVER92
+			T.spcname AS tablespace_name,
+			nullif(pg_tablespace_location(T.oid), '') AS location,
+			pg_get_userbyid(T.spcowner) AS owner,
+			(SELECT string_agg(D.datname, ', ' ORDER BY D.datname) FROM pg_database D
+				WHERE D.dattablespace = T.oid AND NOT D.datistemplate) AS databases,
+			CASE WHEN has_tablespace_privilege(T.oid, 'CREATE')
+				OR T.oid = (SELECT dattablespace FROM pg_database WHERE datname = current_database())
+				OR EXISTS (SELECT 1 FROM pg_roles R WHERE R.rolname = 'pg_read_all_stats' AND pg_has_role(R.oid, 'MEMBER'))
+				THEN pg_tablespace_size(T.oid) END AS size,
+			coalesce((SELECT P.option_value::float FROM pg_options_to_table(T.spcoptions) P WHERE P.option_name = 'seq_page_cost'),
+				current_setting('seq_page_cost')::float) AS seq_page_cost,
+			coalesce((SELECT P.option_value::float FROM pg_options_to_table(T.spcoptions) P WHERE P.option_name = 'random_page_cost'),
+				current_setting('random_page_cost')::float) AS random_page_cost
+		FROM pg_tablespace T;`,
+
+	dataModels: []tablespaceMetrics{},
+}
+
+// tablespaceDefinition is used before pg_tablespace_location existed, when the location was a column
+var tablespaceDefinition = &QueryDefinition{
+	query: `SELECT -- TABLESPACES
+			T.spcname AS tablespace_name,
+			nullif(T.spclocation, '') AS location,
+			pg_get_userbyid(T.spcowner) AS owner,
+			(SELECT string_agg(D.datname, ', ' ORDER BY D.datname) FROM pg_database D
+				WHERE D.dattablespace = T.oid AND NOT D.datistemplate) AS databases,
+			CASE WHEN has_tablespace_privilege(T.oid, 'CREATE')
+				OR T.oid = (SELECT dattablespace FROM pg_database WHERE datname = current_database())
+				THEN pg_tablespace_size(T.oid) END AS size,
+			coalesce((SELECT P.option_value::float FROM pg_options_to_table(T.spcoptions) P WHERE P.option_name = 'seq_page_cost'),
+				current_setting('seq_page_cost')::float) AS seq_page_cost,
+			coalesce((SELECT P.option_value::float FROM pg_options_to_table(T.spcoptions) P WHERE P.option_name = 'random_page_cost'),
+				current_setting('random_page_cost')::float) AS random_page_cost
+		FROM pg_tablespace T;`,
+
+	dataModels: []tablespaceMetrics{},
+}
+
+// tablespaceRelationsDefinition returns the largest tables, materialized views and indexes of the current
+// database in each tablespace. Relations with no tablespace are stored in the default one of the database.
+var tablespaceRelationsDefinition = &QueryDefinition{
+	query: `SELECT -- TABLESPACE_RELATIONS
+			tablespace_name, relation_name, size
+		FROM (
+			SELECT
+				TS.spcname AS tablespace_name,
+				current_database() || '.' || N.nspname || '.' || C.relname AS relation_name,
+				pg_relation_size(C.oid) AS size,
+				row_number() OVER (PARTITION BY TS.spcname ORDER BY pg_relation_size(C.oid) DESC) AS position
+			FROM pg_class C
+			JOIN pg_namespace N ON N.oid = C.relnamespace
+			JOIN pg_tablespace TS ON TS.oid = coalesce(nullif(C.reltablespace, 0),
+				(SELECT dattablespace FROM pg_database WHERE datname = current_database()))
+			WHERE C.relkind IN ('r', 'm', 'i') AND NOT C.relisshared
+		) R
+		WHERE position <= %LIMIT%;`,
+
+	dataModels: []tablespaceRelation{},
+}
@@ -0,0 +1,20 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_generateTablespaceDefinition(t *testing.T) {
+	v91 := semver.MustParse("9.1.0")
+	assert.Equal(t, tablespaceDefinition, generateTablespaceDefinition(&v91))
+
+	v92 := semver.MustParse("9.2.0")
+	assert.Equal(t, tablespaceDefinitionOver92, generateTablespaceDefinition(&v92))
+}
+
+func Test_generateTablespaceRelationsDefinition(t *testing.T) {
+	assert.Contains(t, generateTablespaceRelationsDefinition().GetQuery(), "position <= 5;")
+}
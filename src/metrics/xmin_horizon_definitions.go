@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"github.com/blang/semver/v4"
+)
+
+func generateXminHorizonDefinitions(version *semver.Version) []*QueryDefinition {
+	queryDefinitions := make([]*QueryDefinition, 0, 4)
+
+	// backend_xmin and replication slots were introduced in 9.4
+	v94 := semver.MustParse("9.4.0")
+	if version.GE(v94) {
+		queryDefinitions = append(queryDefinitions, xminHorizonActivityDefinition, xminHorizonReplicationSlotDefinition)
+	}
+
+	queryDefinitions = append(queryDefinitions, xminHorizonPreparedXactDefinition)
+
+	if version.GE(v94) {
+		queryDefinitions = append(queryDefinitions, xminHorizonStandbyDefinition)
+	}
+
+	return queryDefinitions
+}
+
+// xminHorizonActivityDefinition returns the backend holding the oldest snapshot or transaction id.
+// WAL senders are excluded since their xmin is reported by xminHorizonStandbyDefinition.
+var xminHorizonActivityDefinition = &QueryDefinition{
+	query: `SELECT -- XMIN_ACTIVITY
+		A.pid AS pid,
+		greatest(age(A.backend_xmin), age(A.backend_xid)) AS xmin_age,
+		cast(extract(epoch FROM (now() - A.xact_start)) AS bigint) AS xmin_age_seconds
+		FROM pg_stat_activity A
+		WHERE (A.backend_xmin IS NOT NULL OR A.backend_xid IS NOT NULL)
+			AND A.pid <> pg_backend_pid()
+			AND A.pid NOT IN (SELECT R.pid FROM pg_stat_replication R)
+		ORDER BY 2 DESC
+		LIMIT 1;`,
+
+	dataModels: []struct {
+		Pid            *int64 `db:"pid"              metric_name:"xmin.activity.pid"                source_type:"gauge"`
+		XminAge        *int64 `db:"xmin_age"         metric_name:"xmin.activity.ageInTransactions"  source_type:"gauge"`
+		XminAgeSeconds *int64 `db:"xmin_age_seconds" metric_name:"xmin.activity.ageInSeconds"       source_type:"gauge"`
+	}{},
+}
+
+// xminHorizonReplicationSlotDefinition returns the replication slot holding the oldest xmin or catalog_xmin.
+// Slots do not record when their horizon was set, so no age in seconds is reported.
+var xminHorizonReplicationSlotDefinition = &QueryDefinition{
+	query: `SELECT -- XMIN_REPLICATION_SLOTS
+		S.slot_name AS slot_name,
+		greatest(age(S.xmin), age(S.catalog_xmin)) AS xmin_age,
+		age(S.xmin) AS data_xmin_age,
+		age(S.catalog_xmin) AS catalog_xmin_age
+		FROM pg_replication_slots S
+		WHERE S.xmin IS NOT NULL OR S.catalog_xmin IS NOT NULL
+		ORDER BY 2 DESC
+		LIMIT 1;`,
+
+	dataModels: []struct {
+		SlotName       *string `db:"slot_name"        metric_name:"xmin.replicationSlot.slotName"                     source_type:"attribute"`
+		XminAge        *int64  `db:"xmin_age"         metric_name:"xmin.replicationSlot.ageInTransactions"            source_type:"gauge"`
+		DataXminAge    *int64  `db:"data_xmin_age"    metric_name:"xmin.replicationSlot.xminAgeInTransactions"        source_type:"gauge"`
+		CatalogXminAge *int64  `db:"catalog_xmin_age" metric_name:"xmin.replicationSlot.catalogXminAgeInTransactions" source_type:"gauge"`
+	}{},
+}
+
+// xminHorizonPreparedXactDefinition returns the oldest prepared transaction
+var xminHorizonPreparedXactDefinition = &QueryDefinition{
+	query: `SELECT -- XMIN_PREPARED_XACTS
+		P.gid AS gid,
+		age(P.transaction) AS xmin_age,
+		cast(extract(epoch FROM (now() - P.prepared)) AS bigint) AS xmin_age_seconds
+		FROM pg_prepared_xacts P
+		ORDER BY 2 DESC
+		LIMIT 1;`,
+
+	dataModels: []struct {
+		Gid            *string `db:"gid"              metric_name:"xmin.preparedTransaction.gid"               source_type:"attribute"`
+		XminAge        *int64  `db:"xmin_age"         metric_name:"xmin.preparedTransaction.ageInTransactions" source_type:"gauge"`
+		XminAgeSeconds *int64  `db:"xmin_age_seconds" metric_name:"xmin.preparedTransaction.ageInSeconds"      source_type:"gauge"`
+	}{},
+}
+
+// xminHorizonStandbyDefinition returns the standby whose hot_standby_feedback holds the oldest xmin.
+// The feedback message carries no timestamp, so no age in seconds is reported.
+var xminHorizonStandbyDefinition = &QueryDefinition{
+	query: `SELECT -- XMIN_STANDBY
+		R.pid AS pid,
+		R.application_name AS application_name,
+		age(R.backend_xmin) AS xmin_age
+		FROM pg_stat_replication R
+		WHERE R.backend_xmin IS NOT NULL
+		ORDER BY 3 DESC
+		LIMIT 1;`,
+
+	dataModels: []struct {
+		Pid             *int64  `db:"pid"              metric_name:"xmin.standby.pid"               source_type:"gauge"`
+		ApplicationName *string `db:"application_name" metric_name:"xmin.standby.applicationName"   source_type:"attribute"`
+		XminAge         *int64  `db:"xmin_age"         metric_name:"xmin.standby.ageInTransactions" source_type:"gauge"`
+	}{},
+}
@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/blang/semver/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_generateXminHorizonDefinitions(t *testing.T) {
+	tests := []struct {
+		name            string
+		version         string
+		expectedQueries []*QueryDefinition
+	}{
+		{
+			name:            "PostgreSQL 9.3",
+			version:         "9.3.0",
+			expectedQueries: []*QueryDefinition{xminHorizonPreparedXactDefinition},
+		},
+		{
+			name:    "PostgreSQL 9.4",
+			version: "9.4.0",
+			expectedQueries: []*QueryDefinition{
+				xminHorizonActivityDefinition,
+				xminHorizonReplicationSlotDefinition,
+				xminHorizonPreparedXactDefinition,
+				xminHorizonStandbyDefinition,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version := semver.MustParse(tt.version)
+			assert.Equal(t, tt.expectedQueries, generateXminHorizonDefinitions(&version))
+		})
+	}
+}
@@ -0,0 +1,180 @@
+// Package patroni contains a client for the Patroni REST API and the collection of the Patroni cluster state
+package patroni
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Client reads the state of a Patroni cluster from the REST API of one of its members
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the Patroni REST API at baseURL. The certificate of an HTTPS endpoint is
+// verified against caCertLocation when set, otherwise against the system roots.
+func NewClient(baseURL string, timeout time.Duration, caCertLocation string) (*Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if caCertLocation != "" {
+		caCert, err := os.ReadFile(caCertLocation)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Patroni CA certificate: %w", err)
+		}
+		caCertPool := x509.NewCertPool()
+		if !caCertPool.AppendCertsFromPEM(caCert) {
+			return nil, errors.New("failed to parse Patroni CA certificate")
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: caCertPool, MinVersion: tls.VersionTLS12}
+	}
+
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: timeout, Transport: transport},
+	}, nil
+}
+
+// Cluster is the response of /cluster
+type Cluster struct {
+	Members []Member `json:"members"`
+	Pause   bool     `json:"pause"`
+}
+
+// Member is a member of the cluster. Lag is the replication lag in bytes, which Patroni reports as
+// "unknown" when it cannot compute it, so it is nil then.
+type Member struct {
+	Name           string `json:"name"`
+	Role           string `json:"role"`
+	State          string `json:"state"`
+	Host           string `json:"host"`
+	Port           int    `json:"port"`
+	Timeline       *int64 `json:"timeline"`
+	Lag            *Lag   `json:"lag"`
+	PendingRestart bool   `json:"pending_restart"`
+}
+
+// IsLeader returns true for the leader of the cluster, or of a standby cluster
+func (m Member) IsLeader() bool {
+	return m.Role == "leader" || m.Role == "standby_leader"
+}
+
+// UnmarshalJSON reads a member, leaving Lag nil when it is not a number
+func (m *Member) UnmarshalJSON(data []byte) error {
+	type member Member
+	var raw struct {
+		member
+		Lag json.RawMessage `json:"lag"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*m = Member(raw.member)
+	var lag *int64
+	if err := json.Unmarshal(raw.Lag, &lag); err == nil && lag != nil {
+		l := Lag(*lag)
+		m.Lag = &l
+	}
+	return nil
+}
+
+// Lag is a replication lag in bytes
+type Lag int64
+
+// Status is the response of /patroni, the state of the member serving the request
+type Status struct {
+	State          string `json:"state"`
+	Role           string `json:"role"`
+	Timeline       *int64 `json:"timeline"`
+	PendingRestart bool   `json:"pending_restart"`
+	Pause          bool   `json:"pause"`
+	Patroni        struct {
+		Version string `json:"version"`
+		Scope   string `json:"scope"`
+		Name    string `json:"name"`
+	} `json:"patroni"`
+}
+
+// HistoryEntry is an entry of /history, written each time a new timeline starts after a failover or switchover
+type HistoryEntry struct {
+	Timeline  int64
+	LSN       int64
+	Reason    string
+	Timestamp string
+	NewLeader string
+}
+
+// UnmarshalJSON reads an entry, which is an array of timeline, LSN, reason, timestamp and, since Patroni 2.0,
+// new leader
+func (h *HistoryEntry) UnmarshalJSON(data []byte) error {
+	var fields []json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if len(fields) < 4 {
+		return fmt.Errorf("unexpected history entry %s", string(data))
+	}
+
+	if err := json.Unmarshal(fields[0], &h.Timeline); err != nil {
+		return err
+	}
+	// the LSN and reason are informative, so they are only read when of the expected type
+	_ = json.Unmarshal(fields[1], &h.LSN)
+	_ = json.Unmarshal(fields[2], &h.Reason)
+	if err := json.Unmarshal(fields[3], &h.Timestamp); err != nil {
+		return err
+	}
+	if len(fields) > 4 {
+		_ = json.Unmarshal(fields[4], &h.NewLeader)
+	}
+	return nil
+}
+
+// Cluster returns the members of the cluster and whether it is paused
+func (c *Client) Cluster() (*Cluster, error) {
+	cluster := &Cluster{}
+	if err := c.get("/cluster", cluster); err != nil {
+		return nil, err
+	}
+	return cluster, nil
+}
+
+// Status returns the state of the member the client connects to
+func (c *Client) Status() (*Status, error) {
+	status := &Status{}
+	if err := c.get("/patroni", status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// History returns the timeline history of the cluster, oldest first
+func (c *Client) History() ([]HistoryEntry, error) {
+	history := make([]HistoryEntry, 0)
+	if err := c.get("/history", &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// get decodes the JSON response of path. /patroni answers with 503 when the member is not running, with
+// the member state in the body, so the body is decoded whatever the status code.
+func (c *Client) get(path string, v interface{}) error {
+	resp, err := c.httpClient.Get(c.baseURL + path)
+	if err != nil {
+		return fmt.Errorf("request to Patroni %s failed: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode Patroni %s response with status %d: %w", path, resp.StatusCode, err)
+	}
+	return nil
+}
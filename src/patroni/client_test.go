@@ -0,0 +1,111 @@
+package patroni
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	clusterResponse = `{
+		"members": [
+			{"name": "pg-0", "role": "leader", "state": "running", "api_url": "http://pg-0:8008/patroni", "host": "pg-0", "port": 5432, "timeline": 4},
+			{"name": "pg-1", "role": "replica", "state": "streaming", "api_url": "http://pg-1:8008/patroni", "host": "pg-1", "port": 5432, "timeline": 4, "lag": 1024, "pending_restart": true},
+			{"name": "pg-2", "role": "replica", "state": "stopped", "api_url": "http://pg-2:8008/patroni", "host": "pg-2", "port": 5432, "lag": "unknown"}
+		],
+		"pause": true
+	}`
+	statusResponse = `{
+		"state": "running",
+		"postmaster_start_time": "2026-10-18 08:00:00.000000+00:00",
+		"role": "master",
+		"server_version": 160004,
+		"timeline": 4,
+		"pending_restart": true,
+		"patroni": {"version": "3.3.2", "scope": "demo", "name": "pg-0"}
+	}`
+	historyResponse = `[
+		[1, 25165984, "no recovery target specified", "2026-10-01T10:00:00.000000+00:00"],
+		[2, 50331744, "no recovery target specified", "2026-10-10T10:00:00.000000+00:00", "pg-1"],
+		[3, 83886240, "no recovery target specified", "2026-10-17T10:00:00.123456+00:00", "pg-0"]
+	]`
+)
+
+// newTestServer serves the given responses by path, and a 404 for the others
+func newTestServer(t *testing.T, responses map[string]string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient(t *testing.T) {
+	server := newTestServer(t, map[string]string{
+		"/cluster": clusterResponse,
+		"/patroni": statusResponse,
+		"/history": historyResponse,
+	})
+
+	client, err := NewClient(server.URL+"/", time.Second, "")
+	assert.NoError(t, err)
+
+	cluster, err := client.Cluster()
+	assert.NoError(t, err)
+	assert.True(t, cluster.Pause)
+	assert.Len(t, cluster.Members, 3)
+	assert.True(t, cluster.Members[0].IsLeader())
+	assert.Nil(t, cluster.Members[0].Lag)
+	assert.Equal(t, Lag(1024), *cluster.Members[1].Lag)
+	assert.True(t, cluster.Members[1].PendingRestart)
+	assert.Nil(t, cluster.Members[2].Lag)
+	assert.Nil(t, cluster.Members[2].Timeline)
+
+	status, err := client.Status()
+	assert.NoError(t, err)
+	assert.Equal(t, "master", status.Role)
+	assert.Equal(t, int64(4), *status.Timeline)
+	assert.Equal(t, "demo", status.Patroni.Scope)
+	assert.Equal(t, "pg-0", status.Patroni.Name)
+
+	history, err := client.History()
+	assert.NoError(t, err)
+	assert.Equal(t, []HistoryEntry{
+		{Timeline: 1, LSN: 25165984, Reason: "no recovery target specified", Timestamp: "2026-10-01T10:00:00.000000+00:00"},
+		{Timeline: 2, LSN: 50331744, Reason: "no recovery target specified", Timestamp: "2026-10-10T10:00:00.000000+00:00", NewLeader: "pg-1"},
+		{Timeline: 3, LSN: 83886240, Reason: "no recovery target specified", Timestamp: "2026-10-17T10:00:00.123456+00:00", NewLeader: "pg-0"},
+	}, history)
+}
+
+func TestClient_Errors(t *testing.T) {
+	server := newTestServer(t, map[string]string{
+		"/cluster": `{"members": [`,
+	})
+
+	client, err := NewClient(server.URL, time.Second, "")
+	assert.NoError(t, err)
+
+	_, err = client.Cluster()
+	assert.Error(t, err)
+
+	_, err = client.History()
+	assert.Error(t, err)
+
+	server.Close()
+	_, err = client.Status()
+	assert.Error(t, err)
+}
+
+func TestNewClient_InvalidCACertificate(t *testing.T) {
+	_, err := NewClient("https://localhost:8008", time.Second, "/path/that/does/not/exist.pem")
+	assert.Error(t, err)
+}
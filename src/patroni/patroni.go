@@ -0,0 +1,168 @@
+package patroni
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/data/attribute"
+	"github.com/newrelic/infra-integrations-sdk/v3/data/event"
+	"github.com/newrelic/infra-integrations-sdk/v3/data/metric"
+	"github.com/newrelic/infra-integrations-sdk/v3/integration"
+	"github.com/newrelic/infra-integrations-sdk/v3/log"
+	"github.com/newrelic/infra-integrations-sdk/v3/persist"
+)
+
+// leaderStoreKey keeps the leader seen on the previous run, to report leader changes
+const leaderStoreKey = "patroni:leader"
+
+// PopulateMetrics populates a PostgresqlPatroniClusterSample with the state of the cluster and a
+// PostgresqlPatroniMemberSample for each member on the instance entity, and an event when the leader
+// changed since the previous run
+func PopulateMetrics(client *Client, instanceEntity *integration.Entity, store persist.Storer) {
+	cluster, err := client.Cluster()
+	if err != nil {
+		log.Error("Could not get Patroni cluster: %s", err.Error())
+		return
+	}
+
+	status, err := client.Status()
+	if err != nil {
+		log.Warn("Could not get Patroni member status: %s", err.Error())
+		status = &Status{}
+	}
+
+	history, err := client.History()
+	if err != nil {
+		log.Warn("Could not get Patroni history: %s", err.Error())
+	}
+
+	leader := populateClusterMetrics(instanceEntity, cluster, status, history)
+	for _, member := range cluster.Members {
+		populateMemberMetrics(instanceEntity, member)
+	}
+
+	reportLeaderChange(instanceEntity, store, status.Patroni.Scope, leader)
+}
+
+// populateClusterMetrics populates the cluster sample and returns the name of the leader
+func populateClusterMetrics(instanceEntity *integration.Entity, cluster *Cluster, status *Status, history []HistoryEntry) string {
+	metricSet := newMetricSet(instanceEntity, "PostgresqlPatroniClusterSample")
+
+	leader := ""
+	running, replicas, pendingRestart := 0, 0, 0
+	var maxLag *Lag
+	for _, member := range cluster.Members {
+		if member.IsLeader() {
+			leader = member.Name
+		} else {
+			replicas++
+		}
+		if member.State == "running" || member.State == "streaming" {
+			running++
+		}
+		if member.PendingRestart {
+			pendingRestart++
+		}
+		if member.Lag != nil && (maxLag == nil || *member.Lag > *maxLag) {
+			maxLag = member.Lag
+		}
+	}
+
+	setAttribute(metricSet, "patroni.scope", status.Patroni.Scope)
+	setAttribute(metricSet, "patroni.version", status.Patroni.Version)
+	setAttribute(metricSet, "patroni.member", status.Patroni.Name)
+	setAttribute(metricSet, "patroni.role", status.Role)
+	setAttribute(metricSet, "patroni.state", status.State)
+	setAttribute(metricSet, "patroni.leader", leader)
+	setGauge(metricSet, "patroni.members", len(cluster.Members))
+	setGauge(metricSet, "patroni.runningMembers", running)
+	setGauge(metricSet, "patroni.replicas", replicas)
+	setGauge(metricSet, "patroni.pendingRestartMembers", pendingRestart)
+	setGauge(metricSet, "patroni.paused", boolToInt(cluster.Pause || status.Pause))
+	setGauge(metricSet, "patroni.pendingRestart", boolToInt(status.PendingRestart))
+	if status.Timeline != nil {
+		setGauge(metricSet, "patroni.timeline", *status.Timeline)
+	}
+	if maxLag != nil {
+		setGauge(metricSet, "patroni.maxReplicationLagInBytes", int64(*maxLag))
+	}
+
+	if len(history) > 0 {
+		lastFailover := history[len(history)-1]
+		setAttribute(metricSet, "patroni.lastFailoverTime", lastFailover.Timestamp)
+		if failoverTime, err := time.Parse(time.RFC3339Nano, lastFailover.Timestamp); err == nil {
+			setGauge(metricSet, "patroni.secondsSinceLastFailover", time.Since(failoverTime).Seconds())
+		}
+	}
+
+	return leader
+}
+
+func populateMemberMetrics(instanceEntity *integration.Entity, member Member) {
+	metricSet := newMetricSet(instanceEntity, "PostgresqlPatroniMemberSample",
+		attribute.Attribute{Key: "patroni.member.name", Value: member.Name},
+	)
+
+	setAttribute(metricSet, "patroni.member.role", member.Role)
+	setAttribute(metricSet, "patroni.member.state", member.State)
+	setAttribute(metricSet, "patroni.member.host", member.Host)
+	setGauge(metricSet, "patroni.member.port", member.Port)
+	setGauge(metricSet, "patroni.member.pendingRestart", boolToInt(member.PendingRestart))
+	if member.Timeline != nil {
+		setGauge(metricSet, "patroni.member.timeline", *member.Timeline)
+	}
+	if member.Lag != nil {
+		setGauge(metricSet, "patroni.member.lagInBytes", int64(*member.Lag))
+	}
+}
+
+// reportLeaderChange adds an event to the instance entity when the leader is not the one of the previous run
+func reportLeaderChange(instanceEntity *integration.Entity, store persist.Storer, scope, leader string) {
+	if leader == "" {
+		return
+	}
+
+	var previousLeader string
+	if _, err := store.Get(leaderStoreKey, &previousLeader); err == nil && previousLeader != "" && previousLeader != leader {
+		summary := fmt.Sprintf("Patroni leader changed from %s to %s", previousLeader, leader)
+		leaderChange := event.NewWithAttributes(summary, "patroni", map[string]interface{}{
+			"patroni.scope":          scope,
+			"patroni.previousLeader": previousLeader,
+			"patroni.leader":         leader,
+		})
+		if err := instanceEntity.AddEvent(leaderChange); err != nil {
+			log.Error("Failed to add Patroni leader change event: %s", err.Error())
+		}
+	}
+	store.Set(leaderStoreKey, leader)
+}
+
+func newMetricSet(instanceEntity *integration.Entity, eventType string, attributes ...attribute.Attribute) *metric.Set {
+	attributes = append([]attribute.Attribute{
+		{Key: "displayName", Value: instanceEntity.Metadata.Name},
+		{Key: "entityName", Value: instanceEntity.Metadata.Namespace + ":" + instanceEntity.Metadata.Name},
+	}, attributes...)
+	return instanceEntity.NewMetricSet(eventType, attributes...)
+}
+
+func setGauge(metricSet *metric.Set, name string, value interface{}) {
+	if err := metricSet.SetMetric(name, value, metric.GAUGE); err != nil {
+		log.Error("Failed to populate Patroni metric %s: %s", name, err.Error())
+	}
+}
+
+func setAttribute(metricSet *metric.Set, name, value string) {
+	if value == "" {
+		return
+	}
+	if err := metricSet.SetMetric(name, value, metric.ATTRIBUTE); err != nil {
+		log.Error("Failed to populate Patroni attribute %s: %s", name, err.Error())
+	}
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,116 @@
+package patroni
+
+import (
+	"testing"
+	"time"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/integration"
+	"github.com/newrelic/infra-integrations-sdk/v3/persist"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPopulateMetrics(t *testing.T) {
+	server := newTestServer(t, map[string]string{
+		"/cluster": clusterResponse,
+		"/patroni": statusResponse,
+		"/history": historyResponse,
+	})
+	client, err := NewClient(server.URL, time.Second, "")
+	assert.NoError(t, err)
+
+	testIntegration, _ := integration.New("test", "test")
+	instance, _ := testIntegration.Entity("testhost:1234", "pg-instance")
+	store := persist.NewInMemoryStore()
+
+	PopulateMetrics(client, instance, store)
+
+	assert.Len(t, instance.Metrics, 4)
+	cluster := instance.Metrics[0].Metrics
+	assert.Greater(t, cluster["patroni.secondsSinceLastFailover"], float64(0))
+	delete(cluster, "patroni.secondsSinceLastFailover")
+	assert.Equal(t, map[string]interface{}{
+		"event_type":                       "PostgresqlPatroniClusterSample",
+		"displayName":                      "testhost:1234",
+		"entityName":                       "pg-instance:testhost:1234",
+		"patroni.scope":                    "demo",
+		"patroni.version":                  "3.3.2",
+		"patroni.member":                   "pg-0",
+		"patroni.role":                     "master",
+		"patroni.state":                    "running",
+		"patroni.leader":                   "pg-0",
+		"patroni.members":                  float64(3),
+		"patroni.runningMembers":           float64(2),
+		"patroni.replicas":                 float64(2),
+		"patroni.pendingRestartMembers":    float64(1),
+		"patroni.paused":                   float64(1),
+		"patroni.pendingRestart":           float64(1),
+		"patroni.timeline":                 float64(4),
+		"patroni.maxReplicationLagInBytes": float64(1024),
+		"patroni.lastFailoverTime":         "2026-10-17T10:00:00.123456+00:00",
+	}, cluster)
+
+	assert.Equal(t, map[string]interface{}{
+		"event_type":                    "PostgresqlPatroniMemberSample",
+		"displayName":                   "testhost:1234",
+		"entityName":                    "pg-instance:testhost:1234",
+		"patroni.member.name":           "pg-1",
+		"patroni.member.role":           "replica",
+		"patroni.member.state":          "streaming",
+		"patroni.member.host":           "pg-1",
+		"patroni.member.port":           float64(5432),
+		"patroni.member.pendingRestart": float64(1),
+		"patroni.member.timeline":       float64(4),
+		"patroni.member.lagInBytes":     float64(1024),
+	}, instance.Metrics[2].Metrics)
+	assert.NotContains(t, instance.Metrics[1].Metrics, "patroni.member.lagInBytes")
+	assert.Equal(t, "pg-2", instance.Metrics[3].Metrics["patroni.member.name"])
+	assert.NotContains(t, instance.Metrics[3].Metrics, "patroni.member.lagInBytes")
+
+	// no event on the first run, as there is no previous leader to compare with
+	assert.Empty(t, instance.Events)
+	var leader string
+	_, err = store.Get(leaderStoreKey, &leader)
+	assert.NoError(t, err)
+	assert.Equal(t, "pg-0", leader)
+}
+
+func TestPopulateMetrics_LeaderChange(t *testing.T) {
+	server := newTestServer(t, map[string]string{
+		"/cluster": clusterResponse,
+		"/patroni": statusResponse,
+	})
+	client, err := NewClient(server.URL, time.Second, "")
+	assert.NoError(t, err)
+
+	testIntegration, _ := integration.New("test", "test")
+	instance, _ := testIntegration.Entity("testhost:1234", "pg-instance")
+	store := persist.NewInMemoryStore()
+	store.Set(leaderStoreKey, "pg-1")
+
+	PopulateMetrics(client, instance, store)
+
+	assert.Len(t, instance.Events, 1)
+	assert.Equal(t, "Patroni leader changed from pg-1 to pg-0", instance.Events[0].Summary)
+	assert.Equal(t, "patroni", instance.Events[0].Category)
+	assert.Equal(t, "pg-1", instance.Events[0].Attributes["patroni.previousLeader"])
+	assert.Equal(t, "pg-0", instance.Events[0].Attributes["patroni.leader"])
+	assert.NotContains(t, instance.Metrics[0].Metrics, "patroni.lastFailoverTime")
+
+	// the same leader on the next run doesn't report a change again
+	PopulateMetrics(client, instance, store)
+	assert.Len(t, instance.Events, 1)
+}
+
+func TestPopulateMetrics_ClusterUnavailable(t *testing.T) {
+	server := newTestServer(t, map[string]string{})
+	client, err := NewClient(server.URL, time.Second, "")
+	assert.NoError(t, err)
+
+	testIntegration, _ := integration.New("test", "test")
+	instance, _ := testIntegration.Entity("testhost:1234", "pg-instance")
+
+	PopulateMetrics(client, instance, persist.NewInMemoryStore())
+
+	assert.Empty(t, instance.Metrics)
+	assert.Empty(t, instance.Events)
+}
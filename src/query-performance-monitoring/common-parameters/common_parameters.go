@@ -1,6 +1,8 @@
 package commonparameters
 
 import (
+	"encoding/json"
+
 	"github.com/newrelic/infra-integrations-sdk/v3/log"
 	"github.com/newrelic/nri-postgresql/src/args"
 )
@@ -22,6 +24,47 @@ type CommonParameters struct {
 	Host                                 string
 	Port                                 string
 	IsRds                                bool
+	LongRunningThresholds                LongRunningThresholds
+}
+
+// SessionThresholds are the durations, in seconds, above which a session is reported as long running.
+// A threshold of 0 disables the check. A nil threshold in an override keeps the one it overrides.
+type SessionThresholds struct {
+	Query             *int `json:"query"`
+	Transaction       *int `json:"transaction"`
+	IdleInTransaction *int `json:"idle_in_transaction"`
+}
+
+// LongRunningThresholds are the default session thresholds and their overrides per database and per application name
+type LongRunningThresholds struct {
+	Default      SessionThresholds
+	Databases    map[string]SessionThresholds `json:"databases"`
+	Applications map[string]SessionThresholds `json:"applications"`
+}
+
+// Resolve returns the thresholds of a session, overriding the default ones with the ones of its database
+// and then with the ones of its application
+func (lt LongRunningThresholds) Resolve(database, application string) (query, transaction, idleInTransaction int) {
+	resolved := lt.Default
+	for _, override := range []SessionThresholds{lt.Databases[database], lt.Applications[application]} {
+		if override.Query != nil {
+			resolved.Query = override.Query
+		}
+		if override.Transaction != nil {
+			resolved.Transaction = override.Transaction
+		}
+		if override.IdleInTransaction != nil {
+			resolved.IdleInTransaction = override.IdleInTransaction
+		}
+	}
+	return valueOrZero(resolved.Query), valueOrZero(resolved.Transaction), valueOrZero(resolved.IdleInTransaction)
+}
+
+func valueOrZero(value *int) int {
+	if value == nil {
+		return 0
+	}
+	return *value
 }
 
 func SetCommonParameters(args args.ArgumentList, version uint64, databases string) *CommonParameters {
@@ -33,7 +76,32 @@ func SetCommonParameters(args args.ArgumentList, version uint64, databases strin
 		Host:                                 args.Hostname,
 		Port:                                 args.Port,
 		IsRds:                                args.IsRds,
+		LongRunningThresholds:                validateAndGetLongRunningThresholds(args),
+	}
+}
+
+func validateAndGetLongRunningThresholds(args args.ArgumentList) LongRunningThresholds {
+	thresholds := LongRunningThresholds{}
+	if args.LongRunningThresholdOverrides != "" {
+		if err := json.Unmarshal([]byte(args.LongRunningThresholdOverrides), &thresholds); err != nil {
+			log.Warn("LongRunningThresholdOverrides should be a JSON object with databases and applications but the input is %s, ignoring the overrides: %v", args.LongRunningThresholdOverrides, err)
+			thresholds = LongRunningThresholds{}
+		}
+	}
+	thresholds.Default = SessionThresholds{
+		Query:             validateAndGetSessionThreshold("LongRunningQueryThreshold", args.LongRunningQueryThreshold),
+		Transaction:       validateAndGetSessionThreshold("LongRunningTransactionThreshold", args.LongRunningTransactionThreshold),
+		IdleInTransaction: validateAndGetSessionThreshold("IdleInTransactionThreshold", args.IdleInTransactionThreshold),
+	}
+	return thresholds
+}
+
+func validateAndGetSessionThreshold(name string, threshold int) *int {
+	if threshold < 0 {
+		log.Warn("%s should be greater than or equal to 0 but the input is %d, disabling it", name, threshold)
+		threshold = 0
 	}
+	return &threshold
 }
 
 func validateAndGetQueryMonitoringResponseTimeThreshold(args args.ArgumentList) int {
@@ -0,0 +1,44 @@
+package commonparameters
+
+import (
+	"testing"
+
+	"github.com/newrelic/nri-postgresql/src/args"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLongRunningThresholdsResolve(t *testing.T) {
+	cp := SetCommonParameters(args.ArgumentList{
+		LongRunningQueryThreshold:       300,
+		LongRunningTransactionThreshold: 600,
+		IdleInTransactionThreshold:      -1,
+		LongRunningThresholdOverrides:   `{"databases": {"reporting": {"query": 1800, "transaction": 3600}}, "applications": {"batch": {"transaction": 0}}}`,
+	}, 14, "")
+
+	tests := []struct {
+		database, application                            string
+		expectedQuery, expectedTransaction, expectedIdle int
+	}{
+		{"testdb", "web", 300, 600, 0},
+		{"reporting", "web", 1800, 3600, 0},
+		{"reporting", "batch", 1800, 0, 0},
+	}
+	for _, tt := range tests {
+		query, transaction, idle := cp.LongRunningThresholds.Resolve(tt.database, tt.application)
+		assert.Equal(t, tt.expectedQuery, query)
+		assert.Equal(t, tt.expectedTransaction, transaction)
+		assert.Equal(t, tt.expectedIdle, idle)
+	}
+}
+
+func TestLongRunningThresholdsInvalidOverrides(t *testing.T) {
+	cp := SetCommonParameters(args.ArgumentList{
+		LongRunningQueryThreshold:     300,
+		LongRunningThresholdOverrides: `["reporting"]`,
+	}, 14, "")
+
+	query, transaction, idle := cp.LongRunningThresholds.Resolve("reporting", "")
+	assert.Equal(t, 300, query)
+	assert.Equal(t, 0, transaction)
+	assert.Equal(t, 0, idle)
+}
@@ -26,6 +26,28 @@ func FetchVersionSpecificBlockingQuery(version uint64) (string, error) {
 	}
 }
 
+func FetchVersionSpecificBlockingTreeQuery(version uint64) (string, error) {
+	switch {
+	case version == PostgresVersion12, version == PostgresVersion13:
+		return queries.BlockingTreesForV12AndV13, nil
+	case version >= PostgresVersion14:
+		return queries.BlockingTreesForV14AndAbove, nil
+	default:
+		return "", ErrUnsupportedVersion
+	}
+}
+
+func FetchVersionSpecificLongRunningSessionsQuery(version uint64) (string, error) {
+	switch {
+	case version == PostgresVersion12, version == PostgresVersion13:
+		return queries.LongRunningSessionsForV12AndV13, nil
+	case version >= PostgresVersion14:
+		return queries.LongRunningSessionsForV14AndAbove, nil
+	default:
+		return "", ErrUnsupportedVersion
+	}
+}
+
 func FetchVersionSpecificIndividualQueries(version uint64) (string, error) {
 	switch {
 	case version == PostgresVersion12:
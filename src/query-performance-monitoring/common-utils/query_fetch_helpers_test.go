@@ -54,6 +54,36 @@ func TestFetchVersionSpecificBlockingQueries(t *testing.T) {
 	runTestCases(t, tests, commonutils.FetchVersionSpecificBlockingQuery)
 }
 
+func TestFetchVersionSpecificBlockingTreeQueries(t *testing.T) {
+	tests := []struct {
+		version   uint64
+		expected  string
+		expectErr bool
+	}{
+		{commonutils.PostgresVersion12, queries.BlockingTreesForV12AndV13, false},
+		{commonutils.PostgresVersion13, queries.BlockingTreesForV12AndV13, false},
+		{commonutils.PostgresVersion14, queries.BlockingTreesForV14AndAbove, false},
+		{commonutils.PostgresVersion11, "", true},
+	}
+
+	runTestCases(t, tests, commonutils.FetchVersionSpecificBlockingTreeQuery)
+}
+
+func TestFetchVersionSpecificLongRunningSessionsQueries(t *testing.T) {
+	tests := []struct {
+		version   uint64
+		expected  string
+		expectErr bool
+	}{
+		{commonutils.PostgresVersion12, queries.LongRunningSessionsForV12AndV13, false},
+		{commonutils.PostgresVersion13, queries.LongRunningSessionsForV12AndV13, false},
+		{commonutils.PostgresVersion14, queries.LongRunningSessionsForV14AndAbove, false},
+		{commonutils.PostgresVersion11, "", true},
+	}
+
+	runTestCases(t, tests, commonutils.FetchVersionSpecificLongRunningSessionsQuery)
+}
+
 func TestFetchVersionSpecificIndividualQueries(t *testing.T) {
 	tests := []struct {
 		version   uint64
@@ -37,6 +37,48 @@ type BlockingSessionMetrics struct {
 	BlockingQueryStart *string `db:"blocking_query_start" metric_name:"blocking_query_start" source_type:"attribute"`
 }
 
+// BlockingTreeMetrics is a session waiting on a lock, with the root blocker of its blocking tree
+type BlockingTreeMetrics struct {
+	Newrelic                      *string  `db:"newrelic"                                  metric_name:"newrelic"                                  source_type:"attribute" ingest_data:"false"`
+	RootBlockerPid                *int64   `db:"root_blocker_pid"                          metric_name:"root_blocker_pid"                          source_type:"gauge"`
+	RootBlockerState              *string  `db:"root_blocker_state"                        metric_name:"root_blocker_state"                        source_type:"attribute"`
+	RootBlockerQuery              *string  `db:"root_blocker_query"                        metric_name:"root_blocker_query"                        source_type:"attribute"`
+	RootBlockerUser               *string  `db:"root_blocker_user"                         metric_name:"root_blocker_user"                         source_type:"attribute"`
+	RootBlockerApplication        *string  `db:"root_blocker_application"                  metric_name:"root_blocker_application"                  source_type:"attribute"`
+	RootBlockerTransactionSeconds *float64 `db:"root_blocker_transaction_duration_seconds" metric_name:"root_blocker_transaction_duration_seconds" source_type:"gauge"`
+	RootBlockedSessions           *int64   `db:"root_blocked_sessions"                     metric_name:"root_blocked_sessions"                     source_type:"gauge"`
+	ChainDepth                    *int64   `db:"chain_depth"                               metric_name:"chain_depth"                               source_type:"gauge"`
+	BlockedPid                    *int64   `db:"blocked_pid"                               metric_name:"blocked_pid"                               source_type:"gauge"`
+	BlockedDepth                  *int64   `db:"blocked_depth"                             metric_name:"blocked_depth"                             source_type:"gauge"`
+	BlockingPids                  *string  `db:"blocking_pids"                             metric_name:"blocking_pids"                             source_type:"attribute"`
+	BlockedDatabase               *string  `db:"database_name"                             metric_name:"database_name"                             source_type:"attribute"`
+	BlockedQuery                  *string  `db:"blocked_query"                             metric_name:"blocked_query"                             source_type:"attribute"`
+	BlockedQueryStart             *string  `db:"blocked_query_start"                       metric_name:"blocked_query_start"                       source_type:"attribute"`
+	WaitDurationSeconds           *float64 `db:"wait_duration_seconds"                     metric_name:"wait_duration_seconds"                     source_type:"gauge"`
+	LockMode                      *string  `db:"lock_mode"                                 metric_name:"lock_mode"                                 source_type:"attribute"`
+	LockType                      *string  `db:"lock_type"                                 metric_name:"lock_type"                                 source_type:"attribute"`
+	Relation                      *string  `db:"relation"                                  metric_name:"relation"                                  source_type:"attribute"`
+}
+
+// LongRunningSessionMetrics is a session exceeding at least one of its long running thresholds
+type LongRunningSessionMetrics struct {
+	Newrelic                         *string  `db:"newrelic"                             metric_name:"newrelic"                             source_type:"attribute" ingest_data:"false"`
+	Pid                              *int64   `db:"pid"                                  metric_name:"pid"                                  source_type:"gauge"`
+	UserName                         *string  `db:"user_name"                            metric_name:"user_name"                            source_type:"attribute"`
+	ApplicationName                  *string  `db:"application_name"                     metric_name:"application_name"                     source_type:"attribute"`
+	ClientAddress                    *string  `db:"client_address"                       metric_name:"client_address"                       source_type:"attribute"`
+	DatabaseName                     *string  `db:"database_name"                        metric_name:"database_name"                        source_type:"attribute"`
+	State                            *string  `db:"state"                                metric_name:"state"                                source_type:"attribute"`
+	WaitEventType                    *string  `db:"wait_event_type"                      metric_name:"wait_event_type"                      source_type:"attribute"`
+	WaitEvent                        *string  `db:"wait_event"                           metric_name:"wait_event"                           source_type:"attribute"`
+	QueryText                        *string  `db:"query_text"                           metric_name:"query_text"                           source_type:"attribute"`
+	QueryID                          *string  `db:"query_id"                             metric_name:"query_id"                             source_type:"attribute"`
+	QueryDurationSeconds             *float64 `db:"query_duration_seconds"               metric_name:"query_duration_seconds"               source_type:"gauge"`
+	TransactionDurationSeconds       *float64 `db:"transaction_duration_seconds"         metric_name:"transaction_duration_seconds"         source_type:"gauge"`
+	IdleInTransactionDurationSeconds *float64 `db:"idle_in_transaction_duration_seconds" metric_name:"idle_in_transaction_duration_seconds" source_type:"gauge"`
+	ExceededThresholds               *string  `db:"-"                                    metric_name:"exceeded_thresholds"                  source_type:"attribute"`
+}
+
 type IndividualQueryMetrics struct {
 	QueryText       *string  `json:"query" db:"query" metric_name:"query_text" source_type:"attribute"`
 	QueryID         *string  `json:"queryid" db:"queryid" metric_name:"query_id" source_type:"attribute"`
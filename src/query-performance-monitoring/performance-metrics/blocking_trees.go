@@ -0,0 +1,63 @@
+package performancemetrics
+
+import (
+	"fmt"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/integration"
+	"github.com/newrelic/infra-integrations-sdk/v3/log"
+	performancedbconnection "github.com/newrelic/nri-postgresql/src/connection"
+	commonparameters "github.com/newrelic/nri-postgresql/src/query-performance-monitoring/common-parameters"
+	commonutils "github.com/newrelic/nri-postgresql/src/query-performance-monitoring/common-utils"
+	"github.com/newrelic/nri-postgresql/src/query-performance-monitoring/datamodels"
+)
+
+// PopulateBlockingTreeMetrics reports every session waiting on a lock with the root blocker of its blocking tree.
+// Unlike the blocking sessions, it relies on pg_blocking_pids only and needs no extension.
+func PopulateBlockingTreeMetrics(conn *performancedbconnection.PGSQLConnection, pgIntegration *integration.Integration, cp *commonparameters.CommonParameters) {
+	blockingTreeMetricsList, err := getBlockingTreeMetrics(conn, cp)
+	if err != nil {
+		log.Error("Error fetching blocking trees: %v", err)
+		return
+	}
+	if len(blockingTreeMetricsList) == 0 {
+		log.Debug("No blocking trees found.")
+		return
+	}
+	err = commonutils.IngestMetric(blockingTreeMetricsList, "PostgresBlockingTrees", pgIntegration, cp)
+	if err != nil {
+		log.Error("Error ingesting blocking trees: %v", err)
+		return
+	}
+}
+
+func getBlockingTreeMetrics(conn *performancedbconnection.PGSQLConnection, cp *commonparameters.CommonParameters) ([]interface{}, error) {
+	var blockingTreeMetricsList []interface{}
+	versionSpecificBlockingTreeQuery, err := commonutils.FetchVersionSpecificBlockingTreeQuery(cp.Version)
+	if err != nil {
+		log.Error("Unsupported postgres version: %v", err)
+		return nil, err
+	}
+	var query = fmt.Sprintf(versionSpecificBlockingTreeQuery, cp.Databases, cp.QueryMonitoringCountThreshold)
+	rows, err := conn.Queryx(query)
+	if err != nil {
+		log.Error("Failed to execute query: %v", err)
+		return nil, commonutils.ErrUnExpectedError
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var blockingTreeMetric datamodels.BlockingTreeMetrics
+		if scanError := rows.StructScan(&blockingTreeMetric); scanError != nil {
+			return nil, scanError
+		}
+		// The queries come from pg_stat_activity, which does not anonymize them
+		if blockingTreeMetric.BlockedQuery != nil {
+			*blockingTreeMetric.BlockedQuery = commonutils.AnonymizeQueryText(*blockingTreeMetric.BlockedQuery)
+		}
+		if blockingTreeMetric.RootBlockerQuery != nil {
+			*blockingTreeMetric.RootBlockerQuery = commonutils.AnonymizeQueryText(*blockingTreeMetric.RootBlockerQuery)
+		}
+		blockingTreeMetricsList = append(blockingTreeMetricsList, blockingTreeMetric)
+	}
+
+	return blockingTreeMetricsList, nil
+}
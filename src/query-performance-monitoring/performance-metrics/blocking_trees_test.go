@@ -0,0 +1,72 @@
+package performancemetrics
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/newrelic/nri-postgresql/src/args"
+	"github.com/newrelic/nri-postgresql/src/connection"
+	common_parameters "github.com/newrelic/nri-postgresql/src/query-performance-monitoring/common-parameters"
+	commonutils "github.com/newrelic/nri-postgresql/src/query-performance-monitoring/common-utils"
+	"github.com/newrelic/nri-postgresql/src/query-performance-monitoring/datamodels"
+	"github.com/newrelic/nri-postgresql/src/query-performance-monitoring/queries"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestGetBlockingTreeMetrics(t *testing.T) {
+	conn, mock := connection.CreateMockSQL(t)
+	args := args.ArgumentList{QueryMonitoringCountThreshold: 10}
+	databaseName := "testdb"
+	version := uint64(14)
+	cp := common_parameters.SetCommonParameters(args, version, databaseName)
+	query := fmt.Sprintf(queries.BlockingTreesForV14AndAbove, databaseName, args.QueryMonitoringCountThreshold)
+	mockRows := sqlmock.NewRows([]string{
+		"newrelic", "root_blocker_pid", "root_blocker_state", "root_blocker_query", "root_blocked_sessions", "chain_depth",
+		"blocked_pid", "blocked_depth", "blocking_pids", "database_name", "blocked_query", "wait_duration_seconds",
+		"lock_mode", "lock_type", "relation",
+	}).AddRow(
+		"newrelic", int64(100), "idle in transaction", "UPDATE t SET a = 1 WHERE id = 42", int64(2), int64(2),
+		int64(200), int64(2), "150", "testdb", "SELECT * FROM t WHERE id = 42 FOR UPDATE", 12.5,
+		"RowShareLock", "relation", "public.t",
+	)
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WillReturnRows(mockRows)
+
+	blockingTreeMetricsList, err := getBlockingTreeMetrics(conn, cp)
+
+	assert.NoError(t, err)
+	assert.Len(t, blockingTreeMetricsList, 1)
+	blockingTree := blockingTreeMetricsList[0].(datamodels.BlockingTreeMetrics)
+	assert.Equal(t, int64(100), *blockingTree.RootBlockerPid)
+	assert.Equal(t, "idle in transaction", *blockingTree.RootBlockerState)
+	assert.Equal(t, "UPDATE t SET a = ? WHERE id = ?", *blockingTree.RootBlockerQuery)
+	assert.Equal(t, int64(2), *blockingTree.RootBlockedSessions)
+	assert.Equal(t, int64(2), *blockingTree.ChainDepth)
+	assert.Equal(t, int64(200), *blockingTree.BlockedPid)
+	assert.Equal(t, "150", *blockingTree.BlockingPids)
+	assert.Equal(t, "SELECT * FROM t WHERE id = ? FOR UPDATE", *blockingTree.BlockedQuery)
+	assert.Equal(t, 12.5, *blockingTree.WaitDurationSeconds)
+	assert.Equal(t, "RowShareLock", *blockingTree.LockMode)
+	assert.Equal(t, "public.t", *blockingTree.Relation)
+	assert.Nil(t, blockingTree.RootBlockerUser)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetBlockingTreeMetricsErr(t *testing.T) {
+	conn, mock := connection.CreateMockSQL(t)
+	args := args.ArgumentList{QueryMonitoringCountThreshold: 10}
+	cp := common_parameters.SetCommonParameters(args, uint64(13), "testdb")
+	_, err := getBlockingTreeMetrics(conn, cp)
+	assert.EqualError(t, err, commonutils.ErrUnExpectedError.Error())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetBlockingTreeMetricsUnsupportedVersion(t *testing.T) {
+	conn, mock := connection.CreateMockSQL(t)
+	args := args.ArgumentList{QueryMonitoringCountThreshold: 10}
+	cp := common_parameters.SetCommonParameters(args, uint64(11), "testdb")
+	_, err := getBlockingTreeMetrics(conn, cp)
+	assert.EqualError(t, err, commonutils.ErrUnsupportedVersion.Error())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,98 @@
+package performancemetrics
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/integration"
+	"github.com/newrelic/infra-integrations-sdk/v3/log"
+	performancedbconnection "github.com/newrelic/nri-postgresql/src/connection"
+	commonparameters "github.com/newrelic/nri-postgresql/src/query-performance-monitoring/common-parameters"
+	commonutils "github.com/newrelic/nri-postgresql/src/query-performance-monitoring/common-utils"
+	"github.com/newrelic/nri-postgresql/src/query-performance-monitoring/datamodels"
+)
+
+// PopulateLongRunningSessionMetrics reports the sessions running a query, holding a transaction open or idle in
+// transaction for longer than the thresholds of their database and application
+func PopulateLongRunningSessionMetrics(conn *performancedbconnection.PGSQLConnection, pgIntegration *integration.Integration, cp *commonparameters.CommonParameters) {
+	longRunningSessionsList, err := getLongRunningSessionMetrics(conn, cp)
+	if err != nil {
+		log.Error("Error fetching long running sessions: %v", err)
+		return
+	}
+	if len(longRunningSessionsList) == 0 {
+		log.Debug("No long running sessions found.")
+		return
+	}
+	err = commonutils.IngestMetric(longRunningSessionsList, "PostgresLongRunningSessions", pgIntegration, cp)
+	if err != nil {
+		log.Error("Error ingesting long running sessions: %v", err)
+		return
+	}
+}
+
+func getLongRunningSessionMetrics(conn *performancedbconnection.PGSQLConnection, cp *commonparameters.CommonParameters) ([]interface{}, error) {
+	var longRunningSessionsList []interface{}
+	versionSpecificLongRunningSessionsQuery, err := commonutils.FetchVersionSpecificLongRunningSessionsQuery(cp.Version)
+	if err != nil {
+		log.Error("Unsupported postgres version: %v", err)
+		return nil, err
+	}
+	var query = fmt.Sprintf(versionSpecificLongRunningSessionsQuery, cp.Databases)
+	rows, err := conn.Queryx(query)
+	if err != nil {
+		log.Error("Failed to execute query: %v", err)
+		return nil, commonutils.ErrUnExpectedError
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var session datamodels.LongRunningSessionMetrics
+		if scanError := rows.StructScan(&session); scanError != nil {
+			return nil, scanError
+		}
+		exceeded := getExceededThresholds(session, cp.LongRunningThresholds)
+		if len(exceeded) == 0 {
+			continue
+		}
+		exceededThresholds := strings.Join(exceeded, ",")
+		session.ExceededThresholds = &exceededThresholds
+		if session.QueryText != nil {
+			*session.QueryText = commonutils.AnonymizeQueryText(*session.QueryText)
+		}
+		longRunningSessionsList = append(longRunningSessionsList, session)
+		// The sessions come with the oldest transactions first
+		if len(longRunningSessionsList) == cp.QueryMonitoringCountThreshold {
+			break
+		}
+	}
+
+	return longRunningSessionsList, nil
+}
+
+// getExceededThresholds returns the names of the thresholds exceeded by the session
+func getExceededThresholds(session datamodels.LongRunningSessionMetrics, thresholds commonparameters.LongRunningThresholds) []string {
+	var database, application string
+	if session.DatabaseName != nil {
+		database = *session.DatabaseName
+	}
+	if session.ApplicationName != nil {
+		application = *session.ApplicationName
+	}
+	queryThreshold, transactionThreshold, idleInTransactionThreshold := thresholds.Resolve(database, application)
+
+	exceeded := make([]string, 0, 3)
+	if exceedsThreshold(session.QueryDurationSeconds, queryThreshold) {
+		exceeded = append(exceeded, "query")
+	}
+	if exceedsThreshold(session.TransactionDurationSeconds, transactionThreshold) {
+		exceeded = append(exceeded, "transaction")
+	}
+	if exceedsThreshold(session.IdleInTransactionDurationSeconds, idleInTransactionThreshold) {
+		exceeded = append(exceeded, "idle_in_transaction")
+	}
+	return exceeded
+}
+
+func exceedsThreshold(duration *float64, threshold int) bool {
+	return threshold > 0 && duration != nil && *duration > float64(threshold)
+}
@@ -0,0 +1,70 @@
+package performancemetrics
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/newrelic/nri-postgresql/src/args"
+	"github.com/newrelic/nri-postgresql/src/connection"
+	common_parameters "github.com/newrelic/nri-postgresql/src/query-performance-monitoring/common-parameters"
+	commonutils "github.com/newrelic/nri-postgresql/src/query-performance-monitoring/common-utils"
+	"github.com/newrelic/nri-postgresql/src/query-performance-monitoring/datamodels"
+	"github.com/newrelic/nri-postgresql/src/query-performance-monitoring/queries"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestGetLongRunningSessionMetrics(t *testing.T) {
+	conn, mock := connection.CreateMockSQL(t)
+	args := args.ArgumentList{
+		QueryMonitoringCountThreshold:   10,
+		LongRunningQueryThreshold:       300,
+		LongRunningTransactionThreshold: 600,
+		IdleInTransactionThreshold:      60,
+		LongRunningThresholdOverrides:   `{"databases": {"reporting": {"query": 1800}}, "applications": {"batch": {"transaction": 0}}}`,
+	}
+	databaseName := "'testdb','reporting'"
+	cp := common_parameters.SetCommonParameters(args, uint64(14), databaseName)
+	query := fmt.Sprintf(queries.LongRunningSessionsForV14AndAbove, databaseName)
+	mockRows := sqlmock.NewRows([]string{
+		"newrelic", "pid", "user_name", "application_name", "client_address", "database_name", "state",
+		"wait_event_type", "wait_event", "query_text", "query_id",
+		"query_duration_seconds", "transaction_duration_seconds", "idle_in_transaction_duration_seconds",
+	}).AddRow(
+		"newrelic", int64(100), "app", "web", "10.0.0.1", "testdb", "idle in transaction",
+		"Client", "ClientRead", "UPDATE t SET a = 1 WHERE id = 42", "123",
+		nil, 3600.0, 120.0,
+	).AddRow(
+		"newrelic", int64(200), "analyst", "psql", nil, "reporting", "active",
+		nil, nil, "SELECT count(*) FROM events", "456",
+		900.0, 500.0, nil,
+	).AddRow(
+		"newrelic", int64(300), "batch", "batch", "10.0.0.2", "testdb", "active",
+		"IO", "DataFileRead", "DELETE FROM events WHERE day < '2024-01-01'", "789",
+		30.0, 7200.0, nil,
+	)
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WillReturnRows(mockRows)
+
+	sessions, err := getLongRunningSessionMetrics(conn, cp)
+
+	assert.NoError(t, err)
+	// The query of the reporting session is below its database threshold and the batch application has no transaction threshold
+	assert.Len(t, sessions, 1)
+	session := sessions[0].(datamodels.LongRunningSessionMetrics)
+	assert.Equal(t, int64(100), *session.Pid)
+	assert.Equal(t, "transaction,idle_in_transaction", *session.ExceededThresholds)
+	assert.Equal(t, "UPDATE t SET a = ? WHERE id = ?", *session.QueryText)
+	assert.Equal(t, "10.0.0.1", *session.ClientAddress)
+	assert.Equal(t, "ClientRead", *session.WaitEvent)
+	assert.Equal(t, "123", *session.QueryID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetLongRunningSessionMetricsErr(t *testing.T) {
+	conn, mock := connection.CreateMockSQL(t)
+	cp := common_parameters.SetCommonParameters(args.ArgumentList{}, uint64(13), "testdb")
+	_, err := getLongRunningSessionMetrics(conn, cp)
+	assert.EqualError(t, err, commonutils.ErrUnExpectedError.Error())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
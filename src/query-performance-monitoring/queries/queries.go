@@ -236,6 +236,163 @@ const (
 		ORDER BY blocked_activity.query_start ASC -- Order by the start time of the blocked query in ascending order
 		LIMIT %d; -- Limit the number of results`
 
+	// BlockingTreesForV12AndV13 is BlockingTreesForV14AndAbove for PostgreSQL versions 12 and 13, which do not record when a lock wait started,
+	// so the wait duration is measured from the last state change of the blocked session
+	BlockingTreesForV12AndV13 = `WITH RECURSIVE sessions AS (
+		SELECT pid, datname, usename, application_name, state, query, query_start, xact_start, state_change,
+			pg_blocking_pids(pid) AS blocked_by -- Sessions holding the lock this session waits for, or queued ahead of it
+		FROM pg_stat_activity
+	), tree AS (
+		SELECT s.pid AS root_pid, s.pid, 0 AS depth
+		FROM sessions s
+		WHERE cardinality(s.blocked_by) = 0
+			AND EXISTS (SELECT 1 FROM sessions w WHERE s.pid = ANY(w.blocked_by))
+		UNION ALL
+		SELECT t.root_pid, w.pid, t.depth + 1
+		FROM tree t
+		JOIN sessions w ON t.pid = ANY(w.blocked_by)
+		WHERE t.depth < 32 -- Stops on the cycles of deadlocks not yet detected
+	), chains AS (
+		SELECT root_pid, pid, min(depth) AS depth,
+			count(*) OVER (PARTITION BY root_pid) AS root_blocked_sessions,
+			max(min(depth)) OVER (PARTITION BY root_pid) AS chain_depth
+		FROM tree
+		WHERE depth > 0
+		GROUP BY root_pid, pid
+	)
+	SELECT 'newrelic' as newrelic, -- Common value to filter with like operator in slow query metrics
+		c.root_pid AS root_blocker_pid, -- Process ID of the session at the root of the blocking tree
+		r.state AS root_blocker_state, -- State of the root blocker, such as idle in transaction
+		LEFT(r.query, 4095) AS root_blocker_query, -- Current or last query of the root blocker truncated to 4095 characters
+		r.usename AS root_blocker_user, -- User of the root blocker
+		r.application_name AS root_blocker_application, -- Application of the root blocker
+		EXTRACT(EPOCH FROM now() - r.xact_start) AS root_blocker_transaction_duration_seconds, -- Age of the transaction of the root blocker
+		c.root_blocked_sessions, -- Number of sessions blocked directly or transitively by the root blocker
+		c.chain_depth, -- Length of the longest chain from the root blocker
+		b.pid AS blocked_pid, -- Process ID of the blocked session
+		c.depth AS blocked_depth, -- Distance of the blocked session from the root blocker
+		array_to_string(b.blocked_by, ',') AS blocking_pids, -- Sessions blocking this session directly
+		b.datname AS database_name, -- Name of the database
+		LEFT(b.query, 4095) AS blocked_query, -- Blocked query text truncated to 4095 characters
+		b.query_start AS blocked_query_start, -- Start time of the blocked query
+		EXTRACT(EPOCH FROM now() - b.state_change) AS wait_duration_seconds, -- Time spent waiting for the lock
+		l.mode AS lock_mode, -- Mode of the awaited lock
+		l.locktype AS lock_type, -- Type of the awaited lock
+		CASE WHEN l.database = (SELECT oid FROM pg_database WHERE datname = current_database())
+			THEN l.relation::regclass::text ELSE l.relation::text END AS relation -- Relation of the awaited lock, an OID when in another database
+	FROM chains c
+	JOIN sessions r ON r.pid = c.root_pid
+	JOIN sessions b ON b.pid = c.pid
+	LEFT JOIN pg_locks l ON l.pid = b.pid AND NOT l.granted
+	WHERE b.datname IN (%s) -- List of database names
+		AND b.query NOT LIKE 'EXPLAIN (FORMAT JSON) %%' -- Exclude EXPLAIN queries
+	ORDER BY wait_duration_seconds DESC NULLS LAST -- Longest waits first
+	LIMIT %d; -- Limit the number of results`
+
+	// BlockingTreesForV14AndAbove retrieves the sessions waiting on locks for PostgreSQL version 14 and above, walking pg_blocking_pids from each root blocker,
+	// a session blocking others without being blocked itself, so that sessions blocked through other blocked sessions are included.
+	// pg_blocking_pids reports the leader of a parallel query when one of its workers holds or awaits the lock.
+	BlockingTreesForV14AndAbove = `WITH RECURSIVE sessions AS (
+		SELECT pid, datname, usename, application_name, state, query, query_start, xact_start, state_change,
+			pg_blocking_pids(pid) AS blocked_by -- Sessions holding the lock this session waits for, or queued ahead of it
+		FROM pg_stat_activity
+	), tree AS (
+		SELECT s.pid AS root_pid, s.pid, 0 AS depth
+		FROM sessions s
+		WHERE cardinality(s.blocked_by) = 0
+			AND EXISTS (SELECT 1 FROM sessions w WHERE s.pid = ANY(w.blocked_by))
+		UNION ALL
+		SELECT t.root_pid, w.pid, t.depth + 1
+		FROM tree t
+		JOIN sessions w ON t.pid = ANY(w.blocked_by)
+		WHERE t.depth < 32 -- Stops on the cycles of deadlocks not yet detected
+	), chains AS (
+		SELECT root_pid, pid, min(depth) AS depth,
+			count(*) OVER (PARTITION BY root_pid) AS root_blocked_sessions,
+			max(min(depth)) OVER (PARTITION BY root_pid) AS chain_depth
+		FROM tree
+		WHERE depth > 0
+		GROUP BY root_pid, pid
+	)
+	SELECT 'newrelic' as newrelic, -- Common value to filter with like operator in slow query metrics
+		c.root_pid AS root_blocker_pid, -- Process ID of the session at the root of the blocking tree
+		r.state AS root_blocker_state, -- State of the root blocker, such as idle in transaction
+		LEFT(r.query, 4095) AS root_blocker_query, -- Current or last query of the root blocker truncated to 4095 characters
+		r.usename AS root_blocker_user, -- User of the root blocker
+		r.application_name AS root_blocker_application, -- Application of the root blocker
+		EXTRACT(EPOCH FROM now() - r.xact_start) AS root_blocker_transaction_duration_seconds, -- Age of the transaction of the root blocker
+		c.root_blocked_sessions, -- Number of sessions blocked directly or transitively by the root blocker
+		c.chain_depth, -- Length of the longest chain from the root blocker
+		b.pid AS blocked_pid, -- Process ID of the blocked session
+		c.depth AS blocked_depth, -- Distance of the blocked session from the root blocker
+		array_to_string(b.blocked_by, ',') AS blocking_pids, -- Sessions blocking this session directly
+		b.datname AS database_name, -- Name of the database
+		LEFT(b.query, 4095) AS blocked_query, -- Blocked query text truncated to 4095 characters
+		b.query_start AS blocked_query_start, -- Start time of the blocked query
+		EXTRACT(EPOCH FROM now() - l.waitstart) AS wait_duration_seconds, -- Time spent waiting for the lock
+		l.mode AS lock_mode, -- Mode of the awaited lock
+		l.locktype AS lock_type, -- Type of the awaited lock
+		CASE WHEN l.database = (SELECT oid FROM pg_database WHERE datname = current_database())
+			THEN l.relation::regclass::text ELSE l.relation::text END AS relation -- Relation of the awaited lock, an OID when in another database
+	FROM chains c
+	JOIN sessions r ON r.pid = c.root_pid
+	JOIN sessions b ON b.pid = c.pid
+	LEFT JOIN pg_locks l ON l.pid = b.pid AND NOT l.granted
+	WHERE b.datname IN (%s) -- List of database names
+		AND b.query NOT LIKE 'EXPLAIN (FORMAT JSON) %%' -- Exclude EXPLAIN queries
+	ORDER BY wait_duration_seconds DESC NULLS LAST -- Longest waits first
+	LIMIT %d; -- Limit the number of results`
+
+	// LongRunningSessionsForV12AndV13 retrieves the client sessions with an open transaction for PostgreSQL versions 12 and 13, where pg_stat_activity has no query ID, the ones that can
+	// exceed the long running thresholds, which are applied afterwards as they depend on the database and application of each session
+	LongRunningSessionsForV12AndV13 = `SELECT 'newrelic' as newrelic, -- Common value to filter with like operator in slow query metrics
+		pid, -- Process ID of the session
+		usename AS user_name, -- User of the session
+		application_name, -- Application of the session
+		host(client_addr) AS client_address, -- Client address, empty for Unix socket connections
+		datname AS database_name, -- Name of the database
+		state, -- State of the session
+		wait_event_type, -- Type of event the session waits for, if any
+		wait_event, -- Event the session waits for, if any
+		LEFT(query, 4095) AS query_text, -- Current or last query truncated to 4095 characters
+		NULL::text AS query_id, -- Unique identifier for the query
+		CASE WHEN state = 'active' THEN EXTRACT(EPOCH FROM now() - query_start) END AS query_duration_seconds, -- Duration of the running query
+		EXTRACT(EPOCH FROM now() - xact_start) AS transaction_duration_seconds, -- Duration of the open transaction
+		CASE WHEN state IN ('idle in transaction', 'idle in transaction (aborted)')
+			THEN EXTRACT(EPOCH FROM now() - state_change) END AS idle_in_transaction_duration_seconds -- Time spent idle in transaction
+	FROM pg_stat_activity
+	WHERE backend_type = 'client backend'
+		AND pid <> pg_backend_pid()
+		AND xact_start IS NOT NULL
+		AND datname IN (%s) -- List of database names
+		AND query NOT LIKE 'EXPLAIN (FORMAT JSON) %%' -- Exclude EXPLAIN queries
+	ORDER BY xact_start ASC; -- Oldest transactions first`
+
+	// LongRunningSessionsForV14AndAbove retrieves the client sessions with an open transaction for PostgreSQL version 14 and above, the ones that can
+	// exceed the long running thresholds, which are applied afterwards as they depend on the database and application of each session
+	LongRunningSessionsForV14AndAbove = `SELECT 'newrelic' as newrelic, -- Common value to filter with like operator in slow query metrics
+		pid, -- Process ID of the session
+		usename AS user_name, -- User of the session
+		application_name, -- Application of the session
+		host(client_addr) AS client_address, -- Client address, empty for Unix socket connections
+		datname AS database_name, -- Name of the database
+		state, -- State of the session
+		wait_event_type, -- Type of event the session waits for, if any
+		wait_event, -- Event the session waits for, if any
+		LEFT(query, 4095) AS query_text, -- Current or last query truncated to 4095 characters
+		query_id::text AS query_id, -- Unique identifier for the query
+		CASE WHEN state = 'active' THEN EXTRACT(EPOCH FROM now() - query_start) END AS query_duration_seconds, -- Duration of the running query
+		EXTRACT(EPOCH FROM now() - xact_start) AS transaction_duration_seconds, -- Duration of the open transaction
+		CASE WHEN state IN ('idle in transaction', 'idle in transaction (aborted)')
+			THEN EXTRACT(EPOCH FROM now() - state_change) END AS idle_in_transaction_duration_seconds -- Time spent idle in transaction
+	FROM pg_stat_activity
+	WHERE backend_type = 'client backend'
+		AND pid <> pg_backend_pid()
+		AND xact_start IS NOT NULL
+		AND datname IN (%s) -- List of database names
+		AND query NOT LIKE 'EXPLAIN (FORMAT JSON) %%' -- Exclude EXPLAIN queries
+	ORDER BY xact_start ASC; -- Oldest transactions first`
+
 	// IndividualQuerySearchV13AndAbove retrieves individual query statistics for PostgreSQL version 13 and above
 	IndividualQuerySearchV13AndAbove = `SELECT 'newrelic' as newrelic, -- Common value to filter with like operator in slow query metrics
 		 LEFT(query, 4095) as query, -- Query text truncated to 4095 characters
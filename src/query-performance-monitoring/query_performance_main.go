@@ -64,6 +64,16 @@ func populateQueryPerformanceMetrics(newConnection *performancedbconnection.PGSQ
 		performancemetrics.PopulateBlockingMetrics(newConnection, pgIntegration, cp, enabledExtensions)
 		log.Debug("PopulateBlockingMetrics completed in ", time.Since(start))
 
+		start = time.Now()
+		log.Debug("Starting PopulateBlockingTreeMetrics at ", start)
+		performancemetrics.PopulateBlockingTreeMetrics(newConnection, pgIntegration, cp)
+		log.Debug("PopulateBlockingTreeMetrics completed in ", time.Since(start))
+
+		start = time.Now()
+		log.Debug("Starting PopulateLongRunningSessionMetrics at ", start)
+		performancemetrics.PopulateLongRunningSessionMetrics(newConnection, pgIntegration, cp)
+		log.Debug("PopulateLongRunningSessionMetrics completed in ", time.Since(start))
+
 		start = time.Now()
 		log.Debug("Starting PopulateSlowRunningMetrics at ", start)
 		slowRunningQueries := performancemetrics.PopulateSlowRunningMetrics(newConnection, pgIntegration, cp, enabledExtensions)
@@ -111,5 +121,15 @@ func populateQueryPerformanceMetrics(newConnection *performancedbconnection.PGSQ
 		log.Debug("Starting PopulateBlockingMetricsPgStat at ", start)
 		performancemetrics.PopulateBlockingMetricsPgStat(newConnection, pgIntegration, cp, enabledExtensions, slowQueries)
 		log.Debug("PopulateBlockingMetrics completed in ", time.Since(start))
+
+		start = time.Now()
+		log.Debug("Starting PopulateBlockingTreeMetrics at ", start)
+		performancemetrics.PopulateBlockingTreeMetrics(newConnection, pgIntegration, cp)
+		log.Debug("PopulateBlockingTreeMetrics completed in ", time.Since(start))
+
+		start = time.Now()
+		log.Debug("Starting PopulateLongRunningSessionMetrics at ", start)
+		performancemetrics.PopulateLongRunningSessionMetrics(newConnection, pgIntegration, cp)
+		log.Debug("PopulateLongRunningSessionMetrics completed in ", time.Since(start))
 	}
 }
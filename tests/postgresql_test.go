@@ -3,6 +3,7 @@
 package tests
 
 import (
+	"encoding/json"
 	"flag"
 	"os"
 	"testing"
@@ -97,3 +98,39 @@ func TestIgnoringDB(t *testing.T) {
 	assert.Contains(t, stdout, `"database:postgres"`)
 	assert.NotContains(t, stdout, `"database:demo"`)
 }
+
+func TestDuplicateIndexReportedOnce(t *testing.T) {
+	createDuplicates := "CREATE TABLE IF NOT EXISTS dup_index_test (id integer, name text);" +
+		"CREATE INDEX IF NOT EXISTS dup_index_test_a ON dup_index_test (name);" +
+		"CREATE INDEX IF NOT EXISTS dup_index_test_b ON dup_index_test (name);"
+	_, stderr, err := simulation.ExecInContainer(serviceNamePostgresLatest,
+		[]string{"psql", "-U", *defaultUser, "-d", *defaultDB, "-c", createDuplicates},
+		"PGPASSWORD="+*defaultPassword)
+	assert.NoError(t, err, stderr)
+
+	stdout, stderr, err := simulation.RunIntegration(serviceNamePostgresLatest, integrationContainer, defaultBinaryPath, defaultUser, defaultPassword, defaultDB,
+		`-collection_list=all`, `-metrics=true`)
+	assert.NoError(t, err)
+	assert.Empty(t, stderr)
+
+	var output struct {
+		Data []struct {
+			Entity struct {
+				Name string `json:"name"`
+			} `json:"entity"`
+			Metrics []map[string]interface{} `json:"metrics"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &output))
+
+	duplicates := make([]string, 0)
+	for _, entity := range output.Data {
+		for _, sample := range entity.Metrics {
+			if sample["event_type"] == "PostgresqlIndexHealthSample" && sample["indexHealth.issue"] == "duplicate" &&
+				(entity.Entity.Name == "dup_index_test_a" || entity.Entity.Name == "dup_index_test_b") {
+				duplicates = append(duplicates, entity.Entity.Name)
+			}
+		}
+	}
+	assert.Equal(t, []string{"dup_index_test_b"}, duplicates)
+}
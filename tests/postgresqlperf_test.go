@@ -44,6 +44,7 @@ var (
 		"PostgresSlowQueries",
 		"PostgresWaitEvents",
 		"PostgresBlockingSessions",
+		"PostgresBlockingTrees",
 		"PostgresIndividualQueries",
 		"PostgresExecutionPlanMetrics",
 	}
@@ -51,6 +52,7 @@ var (
 		"PostgresSlowQueries",
 		"PostgresWaitEvents",
 		"PostgresBlockingSessions",
+		"PostgresBlockingTrees",
 		"PostgresIndividualQueries",
 		"PostgresExecutionPlanMetrics",
 	}
@@ -116,6 +116,7 @@ func GetSchemaFileName(sampleType string) string {
 		"PostgresSlowQueries":          "slow-queries-schema.json",
 		"PostgresWaitEvents":           "wait-events-schema.json",
 		"PostgresBlockingSessions":     "blocking-sessions-schema.json",
+		"PostgresBlockingTrees":        "blocking-trees-schema.json",
 		"PostgresIndividualQueries":    "individual-queries-schema.json",
 		"PostgresExecutionPlanMetrics": "execution-plan-schema.json",
 	}